@@ -1,29 +1,86 @@
 // @title			BashAPi service
 // @version		1.0
 // @description	RestAPI for executing bash commands in Docker with a queue system.
-// @BasePath		/api/commands
+// @BasePath		/api
 package main
 
 import (
+	"context"
+	"flag"
 	"fmt"
 	server "github.com/17HIERARCH70/BashAPI/internal/app"
 	"github.com/17HIERARCH70/BashAPI/internal/config"
 	"github.com/17HIERARCH70/BashAPI/internal/logger"
 	"github.com/17HIERARCH70/BashAPI/internal/storage/postgresql"
+	"github.com/jackc/pgx/v4/pgxpool"
+	"log/slog"
+	"os"
 )
 
 func main() {
 	cfg := config.MustLoad()
 	log := logger.SetupLogger(cfg.Env)
+	switch cfg.Storage {
+	case config.StorageMemory:
+		if args := flag.Args(); len(args) > 0 && args[0] == "migrate" {
+			fmt.Fprintln(os.Stderr, "migrations are not used by the memory storage")
+			os.Exit(1)
+		}
+		log.Warn("Commands are kept in memory and lost when the server stops")
+		runServer(cfg, log, nil)
+		return
+	case config.StoragePostgres:
+	default:
+		log.Error("Unknown storage", "storage", cfg.Storage)
+		return
+	}
+
 	db, err := postgresql.InitializeDB(cfg)
 	if err != nil {
 		log.Error("Failed to initialize the database", "error", err)
 		return
 	}
 	defer db.Close()
+
+	// BashAPI [-config path] migrate up | down [steps] | status
+	if args := flag.Args(); len(args) > 0 && args[0] == "migrate" {
+		if err := runMigrate(db, args[1:]); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			db.Close()
+			os.Exit(1)
+		}
+		return
+	}
+	if err := prepareSchema(cfg, log, db); err != nil {
+		log.Error("Database schema is not ready", "error", err)
+		return
+	}
+
+	runServer(cfg, log, db)
+}
+
+// runServer serves the API until the server is shut down, keeping the commands in db or,
+// when it is nil, in memory.
+func runServer(cfg *config.Config, log *slog.Logger, db *pgxpool.Pool) {
 	srv := server.NewServer(cfg, log, db)
 	go func() {
 		srv.Start(cfg.Server.Host + ":" + fmt.Sprintf("%d", cfg.Server.Port))
 	}()
 	srv.GracefulShutdown()
 }
+
+// prepareSchema applies the pending migrations when configured to and checks that the
+// schema is at the version the code relies on.
+func prepareSchema(cfg *config.Config, log *slog.Logger, db *pgxpool.Pool) error {
+	ctx := context.Background()
+	if cfg.Postgres.AutoMigrate {
+		applied, err := postgresql.MigrateUp(ctx, db)
+		if err != nil {
+			return err
+		}
+		if len(applied) > 0 {
+			log.Info("Database migrations applied", "versions", applied)
+		}
+	}
+	return postgresql.VerifySchema(ctx, db)
+}
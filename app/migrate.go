@@ -0,0 +1,73 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/17HIERARCH70/BashAPI/internal/storage/postgresql"
+	"github.com/17HIERARCH70/BashAPI/migrations"
+	"github.com/jackc/pgx/v4/pgxpool"
+	"strconv"
+)
+
+var errMigrateUsage = errors.New("usage: migrate up | down [steps] | status")
+
+// runMigrate runs the migrate subcommand: up applies the pending migrations, down rolls
+// back the given number of migrations (one by default) and status prints the schema version.
+func runMigrate(db *pgxpool.Pool, args []string) error {
+	if len(args) == 0 {
+		return errMigrateUsage
+	}
+	ctx := context.Background()
+	switch args[0] {
+	case "up":
+		applied, err := postgresql.MigrateUp(ctx, db)
+		for _, version := range applied {
+			fmt.Println("Applied migration", version)
+		}
+		if err == nil && len(applied) == 0 {
+			fmt.Println("No pending migrations")
+		}
+		return err
+	case "down":
+		steps := 1
+		if len(args) > 1 {
+			var err error
+			if steps, err = strconv.Atoi(args[1]); err != nil || steps < 1 {
+				return errMigrateUsage
+			}
+		}
+		reverted, err := postgresql.MigrateDown(ctx, db, steps)
+		for _, version := range reverted {
+			fmt.Println("Rolled back migration", version)
+		}
+		return err
+	case "status":
+		return printMigrationStatus(ctx, db)
+	}
+	return errMigrateUsage
+}
+
+// printMigrationStatus prints the schema version and the state of every embedded migration.
+func printMigrationStatus(ctx context.Context, db *pgxpool.Pool) error {
+	version, dirty, err := postgresql.ReadSchemaVersion(ctx, db)
+	if err != nil {
+		return err
+	}
+	list, err := postgresql.LoadMigrations(migrations.FS)
+	if err != nil {
+		return err
+	}
+	fmt.Printf("Schema version %d, expected %d, dirty %t\n", version, postgresql.SchemaVersion, dirty)
+	for _, migration := range list {
+		state := "applied"
+		switch {
+		case migration.Version == version && dirty:
+			state = "dirty"
+		case migration.Version > version:
+			state = "pending"
+		}
+		fmt.Printf("%06d %-7s %s\n", migration.Version, state, migration.Name)
+	}
+	return nil
+}
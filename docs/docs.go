@@ -15,36 +15,83 @@ const docTemplate = `{
     "host": "{{.Host}}",
     "basePath": "{{.BasePath}}",
     "paths": {
-        "/": {
+        "/admin/pause": {
+            "post": {
+                "description": "Stop starting queued commands from every queue. Running commands keep running\nand new commands are still accepted into the queue. The state survives restarts.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Admin"
+                ],
+                "summary": "Pause dispatching",
+                "parameters": [
+                    {
+                        "description": "Reason of the pause",
+                        "name": "pause",
+                        "in": "body",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Dispatching paused",
+                        "schema": {
+                            "$ref": "#/definitions/models.Message"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid request body",
+                        "schema": {
+                            "$ref": "#/definitions/models.Error"
+                        }
+                    },
+                    "500": {
+                        "description": "Problem on server side",
+                        "schema": {
+                            "$ref": "#/definitions/models.Error"
+                        }
+                    }
+                }
+            }
+        },
+        "/admin/pauses": {
             "get": {
-                "description": "Get a list of all commands processed by the system",
+                "description": "Get the paused queues; \"*\" stands for the global pause",
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "Getting commands"
+                    "Admin"
                 ],
-                "summary": "Retrieve all commands",
+                "summary": "List paused queues",
                 "responses": {
                     "200": {
-                        "description": "List of commands",
+                        "description": "Paused queues",
                         "schema": {
                             "type": "array",
                             "items": {
-                                "$ref": "#/definitions/models.Command"
+                                "$ref": "#/definitions/models.QueuePause"
                             }
                         }
                     },
                     "500": {
-                        "description": "Server error",
+                        "description": "Problem on server side",
                         "schema": {
                             "$ref": "#/definitions/models.Error"
                         }
                     }
                 }
-            },
+            }
+        },
+        "/admin/purge": {
             "post": {
-                "description": "Add a new non-sudo command to the system",
+                "description": "Delete every command matching the filter with its queue entry, output and archived output.\nSet filters are combined with AND, \"all\": true is required to purge the whole history.\nRunning commands are never deleted. With \"dry_run\": true nothing is deleted and the\ncommands that would be deleted are returned.",
                 "consumes": [
                     "application/json"
                 ],
@@ -52,35 +99,87 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "Commands creating"
+                    "Admin"
                 ],
-                "summary": "Create a new command",
+                "summary": "Purge command history",
                 "parameters": [
                     {
-                        "description": "Create command",
-                        "name": "command",
+                        "description": "Filter of the commands to delete",
+                        "name": "filter",
                         "in": "body",
                         "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/models.PurgeFilter"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Deleted commands",
+                        "schema": {
+                            "$ref": "#/definitions/models.PurgeResult"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid filter",
+                        "schema": {
+                            "$ref": "#/definitions/models.Error"
+                        }
+                    },
+                    "500": {
+                        "description": "Problem on server side",
+                        "schema": {
+                            "$ref": "#/definitions/models.Error"
+                        }
+                    }
+                }
+            }
+        },
+        "/admin/queues/{name}/pause": {
+            "post": {
+                "description": "Stop starting queued commands of one named queue. The state survives restarts.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Admin"
+                ],
+                "summary": "Pause a named queue",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Queue name",
+                        "name": "name",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Reason of the pause",
+                        "name": "pause",
+                        "in": "body",
                         "schema": {
                             "type": "string"
                         }
                     }
                 ],
                 "responses": {
-                    "202": {
-                        "description": "Command is being queued",
+                    "200": {
+                        "description": "Queue paused",
                         "schema": {
                             "$ref": "#/definitions/models.Message"
                         }
                     },
                     "400": {
-                        "description": "Error response",
+                        "description": "Invalid queue name or request body",
                         "schema": {
                             "$ref": "#/definitions/models.Error"
                         }
                     },
                     "500": {
-                        "description": "Error response on server side",
+                        "description": "Problem on server side",
                         "schema": {
                             "$ref": "#/definitions/models.Error"
                         }
@@ -88,46 +187,78 @@ const docTemplate = `{
                 }
             }
         },
-        "/commands/{id}/fstart": {
+        "/admin/queues/{name}/resume": {
             "post": {
-                "description": "Forcefully start a queued command by its ID, bypassing queue constraints",
+                "description": "Start dispatching commands of a paused named queue again",
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "Fetching commands"
+                    "Admin"
                 ],
-                "summary": "Force start a command",
+                "summary": "Resume a named queue",
                 "parameters": [
                     {
-                        "type": "integer",
-                        "description": "Command ID",
-                        "name": "id",
+                        "type": "string",
+                        "description": "Queue name",
+                        "name": "name",
                         "in": "path",
                         "required": true
                     }
                 ],
                 "responses": {
                     "200": {
-                        "description": "Command started successfully",
+                        "description": "Queue resumed",
                         "schema": {
                             "$ref": "#/definitions/models.Message"
                         }
                     },
                     "400": {
-                        "description": "Invalid ID supplied",
+                        "description": "Invalid queue name",
                         "schema": {
                             "$ref": "#/definitions/models.Error"
                         }
                     },
-                    "404": {
-                        "description": "Command not found",
+                    "409": {
+                        "description": "Queue is not paused",
                         "schema": {
                             "$ref": "#/definitions/models.Error"
                         }
                     },
                     "500": {
-                        "description": "Server error",
+                        "description": "Problem on server side",
+                        "schema": {
+                            "$ref": "#/definitions/models.Error"
+                        }
+                    }
+                }
+            }
+        },
+        "/admin/resume": {
+            "post": {
+                "description": "Lift the global pause. Queues paused by name stay paused.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Admin"
+                ],
+                "summary": "Resume dispatching",
+                "responses": {
+                    "200": {
+                        "description": "Dispatching resumed",
+                        "schema": {
+                            "$ref": "#/definitions/models.Message"
+                        }
+                    },
+                    "409": {
+                        "description": "Dispatching is not paused",
+                        "schema": {
+                            "$ref": "#/definitions/models.Error"
+                        }
+                    },
+                    "500": {
+                        "description": "Problem on server side",
                         "schema": {
                             "$ref": "#/definitions/models.Error"
                         }
@@ -135,38 +266,36 @@ const docTemplate = `{
                 }
             }
         },
-        "/queue": {
+        "/admin/webhooks": {
             "get": {
-                "description": "Get a list of all commands currently in the queue",
+                "description": "List the webhooks. Secrets are not returned.",
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "Queue"
+                    "Admin"
                 ],
-                "summary": "Retrieve command queue",
+                "summary": "Get webhooks",
                 "responses": {
                     "200": {
-                        "description": "List of queued items",
+                        "description": "Webhooks",
                         "schema": {
                             "type": "array",
                             "items": {
-                                "$ref": "#/definitions/models.Queue"
+                                "$ref": "#/definitions/models.Webhook"
                             }
                         }
                     },
                     "500": {
-                        "description": "Server error",
+                        "description": "Problem on server side",
                         "schema": {
                             "$ref": "#/definitions/models.Error"
                         }
                     }
                 }
-            }
-        },
-        "/sudo": {
+            },
             "post": {
-                "description": "Add a new sudo command to the system",
+                "description": "Subscribe a URL to command events: queued, started, completed, failed, timeout, stopped,\ncancelled and expired, all of them when events is empty. Every delivery is a POST of a JSON\npayload signed with HMAC-SHA256 in the X-BashAPI-Signature header, retried with backoff.",
                 "consumes": [
                     "application/json"
                 ],
@@ -174,35 +303,35 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "Commands creating"
+                    "Admin"
                 ],
-                "summary": "Create a new sudo command",
+                "summary": "Create a webhook",
                 "parameters": [
                     {
-                        "description": "Create sudo command",
-                        "name": "command",
+                        "description": "URL, secret and events of the webhook",
+                        "name": "webhook",
                         "in": "body",
                         "required": true,
                         "schema": {
-                            "type": "string"
+                            "$ref": "#/definitions/handlers.webhookRequest"
                         }
                     }
                 ],
                 "responses": {
-                    "202": {
-                        "description": "Command is being queued",
+                    "201": {
+                        "description": "Webhook created",
                         "schema": {
-                            "$ref": "#/definitions/models.Message"
+                            "$ref": "#/definitions/models.Webhook"
                         }
                     },
                     "400": {
-                        "description": "Error response",
+                        "description": "Invalid URL or events",
                         "schema": {
                             "$ref": "#/definitions/models.Error"
                         }
                     },
                     "500": {
-                        "description": "Error response on server side",
+                        "description": "Problem on server side",
                         "schema": {
                             "$ref": "#/definitions/models.Error"
                         }
@@ -210,20 +339,20 @@ const docTemplate = `{
                 }
             }
         },
-        "/{id}": {
-            "get": {
-                "description": "Retrieve a specific command by its unique ID",
+        "/admin/webhooks/{id}": {
+            "delete": {
+                "description": "Delete a webhook together with its pending and past deliveries.",
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "Getting commands"
+                    "Admin"
                 ],
-                "summary": "Get a command by ID",
+                "summary": "Delete a webhook",
                 "parameters": [
                     {
                         "type": "integer",
-                        "description": "Command ID",
+                        "description": "Webhook ID",
                         "name": "id",
                         "in": "path",
                         "required": true
@@ -231,9 +360,9 @@ const docTemplate = `{
                 ],
                 "responses": {
                     "200": {
-                        "description": "Command detail",
+                        "description": "Webhook deleted",
                         "schema": {
-                            "$ref": "#/definitions/models.Command"
+                            "$ref": "#/definitions/models.Message"
                         }
                     },
                     "400": {
@@ -243,7 +372,7 @@ const docTemplate = `{
                         }
                     },
                     "404": {
-                        "description": "Command not found",
+                        "description": "Webhook not found",
                         "schema": {
                             "$ref": "#/definitions/models.Error"
                         }
@@ -257,78 +386,1585 @@ const docTemplate = `{
                 }
             }
         },
-        "/{id}/stop": {
-            "post": {
-                "description": "Stop a running command by its ID",
+        "/admin/webhooks/{id}/deliveries": {
+            "get": {
+                "description": "List the latest deliveries of a webhook, newest first, with every attempt and its response.",
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "Fetching commands"
+                    "Admin"
                 ],
-                "summary": "Stop a command",
+                "summary": "Get webhook deliveries",
                 "parameters": [
                     {
                         "type": "integer",
-                        "description": "Command ID",
+                        "description": "Webhook ID",
                         "name": "id",
                         "in": "path",
                         "required": true
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Maximum number of deliveries, 100 by default",
+                        "name": "limit",
+                        "in": "query"
                     }
                 ],
                 "responses": {
                     "200": {
-                        "description": "Command stopped successfully",
+                        "description": "Deliveries",
                         "schema": {
-                            "$ref": "#/definitions/models.Message"
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/models.WebhookDelivery"
+                            }
                         }
                     },
                     "400": {
-                        "description": "Invalid ID supplied",
+                        "description": "Invalid ID or limit",
                         "schema": {
                             "$ref": "#/definitions/models.Error"
                         }
                     },
-                    "404": {
-                        "description": "Command not found",
+                    "500": {
+                        "description": "Problem on server side",
+                        "schema": {
+                            "$ref": "#/definitions/models.Error"
+                        }
+                    }
+                }
+            }
+        },
+        "/commands/": {
+            "get": {
+                "description": "Get a page of the commands processed by the system. The output is left out unless\nrequested with fields. The cursor of the next page is returned in the X-Next-Cursor header.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Getting commands"
+                ],
+                "summary": "Retrieve commands",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Comma separated statuses",
+                        "name": "status",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "RFC 3339 time, inclusive",
+                        "name": "created_after",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "RFC 3339 time, exclusive",
+                        "name": "created_before",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "RFC 3339 time, inclusive",
+                        "name": "updated_after",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "RFC 3339 time, exclusive",
+                        "name": "updated_before",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Submitter of the command",
+                        "name": "submitter",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Comma separated tags the command must all carry",
+                        "name": "tag",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Label selector, e.g. env=prod,team!=infra",
+                        "name": "selector",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Substring of the script",
+                        "name": "script",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Exit code of the command",
+                        "name": "exit_code",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Sort by id, created_at or updated_at",
+                        "name": "sort",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "asc or desc",
+                        "name": "order",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Page size, 100 by default and at most 1000",
+                        "name": "limit",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "X-Next-Cursor of the previous page",
+                        "name": "cursor",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Comma separated fields to return, e.g. ID,Status,Output",
+                        "name": "fields",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "List of commands",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/models.Command"
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid query parameters",
                         "schema": {
                             "$ref": "#/definitions/models.Error"
                         }
                     },
                     "500": {
-                        "description": "Problem on server side",
+                        "description": "Server error",
                         "schema": {
                             "$ref": "#/definitions/models.Error"
                         }
                     }
                 }
-            }
-        }
-    },
-    "definitions": {
-        "models.Command": {
-            "type": "object",
-            "properties": {
-                "createdAt": {
-                    "type": "string"
-                },
-                "id": {
-                    "type": "integer"
-                },
-                "output": {
-                    "type": "string"
-                },
+            },
+            "post": {
+                "description": "Add a new non-sudo command to the system.\nOptional \"locks\" ([{\"key\": \"db\", \"mode\": \"exclusive|shared\"}]) keep conflicting commands in the queue.\nOptional \"labels\" ({\"env\": \"prod\"}) can be used in selectors, \"annotations\" are free-form.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Commands creating"
+                ],
+                "summary": "Create a new command",
+                "parameters": [
+                    {
+                        "description": "Create command",
+                        "name": "command",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    {
+                        "type": "string",
+                        "description": "Key to safely retry the request",
+                        "name": "Idempotency-Key",
+                        "in": "header"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Submitter of the command, used to filter the list",
+                        "name": "X-Submitter",
+                        "in": "header"
+                    },
+                    {
+                        "type": "boolean",
+                        "description": "Wait for the command to finish and return it with its output",
+                        "name": "wait",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Time to wait with wait=true, e.g. 60s, 30s by default",
+                        "name": "timeout",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Finished command, with wait=true",
+                        "schema": {
+                            "$ref": "#/definitions/models.Command"
+                        }
+                    },
+                    "202": {
+                        "description": "Command is being queued",
+                        "schema": {
+                            "$ref": "#/definitions/models.Message"
+                        }
+                    },
+                    "400": {
+                        "description": "Error response",
+                        "schema": {
+                            "$ref": "#/definitions/models.Error"
+                        }
+                    },
+                    "409": {
+                        "description": "Idempotency key conflict",
+                        "schema": {
+                            "$ref": "#/definitions/models.Error"
+                        }
+                    },
+                    "500": {
+                        "description": "Error response on server side",
+                        "schema": {
+                            "$ref": "#/definitions/models.Error"
+                        }
+                    },
+                    "503": {
+                        "description": "Server is shutting down",
+                        "schema": {
+                            "$ref": "#/definitions/models.Error"
+                        }
+                    }
+                }
+            }
+        },
+        "/commands/admission": {
+            "get": {
+                "description": "Show whether the dispatcher currently starts queued commands\nand the host load metrics and thresholds behind the decision",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Queue"
+                ],
+                "summary": "Retrieve admission decision",
+                "responses": {
+                    "200": {
+                        "description": "Admission decision",
+                        "schema": {
+                            "$ref": "#/definitions/models.Admission"
+                        }
+                    }
+                }
+            }
+        },
+        "/commands/capacity": {
+            "get": {
+                "description": "Show the used and available concurrency slots shared by running commands",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Queue"
+                ],
+                "summary": "Retrieve capacity usage",
+                "responses": {
+                    "200": {
+                        "description": "Capacity usage",
+                        "schema": {
+                            "$ref": "#/definitions/models.Capacity"
+                        }
+                    },
+                    "500": {
+                        "description": "Server error",
+                        "schema": {
+                            "$ref": "#/definitions/models.Error"
+                        }
+                    }
+                }
+            }
+        },
+        "/commands/queue": {
+            "get": {
+                "description": "Get a list of all commands currently in the queue.\nBlockedBy holds the ID of the running command owning a conflicting lock.\nEstimatedStartAt is derived from the durations of similar completed scripts.\nThe concurrency slots shared by the running commands are reported in the X-Capacity headers.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Queue"
+                ],
+                "summary": "Retrieve command queue",
+                "responses": {
+                    "200": {
+                        "description": "List of queued items",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/models.Queue"
+                            }
+                        },
+                        "headers": {
+                            "X-Capacity-Available": {
+                                "type": "integer",
+                                "description": "Free slots"
+                            },
+                            "X-Capacity-Total": {
+                                "type": "integer",
+                                "description": "Total concurrency slots"
+                            },
+                            "X-Capacity-Used": {
+                                "type": "integer",
+                                "description": "Slots taken by the running commands"
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "Server error",
+                        "schema": {
+                            "$ref": "#/definitions/models.Error"
+                        }
+                    }
+                }
+            }
+        },
+        "/commands/queue/cancel": {
+            "post": {
+                "description": "Cancel every waiting command matching the filter. Set filters are combined with AND,\n\"all\": true is required to cancel the whole queue.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Queue"
+                ],
+                "summary": "Bulk cancel queued commands",
+                "parameters": [
+                    {
+                        "description": "Filter of the commands to cancel",
+                        "name": "filter",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/models.QueueFilter"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Commands cancelled",
+                        "schema": {
+                            "$ref": "#/definitions/models.Message"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid filter",
+                        "schema": {
+                            "$ref": "#/definitions/models.Error"
+                        }
+                    },
+                    "500": {
+                        "description": "Problem on server side",
+                        "schema": {
+                            "$ref": "#/definitions/models.Error"
+                        }
+                    }
+                }
+            }
+        },
+        "/commands/search": {
+            "get": {
+                "description": "Find the commands whose script or output contains the words of q, or q as a substring.\nThe matches are highlighted with \u003cmark\u003e tags in the snippets. Takes the filters and the\npaging of the command list, newest first by default.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Getting commands"
+                ],
+                "summary": "Search commands",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Words or substring to search for",
+                        "name": "q",
+                        "in": "query",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Comma separated statuses",
+                        "name": "status",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "RFC 3339 time, inclusive",
+                        "name": "created_after",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "RFC 3339 time, exclusive",
+                        "name": "created_before",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "RFC 3339 time, inclusive",
+                        "name": "updated_after",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "RFC 3339 time, exclusive",
+                        "name": "updated_before",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Submitter of the command",
+                        "name": "submitter",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Comma separated tags the command must all carry",
+                        "name": "tag",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Label selector, e.g. env=prod,team!=infra",
+                        "name": "selector",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Substring of the script",
+                        "name": "script",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Exit code of the command",
+                        "name": "exit_code",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Sort by id, created_at or updated_at",
+                        "name": "sort",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "asc or desc",
+                        "name": "order",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Page size, 100 by default and at most 1000",
+                        "name": "limit",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "X-Next-Cursor of the previous page",
+                        "name": "cursor",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Matching commands",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/models.SearchResult"
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid query parameters",
+                        "schema": {
+                            "$ref": "#/definitions/models.Error"
+                        }
+                    },
+                    "500": {
+                        "description": "Server error",
+                        "schema": {
+                            "$ref": "#/definitions/models.Error"
+                        }
+                    }
+                }
+            }
+        },
+        "/commands/stop": {
+            "post": {
+                "description": "Stop every running command whose labels match the selector, e.g. \"deploy=1234\"\nor \"env=prod,team!=infra\". Matching queued commands are cancelled.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Commands"
+                ],
+                "summary": "Bulk stop commands",
+                "parameters": [
+                    {
+                        "description": "Label selector of the commands to stop",
+                        "name": "filter",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/models.SelectorFilter"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Commands stopped",
+                        "schema": {
+                            "$ref": "#/definitions/models.Message"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid selector",
+                        "schema": {
+                            "$ref": "#/definitions/models.Error"
+                        }
+                    },
+                    "500": {
+                        "description": "Problem on server side",
+                        "schema": {
+                            "$ref": "#/definitions/models.Error"
+                        }
+                    }
+                }
+            }
+        },
+        "/commands/sudo": {
+            "post": {
+                "description": "Add a new sudo command to the system.\nOptional \"locks\" ([{\"key\": \"db\", \"mode\": \"exclusive|shared\"}]) keep conflicting commands in the queue.\nOptional \"labels\" ({\"env\": \"prod\"}) can be used in selectors, \"annotations\" are free-form.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Commands creating"
+                ],
+                "summary": "Create a new sudo command",
+                "parameters": [
+                    {
+                        "description": "Create sudo command",
+                        "name": "command",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    {
+                        "type": "string",
+                        "description": "Key to safely retry the request",
+                        "name": "Idempotency-Key",
+                        "in": "header"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Submitter of the command, used to filter the list",
+                        "name": "X-Submitter",
+                        "in": "header"
+                    },
+                    {
+                        "type": "boolean",
+                        "description": "Wait for the command to finish and return it with its output",
+                        "name": "wait",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Time to wait with wait=true, e.g. 60s, 30s by default",
+                        "name": "timeout",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Finished command, with wait=true",
+                        "schema": {
+                            "$ref": "#/definitions/models.Command"
+                        }
+                    },
+                    "202": {
+                        "description": "Command is being queued",
+                        "schema": {
+                            "$ref": "#/definitions/models.Message"
+                        }
+                    },
+                    "400": {
+                        "description": "Error response",
+                        "schema": {
+                            "$ref": "#/definitions/models.Error"
+                        }
+                    },
+                    "409": {
+                        "description": "Idempotency key conflict",
+                        "schema": {
+                            "$ref": "#/definitions/models.Error"
+                        }
+                    },
+                    "500": {
+                        "description": "Error response on server side",
+                        "schema": {
+                            "$ref": "#/definitions/models.Error"
+                        }
+                    },
+                    "503": {
+                        "description": "Server is shutting down",
+                        "schema": {
+                            "$ref": "#/definitions/models.Error"
+                        }
+                    }
+                }
+            }
+        },
+        "/commands/{id}": {
+            "get": {
+                "description": "Retrieve a specific command by its unique ID",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Getting commands"
+                ],
+                "summary": "Get a command by ID",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Command ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Command detail",
+                        "schema": {
+                            "$ref": "#/definitions/models.Command"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid ID supplied",
+                        "schema": {
+                            "$ref": "#/definitions/models.Error"
+                        }
+                    },
+                    "404": {
+                        "description": "Command not found",
+                        "schema": {
+                            "$ref": "#/definitions/models.Error"
+                        }
+                    },
+                    "500": {
+                        "description": "Problem on server side",
+                        "schema": {
+                            "$ref": "#/definitions/models.Error"
+                        }
+                    }
+                }
+            },
+            "delete": {
+                "description": "Delete a command that is not running, with its queue entry, output and archived output",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Fetching commands"
+                ],
+                "summary": "Delete a command",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Command ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Command deleted",
+                        "schema": {
+                            "$ref": "#/definitions/models.Message"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid ID supplied",
+                        "schema": {
+                            "$ref": "#/definitions/models.Error"
+                        }
+                    },
+                    "404": {
+                        "description": "Command not found",
+                        "schema": {
+                            "$ref": "#/definitions/models.Error"
+                        }
+                    },
+                    "409": {
+                        "description": "Command is running",
+                        "schema": {
+                            "$ref": "#/definitions/models.Error"
+                        }
+                    },
+                    "500": {
+                        "description": "Problem on server side",
+                        "schema": {
+                            "$ref": "#/definitions/models.Error"
+                        }
+                    }
+                }
+            }
+        },
+        "/commands/{id}/cancel": {
+            "post": {
+                "description": "Remove a waiting command from the queue and mark it as cancelled",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Queue"
+                ],
+                "summary": "Cancel a queued command",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Command ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Command cancelled",
+                        "schema": {
+                            "$ref": "#/definitions/models.Message"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid ID supplied",
+                        "schema": {
+                            "$ref": "#/definitions/models.Error"
+                        }
+                    },
+                    "404": {
+                        "description": "Command not found",
+                        "schema": {
+                            "$ref": "#/definitions/models.Error"
+                        }
+                    },
+                    "409": {
+                        "description": "Command is not waiting in the queue",
+                        "schema": {
+                            "$ref": "#/definitions/models.Error"
+                        }
+                    },
+                    "500": {
+                        "description": "Problem on server side",
+                        "schema": {
+                            "$ref": "#/definitions/models.Error"
+                        }
+                    }
+                }
+            }
+        },
+        "/commands/{id}/deliveries": {
+            "get": {
+                "description": "List the latest webhook and callback deliveries of a command's events, newest first, with every attempt.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Getting commands"
+                ],
+                "summary": "Get command deliveries",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Command ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Maximum number of deliveries, 100 by default",
+                        "name": "limit",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Deliveries",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/models.WebhookDelivery"
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid ID or limit",
+                        "schema": {
+                            "$ref": "#/definitions/models.Error"
+                        }
+                    },
+                    "500": {
+                        "description": "Problem on server side",
+                        "schema": {
+                            "$ref": "#/definitions/models.Error"
+                        }
+                    }
+                }
+            }
+        },
+        "/commands/{id}/events": {
+            "get": {
+                "description": "List the status transitions of a command, oldest first, with the time, the actor and the reason of each.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Getting commands"
+                ],
+                "summary": "Get command events",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Command ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Status transitions",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/models.CommandEvent"
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid ID supplied",
+                        "schema": {
+                            "$ref": "#/definitions/models.Error"
+                        }
+                    },
+                    "404": {
+                        "description": "Command not found",
+                        "schema": {
+                            "$ref": "#/definitions/models.Error"
+                        }
+                    },
+                    "500": {
+                        "description": "Problem on server side",
+                        "schema": {
+                            "$ref": "#/definitions/models.Error"
+                        }
+                    }
+                }
+            }
+        },
+        "/commands/{id}/fstart": {
+            "post": {
+                "description": "Forcefully start a queued command by its ID, bypassing queue constraints",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Fetching commands"
+                ],
+                "summary": "Force start a command",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Command ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Command started successfully",
+                        "schema": {
+                            "$ref": "#/definitions/models.Message"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid ID supplied",
+                        "schema": {
+                            "$ref": "#/definitions/models.Error"
+                        }
+                    },
+                    "404": {
+                        "description": "Command not found",
+                        "schema": {
+                            "$ref": "#/definitions/models.Error"
+                        }
+                    },
+                    "409": {
+                        "description": "Command is running or completed",
+                        "schema": {
+                            "$ref": "#/definitions/models.Error"
+                        }
+                    },
+                    "500": {
+                        "description": "Server error",
+                        "schema": {
+                            "$ref": "#/definitions/models.Error"
+                        }
+                    },
+                    "503": {
+                        "description": "Server is shutting down",
+                        "schema": {
+                            "$ref": "#/definitions/models.Error"
+                        }
+                    }
+                }
+            }
+        },
+        "/commands/{id}/labels": {
+            "patch": {
+                "description": "Set labels and annotations of a command. Keys with a null value are removed, keys\nthat are not listed are kept. Labels can be selected by, annotations are free-form.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Commands"
+                ],
+                "summary": "Update command labels",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Command ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Labels and annotations to set or remove",
+                        "name": "update",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/models.LabelUpdate"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Labels and annotations of the command",
+                        "schema": {
+                            "$ref": "#/definitions/models.CommandLabels"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid ID or labels",
+                        "schema": {
+                            "$ref": "#/definitions/models.Error"
+                        }
+                    },
+                    "404": {
+                        "description": "Command not found",
+                        "schema": {
+                            "$ref": "#/definitions/models.Error"
+                        }
+                    },
+                    "500": {
+                        "description": "Problem on server side",
+                        "schema": {
+                            "$ref": "#/definitions/models.Error"
+                        }
+                    }
+                }
+            }
+        },
+        "/commands/{id}/move": {
+            "post": {
+                "description": "Move a waiting command to the front, to the back or to a 1-based position of the queue",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Queue"
+                ],
+                "summary": "Reorder the queue",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Command ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Position: front, back or a 1-based index",
+                        "name": "move",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Command moved",
+                        "schema": {
+                            "$ref": "#/definitions/models.Message"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid ID or position",
+                        "schema": {
+                            "$ref": "#/definitions/models.Error"
+                        }
+                    },
+                    "404": {
+                        "description": "Command not found",
+                        "schema": {
+                            "$ref": "#/definitions/models.Error"
+                        }
+                    },
+                    "409": {
+                        "description": "Command is not waiting in the queue",
+                        "schema": {
+                            "$ref": "#/definitions/models.Error"
+                        }
+                    },
+                    "500": {
+                        "description": "Problem on server side",
+                        "schema": {
+                            "$ref": "#/definitions/models.Error"
+                        }
+                    }
+                }
+            }
+        },
+        "/commands/{id}/output": {
+            "get": {
+                "description": "Read a byte range of the command output, its first or last lines, or the lines matching a regular expression. With raw=true the output is returned as a text/plain download.",
+                "produces": [
+                    "application/json",
+                    "text/plain"
+                ],
+                "tags": [
+                    "Getting commands"
+                ],
+                "summary": "Get command output",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Command ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Byte offset to start reading from",
+                        "name": "offset",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Maximum number of bytes to read, 0 reads to the end",
+                        "name": "limit",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Return the first N lines of the range",
+                        "name": "head",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Return the last N lines of the range",
+                        "name": "tail",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Return only the lines matching this regular expression",
+                        "name": "grep",
+                        "in": "query"
+                    },
+                    {
+                        "type": "boolean",
+                        "description": "Download the selected output as text/plain",
+                        "name": "raw",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Command output",
+                        "schema": {
+                            "$ref": "#/definitions/models.CommandOutput"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid ID or query parameters",
+                        "schema": {
+                            "$ref": "#/definitions/models.Error"
+                        }
+                    },
+                    "404": {
+                        "description": "Command not found",
+                        "schema": {
+                            "$ref": "#/definitions/models.Error"
+                        }
+                    },
+                    "500": {
+                        "description": "Problem on server side",
+                        "schema": {
+                            "$ref": "#/definitions/models.Error"
+                        }
+                    }
+                }
+            }
+        },
+        "/commands/{id}/stop": {
+            "post": {
+                "description": "Stop a running command by its ID, a queued command is cancelled",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Fetching commands"
+                ],
+                "summary": "Stop a command",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Command ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Command stopped successfully",
+                        "schema": {
+                            "$ref": "#/definitions/models.Message"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid ID supplied",
+                        "schema": {
+                            "$ref": "#/definitions/models.Error"
+                        }
+                    },
+                    "404": {
+                        "description": "Command not found",
+                        "schema": {
+                            "$ref": "#/definitions/models.Error"
+                        }
+                    },
+                    "409": {
+                        "description": "Command is not running",
+                        "schema": {
+                            "$ref": "#/definitions/models.Error"
+                        }
+                    },
+                    "500": {
+                        "description": "Problem on server side",
+                        "schema": {
+                            "$ref": "#/definitions/models.Error"
+                        }
+                    }
+                }
+            }
+        },
+        "/commands/{id}/wait": {
+            "get": {
+                "description": "Block until the command reaches a final status and return it. When the timeout elapses\nfirst, the command is returned as it is with 202. The timeout is capped by max_wait.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Getting commands"
+                ],
+                "summary": "Wait for a command",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Command ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Time to wait, e.g. 60s or 60, 30s by default",
+                        "name": "timeout",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Finished command",
+                        "schema": {
+                            "$ref": "#/definitions/models.Command"
+                        }
+                    },
+                    "202": {
+                        "description": "Command is still waiting or running",
+                        "schema": {
+                            "$ref": "#/definitions/models.Command"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid ID or timeout",
+                        "schema": {
+                            "$ref": "#/definitions/models.Error"
+                        }
+                    },
+                    "404": {
+                        "description": "Command not found",
+                        "schema": {
+                            "$ref": "#/definitions/models.Error"
+                        }
+                    },
+                    "500": {
+                        "description": "Problem on server side",
+                        "schema": {
+                            "$ref": "#/definitions/models.Error"
+                        }
+                    }
+                }
+            }
+        },
+        "/events": {
+            "get": {
+                "description": "Stream the lifecycle events of all commands on every replica as Server-Sent Events, or as\nJSON WebSocket messages when the request asks for a WebSocket upgrade. SSE clients resume\nafter a reconnect with the Last-Event-ID header, the after parameter does the same for both.\nEvents that occurred shortly before the resumed one may be sent again, event_id tells them apart.",
+                "produces": [
+                    "text/event-stream"
+                ],
+                "tags": [
+                    "Getting commands"
+                ],
+                "summary": "Stream command events",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Only commands of this queue",
+                        "name": "namespace",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Label selector, e.g. env=prod,team!=infra",
+                        "name": "selector",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Comma-separated event types, e.g. completed,failed",
+                        "name": "type",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Replay the stored events after this event ID first",
+                        "name": "after",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Stream of events",
+                        "schema": {
+                            "$ref": "#/definitions/models.LifecycleEvent"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid filter",
+                        "schema": {
+                            "$ref": "#/definitions/models.Error"
+                        }
+                    },
+                    "500": {
+                        "description": "Problem on server side",
+                        "schema": {
+                            "$ref": "#/definitions/models.Error"
+                        }
+                    }
+                }
+            }
+        }
+    },
+    "definitions": {
+        "handlers.webhookRequest": {
+            "type": "object",
+            "required": [
+                "url"
+            ],
+            "properties": {
+                "events": {
+                    "description": "Events lists the subscribed events, all of them when empty.",
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "secret": {
+                    "description": "Secret signs the deliveries, the configured secret is used when empty.",
+                    "type": "string"
+                },
+                "url": {
+                    "type": "string"
+                }
+            }
+        },
+        "models.Admission": {
+            "type": "object",
+            "properties": {
+                "admit": {
+                    "type": "boolean"
+                },
+                "checked_at": {
+                    "type": "string"
+                },
+                "enabled": {
+                    "type": "boolean"
+                },
+                "error": {
+                    "type": "string"
+                },
+                "metrics": {
+                    "$ref": "#/definitions/models.HostMetrics"
+                },
+                "reasons": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "thresholds": {
+                    "$ref": "#/definitions/models.AdmissionThresholds"
+                }
+            }
+        },
+        "models.AdmissionThresholds": {
+            "type": "object",
+            "properties": {
+                "max_cpu_pressure": {
+                    "type": "number"
+                },
+                "max_load_per_cpu": {
+                    "type": "number"
+                },
+                "min_available_memory_mb": {
+                    "type": "integer"
+                }
+            }
+        },
+        "models.Capacity": {
+            "type": "object",
+            "properties": {
+                "available": {
+                    "type": "integer"
+                },
+                "total": {
+                    "type": "integer"
+                },
+                "used": {
+                    "type": "integer"
+                }
+            }
+        },
+        "models.Command": {
+            "type": "object",
+            "properties": {
+                "annotations": {
+                    "type": "object",
+                    "additionalProperties": {
+                        "type": "string"
+                    }
+                },
+                "createdAt": {
+                    "type": "string"
+                },
+                "exitCode": {
+                    "type": "integer"
+                },
+                "finishedAt": {
+                    "type": "string"
+                },
+                "id": {
+                    "type": "integer"
+                },
+                "labels": {
+                    "description": "Labels can be selected by, annotations only carry information.",
+                    "type": "object",
+                    "additionalProperties": {
+                        "type": "string"
+                    }
+                },
+                "output": {
+                    "type": "string"
+                },
+                "outputArchivedAt": {
+                    "description": "OutputArchivedAt is set once the output was moved to the archive.",
+                    "type": "string"
+                },
+                "outputSize": {
+                    "type": "integer"
+                },
+                "outputTruncated": {
+                    "description": "OutputTruncated is set once output was dropped because of the output limit.",
+                    "type": "boolean"
+                },
                 "pid": {
                     "type": "integer"
                 },
+                "queue": {
+                    "type": "string"
+                },
                 "script": {
                     "type": "string"
                 },
+                "startedAt": {
+                    "type": "string"
+                },
                 "status": {
                     "type": "string"
                 },
+                "submitter": {
+                    "type": "string"
+                },
+                "tags": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
                 "updatedAt": {
                     "type": "string"
+                },
+                "weight": {
+                    "type": "integer"
+                }
+            }
+        },
+        "models.CommandEvent": {
+            "type": "object",
+            "properties": {
+                "actor": {
+                    "description": "Actor is the part of the service that changed the status, e.g. \"api\" or \"dispatcher\".",
+                    "type": "string"
+                },
+                "command_id": {
+                    "type": "integer"
+                },
+                "created_at": {
+                    "type": "string"
+                },
+                "from": {
+                    "description": "From is nil for the creation of the command.",
+                    "type": "string"
+                },
+                "id": {
+                    "type": "integer"
+                },
+                "reason": {
+                    "type": "string"
+                },
+                "to": {
+                    "type": "string"
+                }
+            }
+        },
+        "models.CommandLabels": {
+            "type": "object",
+            "properties": {
+                "annotations": {
+                    "type": "object",
+                    "additionalProperties": {
+                        "type": "string"
+                    }
+                },
+                "labels": {
+                    "type": "object",
+                    "additionalProperties": {
+                        "type": "string"
+                    }
+                }
+            }
+        },
+        "models.CommandOutput": {
+            "type": "object",
+            "properties": {
+                "command_id": {
+                    "type": "integer"
+                },
+                "dropped": {
+                    "description": "Dropped is the number of output bytes that were not kept.",
+                    "type": "integer"
+                },
+                "lines": {
+                    "description": "Lines is the number of selected lines, set for line queries only.",
+                    "type": "integer"
+                },
+                "next_offset": {
+                    "description": "NextOffset is where the following byte range starts, set for byte range queries only.",
+                    "type": "integer"
+                },
+                "offset": {
+                    "type": "integer"
+                },
+                "output": {
+                    "type": "string"
+                },
+                "size": {
+                    "description": "Size is the length of the kept output, including the truncation marker.",
+                    "type": "integer"
+                },
+                "truncated": {
+                    "description": "Truncated is set when part of the output was dropped because of the output limit.",
+                    "type": "boolean"
                 }
             }
         },
@@ -340,6 +1976,95 @@ const docTemplate = `{
                 }
             }
         },
+        "models.HostMetrics": {
+            "type": "object",
+            "properties": {
+                "cpu_pressure_avg10": {
+                    "type": "number"
+                },
+                "cpus": {
+                    "type": "integer"
+                },
+                "load1": {
+                    "type": "number"
+                },
+                "load15": {
+                    "type": "number"
+                },
+                "load5": {
+                    "type": "number"
+                },
+                "load_per_cpu": {
+                    "type": "number"
+                },
+                "mem_available_bytes": {
+                    "type": "integer"
+                },
+                "mem_total_bytes": {
+                    "type": "integer"
+                }
+            }
+        },
+        "models.LabelUpdate": {
+            "type": "object",
+            "properties": {
+                "annotations": {
+                    "type": "object",
+                    "additionalProperties": {
+                        "type": "string"
+                    }
+                },
+                "labels": {
+                    "type": "object",
+                    "additionalProperties": {
+                        "type": "string"
+                    }
+                }
+            }
+        },
+        "models.LifecycleEvent": {
+            "type": "object",
+            "properties": {
+                "actor": {
+                    "type": "string"
+                },
+                "command_id": {
+                    "type": "integer"
+                },
+                "event": {
+                    "description": "Event is the name of the reached status, e.g. \"started\" or \"failed\", see StatusEvents.",
+                    "type": "string"
+                },
+                "event_id": {
+                    "type": "integer"
+                },
+                "exit_code": {
+                    "description": "ExitCode is only set once the command has finished.",
+                    "type": "integer"
+                },
+                "labels": {
+                    "type": "object",
+                    "additionalProperties": {
+                        "type": "string"
+                    }
+                },
+                "occurred_at": {
+                    "type": "string"
+                },
+                "previous_status": {
+                    "type": "string"
+                },
+                "queue": {
+                    "type": "string"
+                },
+                "reason": {
+                    "type": "string"
+                },
+                "status": {
+                    "type": "string"
+                }
+            }
+        },
         "models.Message": {
             "type": "object",
             "properties": {
@@ -351,17 +2076,260 @@ const docTemplate = `{
                 }
             }
         },
+        "models.PurgeFilter": {
+            "type": "object",
+            "properties": {
+                "all": {
+                    "type": "boolean"
+                },
+                "dry_run": {
+                    "type": "boolean"
+                },
+                "finished_before": {
+                    "type": "string"
+                },
+                "ids": {
+                    "type": "array",
+                    "items": {
+                        "type": "integer"
+                    }
+                },
+                "keep_last": {
+                    "description": "KeepLast spares the newest matching commands of every queue.",
+                    "type": "integer"
+                },
+                "queue": {
+                    "type": "string"
+                },
+                "selector": {
+                    "description": "Selector selects commands by their labels, e.g. \"env=prod,team!=infra\".",
+                    "type": "string"
+                },
+                "statuses": {
+                    "description": "Statuses defaults to every finished status, \"waiting\" has to be listed explicitly.",
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                }
+            }
+        },
+        "models.PurgeResult": {
+            "type": "object",
+            "properties": {
+                "archived_outputs": {
+                    "description": "ArchivedOutputs is the number of archived output files removed with the commands.",
+                    "type": "integer"
+                },
+                "count": {
+                    "type": "integer"
+                },
+                "dry_run": {
+                    "type": "boolean"
+                },
+                "ids": {
+                    "type": "array",
+                    "items": {
+                        "type": "integer"
+                    }
+                }
+            }
+        },
         "models.Queue": {
             "type": "object",
             "properties": {
+                "ahead": {
+                    "type": "integer"
+                },
+                "blockedBy": {
+                    "description": "BlockedBy is the running command holding a lock this one waits for.",
+                    "type": "integer"
+                },
                 "commandId": {
                     "type": "integer"
                 },
+                "estimatedStartAt": {
+                    "description": "EstimatedStartAt is nil when the command is held back, e.g. by a paused queue.",
+                    "type": "string"
+                },
+                "expiresAt": {
+                    "description": "ExpiresAt is when the command gives up waiting and becomes expired.",
+                    "type": "string"
+                },
+                "paused": {
+                    "type": "boolean"
+                },
+                "position": {
+                    "description": "Position is the 1-based place in dispatch order, Ahead the number of commands before it.",
+                    "type": "integer"
+                },
+                "queue": {
+                    "type": "string"
+                },
                 "queueId": {
                     "type": "integer"
                 },
                 "status": {
                     "type": "string"
+                },
+                "weight": {
+                    "type": "integer"
+                }
+            }
+        },
+        "models.QueueFilter": {
+            "type": "object",
+            "properties": {
+                "all": {
+                    "type": "boolean"
+                },
+                "created_before": {
+                    "type": "string"
+                },
+                "ids": {
+                    "type": "array",
+                    "items": {
+                        "type": "integer"
+                    }
+                },
+                "queue": {
+                    "type": "string"
+                },
+                "script_contains": {
+                    "type": "string"
+                },
+                "selector": {
+                    "description": "Selector selects commands by their labels, e.g. \"env=prod,team!=infra\".",
+                    "type": "string"
+                }
+            }
+        },
+        "models.QueuePause": {
+            "type": "object",
+            "properties": {
+                "paused_at": {
+                    "type": "string"
+                },
+                "queue": {
+                    "type": "string"
+                },
+                "reason": {
+                    "type": "string"
+                }
+            }
+        },
+        "models.SearchResult": {
+            "type": "object",
+            "properties": {
+                "command": {
+                    "$ref": "#/definitions/models.Command"
+                },
+                "output_snippet": {
+                    "type": "string"
+                },
+                "script_snippet": {
+                    "type": "string"
+                }
+            }
+        },
+        "models.SelectorFilter": {
+            "type": "object",
+            "properties": {
+                "selector": {
+                    "type": "string"
+                }
+            }
+        },
+        "models.Webhook": {
+            "type": "object",
+            "properties": {
+                "created_at": {
+                    "type": "string"
+                },
+                "events": {
+                    "description": "Events lists the subscribed events, all of them when empty.",
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "id": {
+                    "type": "integer"
+                },
+                "secret": {
+                    "description": "Secret signs the deliveries, the configured one is used when empty. It is never returned.",
+                    "type": "string"
+                },
+                "url": {
+                    "type": "string"
+                }
+            }
+        },
+        "models.WebhookAttempt": {
+            "type": "object",
+            "properties": {
+                "attempt": {
+                    "type": "integer"
+                },
+                "created_at": {
+                    "type": "string"
+                },
+                "duration_ms": {
+                    "type": "integer"
+                },
+                "error": {
+                    "type": "string"
+                },
+                "status_code": {
+                    "type": "integer"
+                }
+            }
+        },
+        "models.WebhookDelivery": {
+            "type": "object",
+            "properties": {
+                "attempt_log": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/models.WebhookAttempt"
+                    }
+                },
+                "attempts": {
+                    "type": "integer"
+                },
+                "command_id": {
+                    "type": "integer"
+                },
+                "created_at": {
+                    "type": "string"
+                },
+                "delivered_at": {
+                    "type": "string"
+                },
+                "event": {
+                    "type": "string"
+                },
+                "id": {
+                    "type": "integer"
+                },
+                "last_error": {
+                    "type": "string"
+                },
+                "last_status_code": {
+                    "type": "integer"
+                },
+                "next_attempt_at": {
+                    "type": "string"
+                },
+                "status": {
+                    "type": "string"
+                },
+                "url": {
+                    "type": "string"
+                },
+                "webhook_id": {
+                    "description": "WebhookID is nil for the callback_url of the command.",
+                    "type": "integer"
                 }
             }
         }
@@ -372,7 +2340,7 @@ const docTemplate = `{
 var SwaggerInfo = &swag.Spec{
 	Version:          "1.0",
 	Host:             "",
-	BasePath:         "/api/commands",
+	BasePath:         "/api",
 	Schemes:          []string{},
 	Title:            "BashAPi service",
 	Description:      "RestAPI for executing bash commands in Docker with a queue system.",
@@ -8,9 +8,17 @@ import (
 	ginSwagger "github.com/swaggo/gin-swagger"
 )
 
-// SetupRoutes sets up the routes for the server.
-func SetupRoutes(router *gin.Engine, commandHandlers *handlers.CommandHandlers, loggerMiddleware gin.HandlerFunc) {
+// SetupRoutes sets up the routes for the server. A nil metricsHandler leaves /metrics out,
+// a nil adminMiddleware leaves /api/admin out.
+func SetupRoutes(router *gin.Engine, commandHandlers *handlers.CommandHandlers, loggerMiddleware, adminMiddleware, metricsHandler gin.HandlerFunc) {
+	// Liveness and readiness probes, registered before the logger to keep them out of the log
+	router.GET("/healthz", commandHandlers.Healthz)
+	router.GET("/readyz", commandHandlers.Readyz)
 	router.Use(loggerMiddleware)
+	if metricsHandler != nil {
+		// Prometheus metrics
+		router.GET("/metrics", metricsHandler)
+	}
 	api := router.Group("/api")
 	{
 		commands := api.Group("/commands")
@@ -21,16 +29,64 @@ func SetupRoutes(router *gin.Engine, commandHandlers *handlers.CommandHandlers,
 			commands.POST("/sudo", commandHandlers.CreateSudoCommand)
 			// Get list of all commands
 			commands.GET("/", commandHandlers.GetCommandsList)
+			// Search scripts and outputs of commands
+			commands.GET("/search", commandHandlers.SearchCommands)
 			// Get one command by its ID
 			commands.GET("/:id", commandHandlers.GetCommandByID)
+			// Get a range, the head, the tail or matching lines of the command output
+			commands.GET("/:id/output", commandHandlers.GetCommandOutput)
+			// Wait until a command finishes
+			commands.GET("/:id/wait", commandHandlers.WaitForCommand)
+			// Get the status transitions of a command
+			commands.GET("/:id/events", commandHandlers.GetCommandEvents)
+			// Get the webhook and callback deliveries of a command
+			commands.GET("/:id/deliveries", commandHandlers.GetCommandDeliveries)
+			// Delete a command with its output
+			commands.DELETE("/:id", commandHandlers.DeleteCommand)
 			// Stop command by ID
 			commands.POST("/:id/stop", commandHandlers.StopCommand)
+			// Stop commands matching a label selector
+			commands.POST("/stop", commandHandlers.StopCommands)
+			// Set or remove labels and annotations of a command
+			commands.PATCH("/:id/labels", commandHandlers.UpdateCommandLabels)
 			// Force start command by ID
 			commands.POST("/:id/fstart", commandHandlers.ForceStartCommand)
+			// Cancel a queued command
+			commands.POST("/:id/cancel", commandHandlers.CancelCommand)
+			// Move a queued command to another position
+			commands.POST("/:id/move", commandHandlers.MoveQueuedCommand)
 			// Get queue list
 			commands.GET("/queue", commandHandlers.GetQueueList)
+			// Get used and available concurrency slots
+			commands.GET("/capacity", commandHandlers.GetCapacity)
+			// Cancel queued commands matching a filter
+			commands.POST("/queue/cancel", commandHandlers.CancelQueuedCommands)
+			// Get current admission decision of the dispatcher
+			commands.GET("/admission", commandHandlers.GetAdmission)
 			// Swagger UI route
 			api.GET("/swagger/*any", ginSwagger.WrapHandler(swaggerFiles.Handler))
 		}
+		// Stream the lifecycle events of all commands over SSE or WebSocket
+		api.GET("/events", commandHandlers.StreamEvents)
+		if adminMiddleware != nil {
+			admin := api.Group("/admin", adminMiddleware)
+			{
+				// Pause and resume dispatching from all queues
+				admin.POST("/pause", commandHandlers.PauseAllQueues)
+				admin.POST("/resume", commandHandlers.ResumeAllQueues)
+				// Pause and resume dispatching from a named queue
+				admin.POST("/queues/:name/pause", commandHandlers.PauseQueue)
+				admin.POST("/queues/:name/resume", commandHandlers.ResumeQueue)
+				// Get list of paused queues
+				admin.GET("/pauses", commandHandlers.GetQueuePauses)
+				// Delete command history matching a filter
+				admin.POST("/purge", commandHandlers.PurgeCommands)
+				// Manage webhooks and inspect their deliveries
+				admin.POST("/webhooks", commandHandlers.CreateWebhook)
+				admin.GET("/webhooks", commandHandlers.GetWebhooks)
+				admin.DELETE("/webhooks/:id", commandHandlers.DeleteWebhook)
+				admin.GET("/webhooks/:id/deliveries", commandHandlers.GetWebhookDeliveries)
+			}
+		}
 	}
 }
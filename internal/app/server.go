@@ -1,17 +1,33 @@
 package server
 
 import (
+	"crypto/subtle"
 	"fmt"
 	"github.com/17HIERARCH70/BashAPI/internal/config"
 	"github.com/17HIERARCH70/BashAPI/internal/handlers"
+	"github.com/17HIERARCH70/BashAPI/internal/lib/archive"
+	"github.com/17HIERARCH70/BashAPI/internal/lib/metrics"
+	"github.com/17HIERARCH70/BashAPI/internal/lib/tracing"
 	services "github.com/17HIERARCH70/BashAPI/internal/services/command"
+	"github.com/17HIERARCH70/BashAPI/internal/storage"
+	"github.com/17HIERARCH70/BashAPI/internal/storage/memory"
+	"github.com/17HIERARCH70/BashAPI/internal/storage/postgresql"
 	"github.com/gin-gonic/gin"
 	"github.com/jackc/pgx/v4/pgxpool"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/trace"
 	"golang.org/x/net/context"
 	"log/slog"
 	"net/http"
 	"os"
 	"os/signal"
+	"strconv"
+	"strings"
 	"syscall"
 	"time"
 )
@@ -24,14 +40,37 @@ type Server struct {
 	Router         *gin.Engine
 	HttpServer     *http.Server
 	CommandService *services.CommandService
+	Handlers       *handlers.CommandHandlers
+
+	stopDispatcher context.CancelFunc
+	stopArchiver   context.CancelFunc
+	stopJanitor    context.CancelFunc
+	stopWebhooks   context.CancelFunc
+	stopEvents     context.CancelFunc
+	stopTracing    func(context.Context) error
 }
 
-// NewServer creates a new HTTP server and sets up routing.
+// NewServer creates a new HTTP server and sets up routing. Without a database the commands
+// are kept in memory.
 func NewServer(cfg *config.Config, log *slog.Logger, db *pgxpool.Pool) *Server {
 	router := gin.New()
-	commandService := services.NewCommandService(db, log, cfg)
+	var store storage.CommandStore = memory.NewStore()
+	if db != nil {
+		store = postgresql.NewStore(db)
+	}
+	commandService := services.NewCommandService(store, log, cfg)
+	// The store is set up even with archiving disabled so that archived outputs stay readable
+	if archiveStore, err := archive.NewFileStore(cfg.Commands.Archive.Dir, cfg.Commands.Archive.Compression); err != nil {
+		log.Error("Output archive is not available", "error", err)
+	} else {
+		commandService.Archive = archiveStore
+	}
 	commandHandlers := handlers.NewCommandHandlers(commandService, log)
 	loggerMiddleware := createLoggerMiddleware(log)
+	adminMiddleware := createAdminAuthMiddleware(cfg.Server.AdminToken)
+	if adminMiddleware == nil {
+		log.Warn("Admin token is not configured, admin endpoints are disabled")
+	}
 
 	httpServer := &http.Server{
 		Addr:         cfg.Server.Host + ":" + fmt.Sprintf("%d", cfg.Server.Port),
@@ -47,27 +86,155 @@ func NewServer(cfg *config.Config, log *slog.Logger, db *pgxpool.Pool) *Server {
 		Router:         router,
 		HttpServer:     httpServer,
 		CommandService: commandService,
+		Handlers:       commandHandlers,
 	}
-	server.executeQueuedCommands()
-	SetupRoutes(router, commandHandlers, loggerMiddleware)
+	server.startEventStream()
+	server.startDispatcher()
+	server.startArchiver()
+	server.startJanitor()
+	server.startWebhooks()
+	server.setupTracing()
+	SetupRoutes(router, commandHandlers, loggerMiddleware, adminMiddleware, server.setupMetrics())
 	return server
 }
 
-func (s *Server) executeQueuedCommands() {
-	s.Logger.Info("Checking for queued commands to execute on startup...")
-	queuedCommands, err := s.CommandService.FetchQueueList()
+// startDispatcher launches the queue dispatcher, which also picks up
+// the commands left in the queue by the previous run.
+func (s *Server) startDispatcher() {
+	ctx, cancel := context.WithCancel(context.Background())
+	s.stopDispatcher = cancel
+	s.CommandService.StartDispatcher(ctx)
+	s.Logger.Info("Queued commands are being processed...")
+}
+
+// startEventStream listens to the command events of all replicas for the event stream.
+func (s *Server) startEventStream() {
+	ctx, cancel := context.WithCancel(context.Background())
+	s.stopEvents = cancel
+	s.CommandService.StartEventStream(ctx)
+}
+
+// startArchiver launches the output archiver when archiving is enabled.
+func (s *Server) startArchiver() {
+	s.stopArchiver = func() {}
+	if !s.Config.Commands.Archive.Enabled || s.CommandService.Archive == nil {
+		return
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	s.stopArchiver = cancel
+	s.CommandService.StartArchiver(ctx)
+	s.Logger.Info("Output archiver started", "dir", s.Config.Commands.Archive.Dir, "after_days", s.Config.Commands.Archive.AfterDays)
+}
+
+// startJanitor launches the retention janitor when retention is enabled.
+func (s *Server) startJanitor() {
+	s.stopJanitor = func() {}
+	if !s.Config.Commands.Retention.Enabled {
+		return
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	s.stopJanitor = cancel
+	s.CommandService.StartJanitor(ctx)
+	s.Logger.Info("Retention janitor started", "rules", len(s.Config.Commands.Retention.Rules))
+}
+
+// startWebhooks launches the webhook delivery when webhooks are enabled.
+func (s *Server) startWebhooks() {
+	s.stopWebhooks = func() {}
+	if !s.Config.Commands.Webhooks.Enabled {
+		return
+	}
+	if s.Config.Commands.Webhooks.Secret == "" {
+		s.Logger.Warn("Webhook secret is not configured, callbacks and webhooks without a secret are not signed")
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	s.stopWebhooks = cancel
+	s.CommandService.StartWebhooks(ctx)
+	s.Logger.Info("Webhook delivery started")
+}
+
+// setupMetrics registers the metrics collectors and the HTTP middleware when metrics are
+// enabled, and returns the handler of /metrics.
+func (s *Server) setupMetrics() gin.HandlerFunc {
+	if !s.Config.Server.Metrics {
+		return nil
+	}
+	collectors := []prometheus.Collector{metrics.NewCommandCollector(s.CommandService.FetchCommandStats)}
+	if s.DB != nil {
+		collectors = append(collectors, metrics.NewPoolCollector(s.DB.Stat))
+	}
+	registry, err := metrics.NewRegistry(collectors...)
+	if err != nil {
+		s.Logger.Error("Metrics are not available", "error", err)
+		return nil
+	}
+	s.Router.Use(createMetricsMiddleware())
+	return gin.WrapH(promhttp.HandlerFor(registry, promhttp.HandlerOpts{}))
+}
+
+// setupTracing starts the export of traces and traces every request when tracing is enabled.
+func (s *Server) setupTracing() {
+	s.stopTracing = func(context.Context) error { return nil }
+	cfg := s.Config.Tracing
+	if !cfg.Enabled {
+		return
+	}
+	shutdown, err := tracing.Setup(context.Background(), tracing.Options{
+		Endpoint:    cfg.Endpoint,
+		ServiceName: cfg.ServiceName,
+		SampleRatio: cfg.SampleRatio,
+	})
 	if err != nil {
-		s.Logger.Error("Failed to fetch queued commands", "error", err)
+		s.Logger.Error("Tracing is not available", "error", err)
 		return
 	}
+	s.stopTracing = shutdown
+	s.Router.Use(createTracingMiddleware())
+	s.Logger.Info("Tracing enabled", "endpoint", cfg.Endpoint, "sample_ratio", cfg.SampleRatio)
+}
+
+// createTracingMiddleware starts a server span for every request, continuing the trace of
+// the caller when the request carries a traceparent header.
+func createTracingMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		route := c.FullPath()
+		if route == "" {
+			route = "unmatched"
+		}
+		ctx := otel.GetTextMapPropagator().Extract(c.Request.Context(), propagation.HeaderCarrier(c.Request.Header))
+		ctx, span := tracing.Tracer().Start(ctx, c.Request.Method+" "+route,
+			trace.WithSpanKind(trace.SpanKindServer),
+			trace.WithAttributes(
+				attribute.String("http.request.method", c.Request.Method),
+				attribute.String("http.route", route),
+				attribute.String("url.path", c.Request.URL.Path),
+				attribute.String("client.address", c.ClientIP()),
+			))
+		defer span.End()
+		c.Request = c.Request.WithContext(ctx)
 
-	for _, queueItem := range queuedCommands {
-		queueItem := queueItem
-		go func() {
-			_, _ = s.CommandService.ForceStartCommand(queueItem.CommandId)
-		}()
+		c.Next()
+		status := c.Writer.Status()
+		span.SetAttributes(attribute.Int("http.response.status_code", status))
+		if status >= http.StatusInternalServerError {
+			span.SetStatus(codes.Error, http.StatusText(status))
+		}
+	}
+}
+
+// createMetricsMiddleware counts requests and measures their latency per route. Requests
+// that match no route share one series, so that scanning for paths cannot add new ones.
+func createMetricsMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		startTime := time.Now()
+		c.Next()
+		route := c.FullPath()
+		if route == "" {
+			route = "unmatched"
+		}
+		metrics.HTTPRequests.WithLabelValues(c.Request.Method, route, strconv.Itoa(c.Writer.Status())).Inc()
+		metrics.HTTPDuration.WithLabelValues(c.Request.Method, route).Observe(time.Since(startTime).Seconds())
 	}
-	s.Logger.Info("Queued commands are being processed...")
 }
 
 // createLoggerMiddleware creates middleware for logging requests using slog.
@@ -88,6 +255,22 @@ func createLoggerMiddleware(log *slog.Logger) gin.HandlerFunc {
 	}
 }
 
+// createAdminAuthMiddleware requires the admin token as a bearer token. Without a token it
+// returns nil, so that the admin endpoints are not served at all.
+func createAdminAuthMiddleware(token string) gin.HandlerFunc {
+	if token == "" {
+		return nil
+	}
+	return func(c *gin.Context) {
+		provided := strings.TrimPrefix(c.GetHeader("Authorization"), "Bearer ")
+		if subtle.ConstantTimeCompare([]byte(provided), []byte(token)) != 1 {
+			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "Admin token is required"})
+			return
+		}
+		c.Next()
+	}
+}
+
 // Start runs the HTTP server on a specific address.
 func (s *Server) Start(address string) {
 	if err := s.Router.Run(address); err != nil {
@@ -95,20 +278,35 @@ func (s *Server) Start(address string) {
 	}
 }
 
-// GracefulShutdown handles the graceful shutdown of the server,
-// stopping all running commands and preserving the queue.
+// GracefulShutdown handles the graceful shutdown of the server, waiting for, stopping or
+// requeueing the running commands as configured and preserving the queue.
 func (s *Server) GracefulShutdown() {
 	stopChan := make(chan os.Signal, 1)
 	signal.Notify(stopChan, os.Interrupt, syscall.SIGTERM)
 	<-stopChan // Wait for SIGINT or SIGTERM
 
-	s.Logger.Info("Initiating graceful shutdown, stopping all running commands.")
+	s.Logger.Info("Initiating graceful shutdown.", "mode", s.Config.Commands.Shutdown.Mode)
 
-	// Stop all running commands
-	if err := s.CommandService.StopAllRunningCommands(); err != nil {
-		s.Logger.Error("Failed to stop running commands during shutdown", "error", err)
+	// Fail readiness first and give load balancers time to drain the traffic
+	s.Handlers.BeginShutdown()
+	if drain := time.Duration(s.Config.Server.ShutdownDrain) * time.Second; drain > 0 {
+		s.Logger.Info("Draining traffic before stopping commands", "drain", drain)
+		time.Sleep(drain)
+	}
+
+	// Keep queued commands queued
+	s.stopDispatcher()
+	s.stopArchiver()
+	s.stopJanitor()
+	// Events of the stopped commands are delivered after the next start
+	s.stopWebhooks()
+	s.stopEvents()
+
+	// Wait for, stop or requeue the running commands, refusing new ones meanwhile
+	if err := s.CommandService.ShutdownRunningCommands(); err != nil {
+		s.Logger.Error("Failed to shut down running commands", "mode", s.Config.Commands.Shutdown.Mode, "error", err)
 	} else {
-		s.Logger.Info("All running commands have been stopped.")
+		s.Logger.Info("Running commands have been shut down.", "mode", s.Config.Commands.Shutdown.Mode)
 	}
 
 	// Shutdown the HTTP server with a timeout context
@@ -119,6 +317,14 @@ func (s *Server) GracefulShutdown() {
 	} else {
 		s.Logger.Info("HTTP server shutdown successfully")
 	}
+	// Flush the pending spans
+	if err := s.stopTracing(ctx); err != nil {
+		s.Logger.Error("Failed to flush traces", "error", err)
+	}
+	if s.DB == nil {
+		s.Logger.Info("Server shutdown complete. Commands kept in memory are gone.")
+		return
+	}
 	// Close the database connection
 	s.DB.Close()
 	s.Logger.Info("Database connection closed successfully")
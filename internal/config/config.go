@@ -6,17 +6,31 @@ import (
 	"os"
 )
 
+// Storages the commands can be kept in. The memory storage keeps them in the process only,
+// which suits development and tests.
+const (
+	StoragePostgres = "postgres"
+	StorageMemory   = "memory"
+)
+
 type Config struct {
 	Env      string         `yaml:"env" env-default:"local"`
+	Storage  string         `yaml:"storage" env:"BASHAPI_STORAGE" env-default:"postgres"`
 	Server   ServerConfig   `yaml:"server"`
 	Postgres PostgresConfig `yaml:"postgres"`
 	Commands CommandsConfig `yaml:"commands"`
+	Tracing  TracingConfig  `yaml:"tracing"`
 }
 type ServerConfig struct {
 	Host         string `yaml:"host" env-default:"localhost"`
 	Port         int    `yaml:"port" env-default:"8080"`
 	ReadTimeout  int    `yaml:"read_timeout" env-default:"10"`
 	WriteTimeout int    `yaml:"write_timeout" env-default:"10"`
+	AdminToken   string `yaml:"admin_token" env:"BASHAPI_ADMIN_TOKEN"`
+	Metrics      bool   `yaml:"metrics" env-default:"true"`
+	// ShutdownDrain is how long in seconds the server keeps serving with failing readiness
+	// before it stops the commands, so that load balancers move the traffic away.
+	ShutdownDrain int `yaml:"shutdown_drain" env-default:"5"`
 }
 type PostgresConfig struct {
 	Host     string `yaml:"host" env-default:"localhost"`
@@ -25,11 +39,99 @@ type PostgresConfig struct {
 	Password string `yaml:"password"`
 	Database string `yaml:"database" env-default:"SocialManagerDB"`
 	SSLMode  string `yaml:"ssl_mode" env-default:"disable"`
+	// AutoMigrate applies the pending embedded migrations before the server starts.
+	AutoMigrate bool `yaml:"auto_migrate" env:"BASHAPI_AUTO_MIGRATE" env-default:"false"`
+}
+
+// TracingConfig controls the export of OpenTelemetry traces to an OTLP/HTTP collector.
+type TracingConfig struct {
+	Enabled     bool    `yaml:"enabled" env-default:"false"`
+	Endpoint    string  `yaml:"endpoint" env:"OTEL_EXPORTER_OTLP_ENDPOINT" env-default:"http://localhost:4318"`
+	ServiceName string  `yaml:"service_name" env-default:"bashapi"`
+	SampleRatio float64 `yaml:"sample_ratio" env-default:"1"`
 }
 
 type CommandsConfig struct {
-	MaxConcurrent int `yaml:"max_concurrent" env-default:"100"`
-	Timeout       int `yaml:"timeout" env-default:"100"`
+	MaxConcurrent  int             `yaml:"max_concurrent" env-default:"100"`
+	Capacity       int             `yaml:"capacity" env-default:"0"`
+	Timeout        int             `yaml:"timeout" env-default:"100"`
+	IdempotencyTTL int             `yaml:"idempotency_ttl" env-default:"86400"`
+	QueueTTL       int             `yaml:"queue_ttl" env-default:"0"`
+	MaxWait        int             `yaml:"max_wait" env-default:"300"`
+	Admission      AdmissionConfig `yaml:"admission"`
+	Output         OutputConfig    `yaml:"output"`
+	Archive        ArchiveConfig   `yaml:"archive"`
+	Retention      RetentionConfig `yaml:"retention"`
+	Webhooks       WebhooksConfig  `yaml:"webhooks"`
+	Shutdown       ShutdownConfig  `yaml:"shutdown"`
+}
+
+// ShutdownConfig decides what happens to the running commands when the server stops: they
+// are stopped, waited for up to Timeout seconds and stopped after it, or requeued to run
+// again on the next start.
+type ShutdownConfig struct {
+	Mode    string `yaml:"mode" env-default:"stop"`
+	Timeout int    `yaml:"timeout" env-default:"300"`
+}
+
+// OutputConfig controls how command output is buffered, returned, indexed and limited.
+type OutputConfig struct {
+	FlushBytes    int    `yaml:"flush_bytes" env-default:"65536"`
+	FlushInterval int    `yaml:"flush_interval" env-default:"3"`
+	InlineLimit   int    `yaml:"inline_limit" env-default:"1048576"`
+	SearchLimit   int    `yaml:"search_limit" env-default:"262144"`
+	MaxSize       int64  `yaml:"max_size" env-default:"0"`
+	MaxSizeLimit  int64  `yaml:"max_size_limit" env-default:"0"`
+	Policy        string `yaml:"policy" env-default:"head"`
+	KillOnLimit   bool   `yaml:"kill_on_limit" env-default:"false"`
+}
+
+type ArchiveConfig struct {
+	Enabled     bool   `yaml:"enabled" env-default:"false"`
+	Dir         string `yaml:"dir" env-default:"./archive"`
+	Compression string `yaml:"compression" env-default:"zstd"`
+	AfterDays   int    `yaml:"after_days" env-default:"30"`
+	Interval    int    `yaml:"interval" env-default:"3600"`
+	BatchSize   int    `yaml:"batch_size" env-default:"100"`
+}
+
+// WebhooksConfig controls the delivery of command events to webhooks. Secret signs the
+// deliveries of callback URLs and of webhooks created without their own secret.
+type WebhooksConfig struct {
+	Enabled     bool   `yaml:"enabled" env-default:"false"`
+	Secret      string `yaml:"secret" env:"BASHAPI_WEBHOOK_SECRET"`
+	Interval    int    `yaml:"interval" env-default:"2"`
+	Timeout     int    `yaml:"timeout" env-default:"10"`
+	MaxAttempts int    `yaml:"max_attempts" env-default:"8"`
+	Backoff     int    `yaml:"backoff" env-default:"10"`
+	MaxBackoff  int    `yaml:"max_backoff" env-default:"3600"`
+	BatchSize   int    `yaml:"batch_size" env-default:"50"`
+}
+
+type RetentionConfig struct {
+	Enabled  bool            `yaml:"enabled" env-default:"false"`
+	Interval int             `yaml:"interval" env-default:"3600"`
+	Rules    []RetentionRule `yaml:"rules"`
+}
+
+// RetentionRule deletes finished commands of a queue ("" or "*" for every queue) with one
+// of the statuses (all finished ones when empty) once they are older than MaxAgeDays or
+// no longer among the newest MaxCount of them. Zero disables a limit.
+type RetentionRule struct {
+	Queue      string   `yaml:"queue"`
+	Statuses   []string `yaml:"statuses"`
+	MaxAgeDays int      `yaml:"max_age_days"`
+	MaxCount   int      `yaml:"max_count"`
+}
+
+// AdmissionConfig holds the host load thresholds above which queued commands are held back.
+// A zero threshold is not checked.
+type AdmissionConfig struct {
+	Enabled              bool    `yaml:"enabled" env-default:"false"`
+	ProcPath             string  `yaml:"proc_path" env-default:"/proc"`
+	MaxLoadPerCPU        float64 `yaml:"max_load_per_cpu" env-default:"0"`
+	MinAvailableMemoryMB int     `yaml:"min_available_memory_mb" env-default:"0"`
+	MaxCPUPressure       float64 `yaml:"max_cpu_pressure" env-default:"0"`
 }
 
 func MustLoad() *Config {
@@ -1,15 +1,116 @@
 package models
 
-import "time"
+import (
+	"github.com/17HIERARCH70/BashAPI/internal/lib/labels"
+	"time"
+)
 
 type Command struct {
-	ID        int
-	Script    string
-	Status    string
-	PID       *int
-	Output    string
-	CreatedAt time.Time
-	UpdatedAt time.Time
+	ID         int
+	Script     string
+	Status     string
+	PID        *int
+	Weight     int
+	Queue      string
+	Output     string
+	OutputSize int64
+	// OutputTruncated is set once output was dropped because of the output limit.
+	OutputTruncated bool
+	// OutputArchivedAt is set once the output was moved to the archive.
+	OutputArchivedAt *time.Time
+	CreatedAt        time.Time
+	UpdatedAt        time.Time
+	StartedAt        *time.Time
+	FinishedAt       *time.Time
+	Submitter        string
+	Tags             []string
+	ExitCode         *int
+	// Labels can be selected by, annotations only carry information.
+	Labels      map[string]string
+	Annotations map[string]string
+}
+
+// CommandOptions holds the optional parameters of a command creation request.
+type CommandOptions struct {
+	// IdempotencyKey is taken from the Idempotency-Key header, not from the body.
+	IdempotencyKey string        `json:"-"`
+	Locks          []CommandLock `json:"locks,omitempty"`
+	// Weight is the number of concurrency slots the command occupies, 1 by default.
+	Weight int `json:"weight,omitempty"`
+	// Queue is the named queue the command waits in, "default" when omitted.
+	Queue string `json:"queue,omitempty"`
+	// TTL is how many seconds the command may wait in the queue before it expires.
+	TTL int `json:"ttl,omitempty"`
+	// MaxOutput is the number of output bytes kept, the configured default when omitted.
+	MaxOutput int64 `json:"max_output,omitempty"`
+	// OutputPolicy selects which part of an oversized output is kept: head, tail or head_tail.
+	OutputPolicy string `json:"output_policy,omitempty"`
+	// KillOnOutputLimit kills the command as soon as its output exceeds MaxOutput.
+	KillOnOutputLimit bool `json:"kill_on_output_limit,omitempty"`
+	// Tags are free-form markers the command list can be filtered by.
+	Tags []string `json:"tags,omitempty"`
+	// Labels are key/value pairs the commands can be selected by, e.g. with "env=prod,team!=infra".
+	Labels map[string]string `json:"labels,omitempty"`
+	// Annotations are free-form key/value pairs that cannot be selected by.
+	Annotations map[string]string `json:"annotations,omitempty"`
+	// CallbackURL receives a signed POST on every status change of the command.
+	CallbackURL string `json:"callback_url,omitempty"`
+	// Submitter is taken from the X-Submitter header or the client address, not from the body.
+	Submitter string `json:"-"`
+	// TraceParent is the trace context of the creating request, not taken from the body.
+	TraceParent string `json:"-"`
+}
+
+// Output policies decide which part of an output exceeding its limit is kept.
+const (
+	OutputKeepHead     = "head"
+	OutputKeepTail     = "tail"
+	OutputKeepHeadTail = "head_tail"
+)
+
+// Shutdown modes decide what happens to the running commands when the server stops.
+const (
+	ShutdownWait    = "wait"
+	ShutdownStop    = "stop"
+	ShutdownRequeue = "requeue"
+)
+
+// OutputTruncationMarker stands in for the dropped part of a truncated output.
+const OutputTruncationMarker = "\n[... output truncated ...]\n"
+
+const (
+	LockExclusive = "exclusive"
+	LockShared    = "shared"
+)
+
+// CommandLock is a named resource a command holds while it is running.
+// Shared locks on the same key can be held together, an exclusive one cannot.
+type CommandLock struct {
+	Key  string `json:"key"`
+	Mode string `json:"mode,omitempty"`
+}
+
+// IsExclusive reports whether the lock is exclusive, which is the default mode.
+func (l CommandLock) IsExclusive() bool {
+	return l.Mode != LockShared
+}
+
+// LabelUpdate changes the labels and annotations of a command. Listed keys are set,
+// keys with a null value are removed and the others are kept.
+type LabelUpdate struct {
+	Labels      map[string]*string `json:"labels"`
+	Annotations map[string]*string `json:"annotations"`
+}
+
+// CommandLabels are the labels and annotations of a command.
+type CommandLabels struct {
+	Labels      map[string]string `json:"labels"`
+	Annotations map[string]string `json:"annotations"`
+}
+
+// SelectorFilter selects commands by their labels for bulk operations.
+type SelectorFilter struct {
+	Selector labels.Selector `json:"selector" swaggertype:"string"`
 }
 
 type Message struct {
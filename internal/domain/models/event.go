@@ -0,0 +1,83 @@
+package models
+
+import (
+	"github.com/17HIERARCH70/BashAPI/internal/lib/labels"
+	"slices"
+	"time"
+)
+
+// Statuses of a command.
+const (
+	StatusWaiting   = "waiting"
+	StatusRunning   = "running"
+	StatusCompleted = "completed"
+	StatusError     = "error"
+	StatusTimeout   = "timeout"
+	StatusStopped   = "stopped"
+	StatusCancelled = "cancelled"
+	StatusExpired   = "expired"
+)
+
+// FinishedStatuses are the final statuses of a command.
+var FinishedStatuses = []string{StatusCompleted, StatusError, StatusTimeout, StatusStopped, StatusCancelled, StatusExpired}
+
+// IsFinished reports whether a command reached a final status.
+func IsFinished(status string) bool {
+	return slices.Contains(FinishedStatuses, status)
+}
+
+// CommandEvent is one status transition of a command.
+type CommandEvent struct {
+	ID        int64 `json:"id"`
+	CommandID int   `json:"command_id"`
+	// From is nil for the creation of the command.
+	From *string `json:"from"`
+	To   string  `json:"to"`
+	// Actor is the part of the service that changed the status, e.g. "api" or "dispatcher".
+	Actor     string    `json:"actor"`
+	Reason    string    `json:"reason"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// LifecycleEvent is a status transition together with the command details subscribers filter
+// and act on. It is the body of webhook deliveries and the message of the event stream.
+type LifecycleEvent struct {
+	// Event is the name of the reached status, e.g. "started" or "failed", see StatusEvents.
+	Event          string  `json:"event"`
+	EventID        int64   `json:"event_id"`
+	CommandID      int     `json:"command_id"`
+	Status         string  `json:"status"`
+	PreviousStatus *string `json:"previous_status"`
+	Actor          string  `json:"actor"`
+	Reason         string  `json:"reason"`
+	// ExitCode is only set once the command has finished.
+	ExitCode   *int              `json:"exit_code"`
+	Queue      string            `json:"queue"`
+	Labels     map[string]string `json:"labels"`
+	OccurredAt time.Time         `json:"occurred_at"`
+}
+
+// EventFilter selects the lifecycle events of the event stream. Empty fields match every event.
+type EventFilter struct {
+	// Namespace is the queue of the commands.
+	Namespace string
+	Selector  labels.Selector
+	// Events are event names, see StatusEvents.
+	Events []string
+	// After replays the stored events with a higher ID before the live ones, 0 replays nothing.
+	After int64
+	// Since also replays the events up to After that occurred at Since or later. IDs follow
+	// the insert order but events commit in any order, so these may have committed after After.
+	Since time.Time
+}
+
+// Matches reports whether the event passes the namespace, events and selector of the filter.
+func (f EventFilter) Matches(event LifecycleEvent) bool {
+	if f.Namespace != "" && f.Namespace != event.Queue {
+		return false
+	}
+	if len(f.Events) > 0 && !slices.Contains(f.Events, event.Event) {
+		return false
+	}
+	return f.Selector.Matches(event.Labels)
+}
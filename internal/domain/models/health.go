@@ -0,0 +1,22 @@
+package models
+
+// Health statuses of the service and of its checks.
+const (
+	HealthOK      = "ok"
+	HealthFailing = "failing"
+)
+
+// HealthCheck is the result of one readiness check.
+type HealthCheck struct {
+	Name   string `json:"name"`
+	Status string `json:"status"`
+	// Detail describes the checked state, e.g. the schema version.
+	Detail string `json:"detail,omitempty"`
+	Error  string `json:"error,omitempty"`
+}
+
+// Readiness tells whether the service accepts traffic, failing when any check fails.
+type Readiness struct {
+	Status string        `json:"status"`
+	Checks []HealthCheck `json:"checks"`
+}
@@ -0,0 +1,43 @@
+package models
+
+import (
+	"github.com/17HIERARCH70/BashAPI/internal/lib/labels"
+	"time"
+)
+
+// Sort columns of the command list.
+const (
+	SortByID        = "id"
+	SortByCreatedAt = "created_at"
+	SortByUpdatedAt = "updated_at"
+)
+
+// CommandQuery selects one page of the command list. Set filters are combined with AND.
+type CommandQuery struct {
+	Statuses      []string
+	CreatedAfter  *time.Time
+	CreatedBefore *time.Time
+	UpdatedAfter  *time.Time
+	UpdatedBefore *time.Time
+	Submitter     string
+	// Tags selects commands carrying every one of the tags.
+	Tags           []string
+	ScriptContains string
+	ExitCode       *int
+	Labels         labels.Selector
+
+	Sort string
+	Desc bool
+	// Cursor continues the list after the last command of the previous page.
+	Cursor string
+	Limit  int
+	// WithOutput loads the output of every command, which is left out by default.
+	WithOutput bool
+}
+
+// CommandPage is one page of the command list.
+type CommandPage struct {
+	Items []Command
+	// NextCursor is empty on the last page.
+	NextCursor string
+}
@@ -0,0 +1,36 @@
+package models
+
+import "regexp"
+
+// OutputQuery selects a part of a command output. Head, Tail and Grep switch from a
+// byte range to line mode, where lines are searched within the byte range.
+type OutputQuery struct {
+	Offset int64
+	// Limit is the maximum number of bytes to read from Offset, 0 reads to the end.
+	Limit int64
+	Head  int
+	Tail  int
+	Grep  *regexp.Regexp
+}
+
+// IsLineMode reports whether the query selects lines rather than raw bytes.
+func (q OutputQuery) IsLineMode() bool {
+	return q.Head > 0 || q.Tail > 0 || q.Grep != nil
+}
+
+// CommandOutput is the selected part of a command output.
+type CommandOutput struct {
+	CommandID int `json:"command_id"`
+	// Size is the length of the kept output, including the truncation marker.
+	Size int64 `json:"size"`
+	// Truncated is set when part of the output was dropped because of the output limit.
+	Truncated bool `json:"truncated"`
+	// Dropped is the number of output bytes that were not kept.
+	Dropped int64 `json:"dropped,omitempty"`
+	Offset  int64 `json:"offset"`
+	// NextOffset is where the following byte range starts, set for byte range queries only.
+	NextOffset *int64 `json:"next_offset,omitempty"`
+	// Lines is the number of selected lines, set for line queries only.
+	Lines  *int   `json:"lines,omitempty"`
+	Output string `json:"output"`
+}
@@ -1,7 +1,92 @@
 package models
 
+import (
+	"github.com/17HIERARCH70/BashAPI/internal/lib/labels"
+	"time"
+)
+
 type Queue struct {
 	CommandId int
 	QueueId   int
 	Status    string
+	Script    string `json:"-"`
+	Weight    int
+	// Position is the 1-based place in dispatch order, Ahead the number of commands before it.
+	Position int
+	Ahead    int
+	// EstimatedStartAt is nil when the command is held back, e.g. by a paused queue.
+	EstimatedStartAt *time.Time
+	Queue            string
+	Paused           bool
+	// ExpiresAt is when the command gives up waiting and becomes expired.
+	ExpiresAt *time.Time
+	// BlockedBy is the running command holding a lock this one waits for.
+	BlockedBy *int
+}
+
+const (
+	// DefaultQueue is the named queue of commands submitted without one.
+	DefaultQueue = "default"
+	// AllQueues is the queue name under which the global pause is stored.
+	AllQueues = "*"
+)
+
+// QueuePause records that dispatching from a named queue, or from all of them, is paused.
+type QueuePause struct {
+	Queue    string    `json:"queue"`
+	Reason   string    `json:"reason"`
+	PausedAt time.Time `json:"paused_at"`
+}
+
+// QueueFilter selects queued commands for bulk operations. Set fields are combined with AND.
+type QueueFilter struct {
+	All            bool       `json:"all"`
+	IDs            []int      `json:"ids"`
+	Queue          string     `json:"queue"`
+	ScriptContains string     `json:"script_contains"`
+	CreatedBefore  *time.Time `json:"created_before"`
+	// Selector selects commands by their labels, e.g. "env=prod,team!=infra".
+	Selector labels.Selector `json:"selector" swaggertype:"string"`
+}
+
+// IsEmpty reports whether the filter selects nothing on its own.
+func (f QueueFilter) IsEmpty() bool {
+	return !f.All && len(f.IDs) == 0 && f.Queue == "" && f.ScriptContains == "" && f.CreatedBefore == nil &&
+		len(f.Selector) == 0
+}
+
+// Capacity describes the concurrency slots shared by running commands.
+type Capacity struct {
+	Total     int `json:"total"`
+	Used      int `json:"used"`
+	Available int `json:"available"`
+}
+
+// Admission is the decision of the dispatcher whether new commands may start
+// right now, with the host metrics it is based on.
+type Admission struct {
+	Enabled    bool                `json:"enabled"`
+	Admit      bool                `json:"admit"`
+	Reasons    []string            `json:"reasons"`
+	Error      string              `json:"error,omitempty"`
+	Metrics    *HostMetrics        `json:"metrics"`
+	Thresholds AdmissionThresholds `json:"thresholds"`
+	CheckedAt  time.Time           `json:"checked_at"`
+}
+
+type HostMetrics struct {
+	CPUs              int      `json:"cpus"`
+	Load1             float64  `json:"load1"`
+	Load5             float64  `json:"load5"`
+	Load15            float64  `json:"load15"`
+	LoadPerCPU        float64  `json:"load_per_cpu"`
+	MemTotalBytes     uint64   `json:"mem_total_bytes"`
+	MemAvailableBytes uint64   `json:"mem_available_bytes"`
+	CPUPressure       *float64 `json:"cpu_pressure_avg10"`
+}
+
+type AdmissionThresholds struct {
+	MaxLoadPerCPU        float64 `json:"max_load_per_cpu"`
+	MinAvailableMemoryMB int     `json:"min_available_memory_mb"`
+	MaxCPUPressure       float64 `json:"max_cpu_pressure"`
 }
@@ -0,0 +1,37 @@
+package models
+
+import (
+	"github.com/17HIERARCH70/BashAPI/internal/lib/labels"
+	"time"
+)
+
+// PurgeFilter selects commands to delete for good. Set fields are combined with AND,
+// running commands are never deleted.
+type PurgeFilter struct {
+	All   bool   `json:"all"`
+	IDs   []int  `json:"ids"`
+	Queue string `json:"queue"`
+	// Statuses defaults to every finished status, "waiting" has to be listed explicitly.
+	Statuses       []string   `json:"statuses"`
+	FinishedBefore *time.Time `json:"finished_before"`
+	// Selector selects commands by their labels, e.g. "env=prod,team!=infra".
+	Selector labels.Selector `json:"selector" swaggertype:"string"`
+	// KeepLast spares the newest matching commands of every queue.
+	KeepLast int  `json:"keep_last"`
+	DryRun   bool `json:"dry_run"`
+}
+
+// IsEmpty reports whether the filter selects nothing on its own.
+func (f PurgeFilter) IsEmpty() bool {
+	return !f.All && len(f.IDs) == 0 && f.Queue == "" && len(f.Statuses) == 0 && f.FinishedBefore == nil && f.KeepLast == 0 &&
+		len(f.Selector) == 0
+}
+
+// PurgeResult lists the commands deleted by a purge, or that a dry run would delete.
+type PurgeResult struct {
+	DryRun bool  `json:"dry_run"`
+	Count  int   `json:"count"`
+	IDs    []int `json:"ids"`
+	// ArchivedOutputs is the number of archived output files removed with the commands.
+	ArchivedOutputs int `json:"archived_outputs"`
+}
@@ -0,0 +1,23 @@
+package models
+
+// SearchQuery selects one page of the commands whose script or output matches Text.
+// The filters, the sort order and the page work as in the command list.
+type SearchQuery struct {
+	Text string
+	CommandQuery
+}
+
+// SearchResult is a matching command with the matches of its script and output
+// highlighted in <mark> tags. A snippet is empty when its text does not match.
+type SearchResult struct {
+	Command       Command `json:"command"`
+	ScriptSnippet string  `json:"script_snippet"`
+	OutputSnippet string  `json:"output_snippet"`
+}
+
+// SearchPage is one page of search results.
+type SearchPage struct {
+	Items []SearchResult
+	// NextCursor is empty on the last page.
+	NextCursor string
+}
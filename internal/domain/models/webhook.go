@@ -0,0 +1,79 @@
+package models
+
+import "time"
+
+// Webhook events, one per status a command can reach.
+const (
+	EventQueued    = "queued"
+	EventStarted   = "started"
+	EventCompleted = "completed"
+	EventFailed    = "failed"
+	EventTimeout   = "timeout"
+	EventStopped   = "stopped"
+	EventCancelled = "cancelled"
+	EventExpired   = "expired"
+)
+
+// StatusEvents maps the statuses of commands to the webhook events sent when they are reached.
+var StatusEvents = map[string]string{
+	StatusWaiting:   EventQueued,
+	StatusRunning:   EventStarted,
+	StatusCompleted: EventCompleted,
+	StatusError:     EventFailed,
+	StatusTimeout:   EventTimeout,
+	StatusStopped:   EventStopped,
+	StatusCancelled: EventCancelled,
+	StatusExpired:   EventExpired,
+}
+
+// Webhook is a global subscription to command events.
+type Webhook struct {
+	ID  int    `json:"id"`
+	URL string `json:"url"`
+	// Secret signs the deliveries, the configured one is used when empty. It is never returned.
+	Secret string `json:"secret,omitempty"`
+	// Events lists the subscribed events, all of them when empty.
+	Events    []string  `json:"events"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// Delivery statuses.
+const (
+	DeliveryPending   = "pending"
+	DeliveryDelivered = "delivered"
+	DeliveryFailed    = "failed"
+)
+
+// WebhookDelivery is one event sent to one subscriber, with every attempt made so far.
+type WebhookDelivery struct {
+	ID int64 `json:"id"`
+	// WebhookID is nil for the callback_url of the command.
+	WebhookID      *int             `json:"webhook_id"`
+	CommandID      int              `json:"command_id"`
+	Event          string           `json:"event"`
+	URL            string           `json:"url"`
+	Status         string           `json:"status"`
+	Attempts       int              `json:"attempts"`
+	NextAttemptAt  *time.Time       `json:"next_attempt_at"`
+	LastStatusCode *int             `json:"last_status_code"`
+	LastError      string           `json:"last_error"`
+	CreatedAt      time.Time        `json:"created_at"`
+	DeliveredAt    *time.Time       `json:"delivered_at"`
+	AttemptLog     []WebhookAttempt `json:"attempt_log"`
+}
+
+// WebhookAttempt is one HTTP request of a delivery. StatusCode is nil when no response came.
+type WebhookAttempt struct {
+	Attempt    int       `json:"attempt"`
+	StatusCode *int      `json:"status_code"`
+	Error      string    `json:"error"`
+	DurationMs int       `json:"duration_ms"`
+	CreatedAt  time.Time `json:"created_at"`
+}
+
+// DeliveryQuery selects the deliveries of a command or of a webhook.
+type DeliveryQuery struct {
+	CommandID *int
+	WebhookID *int
+	Limit     int
+}
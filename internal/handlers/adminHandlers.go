@@ -0,0 +1,178 @@
+package handlers
+
+import (
+	"errors"
+	"github.com/17HIERARCH70/BashAPI/internal/domain/models"
+	services "github.com/17HIERARCH70/BashAPI/internal/services/command"
+	"github.com/gin-gonic/gin"
+	"net/http"
+)
+
+// pauseRequest is the optional body of the pause endpoints.
+type pauseRequest struct {
+	Reason string `json:"reason"`
+}
+
+// PauseAllQueues godoc
+//
+//	@Summary		Pause dispatching
+//	@Description	Stop starting queued commands from every queue. Running commands keep running
+//	@Description	and new commands are still accepted into the queue. The state survives restarts.
+//	@Tags			Admin
+//	@Accept			json
+//	@Produce		json
+//	@Param			pause	body		string			false	"Reason of the pause"
+//	@Success		200		{object}	models.Message	"Dispatching paused"
+//	@Failure		400		{object}	models.Error	"Invalid request body"
+//	@Failure		500		{object}	models.Error	"Problem on server side"
+//	@Router			/admin/pause [post]
+func (h *CommandHandlers) PauseAllQueues(c *gin.Context) {
+	h.pauseQueue(c, models.AllQueues)
+}
+
+// ResumeAllQueues godoc
+//
+//	@Summary		Resume dispatching
+//	@Description	Lift the global pause. Queues paused by name stay paused.
+//	@Tags			Admin
+//	@Produce		json
+//	@Success		200	{object}	models.Message	"Dispatching resumed"
+//	@Failure		409	{object}	models.Error	"Dispatching is not paused"
+//	@Failure		500	{object}	models.Error	"Problem on server side"
+//	@Router			/admin/resume [post]
+func (h *CommandHandlers) ResumeAllQueues(c *gin.Context) {
+	h.resumeQueue(c, models.AllQueues)
+}
+
+// PauseQueue godoc
+//
+//	@Summary		Pause a named queue
+//	@Description	Stop starting queued commands of one named queue. The state survives restarts.
+//	@Tags			Admin
+//	@Accept			json
+//	@Produce		json
+//	@Param			name	path		string			true	"Queue name"
+//	@Param			pause	body		string			false	"Reason of the pause"
+//	@Success		200		{object}	models.Message	"Queue paused"
+//	@Failure		400		{object}	models.Error	"Invalid queue name or request body"
+//	@Failure		500		{object}	models.Error	"Problem on server side"
+//	@Router			/admin/queues/{name}/pause [post]
+func (h *CommandHandlers) PauseQueue(c *gin.Context) {
+	name := c.Param("name")
+	if !queueNamePattern.MatchString(name) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": invalidQueueNameMessage})
+		return
+	}
+	h.pauseQueue(c, name)
+}
+
+// ResumeQueue godoc
+//
+//	@Summary		Resume a named queue
+//	@Description	Start dispatching commands of a paused named queue again
+//	@Tags			Admin
+//	@Produce		json
+//	@Param			name	path		string			true	"Queue name"
+//	@Success		200		{object}	models.Message	"Queue resumed"
+//	@Failure		400		{object}	models.Error	"Invalid queue name"
+//	@Failure		409		{object}	models.Error	"Queue is not paused"
+//	@Failure		500		{object}	models.Error	"Problem on server side"
+//	@Router			/admin/queues/{name}/resume [post]
+func (h *CommandHandlers) ResumeQueue(c *gin.Context) {
+	name := c.Param("name")
+	if !queueNamePattern.MatchString(name) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": invalidQueueNameMessage})
+		return
+	}
+	h.resumeQueue(c, name)
+}
+
+// GetQueuePauses godoc
+//
+//	@Summary		List paused queues
+//	@Description	Get the paused queues; "*" stands for the global pause
+//	@Tags			Admin
+//	@Produce		json
+//	@Success		200	{array}		models.QueuePause	"Paused queues"
+//	@Failure		500	{object}	models.Error		"Problem on server side"
+//	@Router			/admin/pauses [get]
+func (h *CommandHandlers) GetQueuePauses(c *gin.Context) {
+	pauses, err := h.Service.FetchQueuePauses()
+	if err != nil {
+		h.Logger.Error("Failed to fetch queue pauses", "error", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch queue pauses"})
+		return
+	}
+	c.JSON(http.StatusOK, pauses)
+}
+
+// PurgeCommands godoc
+//
+//	@Summary		Purge command history
+//	@Description	Delete every command matching the filter with its queue entry, output and archived output.
+//	@Description	Set filters are combined with AND, "all": true is required to purge the whole history.
+//	@Description	Running commands are never deleted. With "dry_run": true nothing is deleted and the
+//	@Description	commands that would be deleted are returned.
+//	@Tags			Admin
+//	@Accept			json
+//	@Produce		json
+//	@Param			filter	body		models.PurgeFilter	true	"Filter of the commands to delete"
+//	@Success		200		{object}	models.PurgeResult	"Deleted commands"
+//	@Failure		400		{object}	models.Error		"Invalid filter"
+//	@Failure		500		{object}	models.Error		"Problem on server side"
+//	@Router			/admin/purge [post]
+func (h *CommandHandlers) PurgeCommands(c *gin.Context) {
+	var filter models.PurgeFilter
+	if err := c.ShouldBindJSON(&filter); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": bindErrorMessage(err)})
+		return
+	}
+	if filter.IsEmpty() {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "At least one filter or \"all\": true is required"})
+		return
+	}
+	if filter.KeepLast < 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "keep_last must not be negative"})
+		return
+	}
+
+	result, err := h.Service.PurgeCommands(filter)
+	if err != nil {
+		if h.Logger != nil {
+			h.Logger.Error("Failed to purge commands", "error", err)
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to purge commands"})
+		return
+	}
+	c.JSON(http.StatusOK, result)
+}
+
+func (h *CommandHandlers) pauseQueue(c *gin.Context, name string) {
+	var request pauseRequest
+	if c.Request.ContentLength != 0 {
+		if err := c.ShouldBindJSON(&request); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body"})
+			return
+		}
+	}
+
+	if err := h.Service.PauseQueue(name, request.Reason); err != nil {
+		h.Logger.Error("Failed to pause queue", "queue", name, "error", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to pause queue"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "Queue paused", "queue": name})
+}
+
+func (h *CommandHandlers) resumeQueue(c *gin.Context, name string) {
+	if err := h.Service.ResumeQueue(name); err != nil {
+		if errors.Is(err, services.ErrQueueNotPaused) {
+			c.JSON(http.StatusConflict, gin.H{"error": "Queue is not paused"})
+			return
+		}
+		h.Logger.Error("Failed to resume queue", "queue", name, "error", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to resume queue"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "Queue resumed", "queue": name})
+}
@@ -2,19 +2,48 @@ package handlers
 
 import (
 	"errors"
-	_ "github.com/17HIERARCH70/BashAPI/internal/domain/models"
+	"github.com/17HIERARCH70/BashAPI/internal/domain/models"
+	"github.com/17HIERARCH70/BashAPI/internal/lib/labels"
+	"github.com/17HIERARCH70/BashAPI/internal/lib/tracing"
 	services "github.com/17HIERARCH70/BashAPI/internal/services/command"
 	"github.com/gin-gonic/gin"
 	"log/slog"
 	"net/http"
+	"regexp"
 	"strconv"
 	"strings"
+	"sync/atomic"
 )
 
+const (
+	// maxIdempotencyKeyLength matches the column size of commands.idempotency_keys.
+	maxIdempotencyKeyLength = 255
+	// maxLockKeyLength matches the column size of commands.command_locks.
+	maxLockKeyLength = 255
+	// maxSubmitterLength matches the column size of commands.commands.submitter.
+	maxSubmitterLength = 255
+)
+
+// queueNamePattern restricts the names of named queues.
+var queueNamePattern = regexp.MustCompile(`^[A-Za-z0-9_.-]{1,64}$`)
+
+const invalidQueueNameMessage = "Queue name must be 1-64 letters, digits, '_', '.' or '-'"
+
+// tagPattern restricts the tags of commands.
+var tagPattern = regexp.MustCompile(`^[A-Za-z0-9_.:/-]{1,64}$`)
+
+// commandRequest is the body accepted by the command creation endpoints.
+type commandRequest struct {
+	Script string `json:"script"`
+	models.CommandOptions
+}
+
 // CommandHandlers Structure for organizing command handlers.
 type CommandHandlers struct {
 	Service services.ICommandService
 	Logger  *slog.Logger
+
+	shuttingDown atomic.Bool
 }
 
 // NewCommandHandlers creates an instance CommandHandlers.
@@ -31,20 +60,27 @@ func NewCommandHandlers(service services.ICommandService, logger *slog.Logger) *
 // CreateCommand godoc
 //
 //	@Summary		Create a new command
-//	@Description	Add a new non-sudo command to the system
+//	@Description	Add a new non-sudo command to the system.
+//	@Description	Optional "locks" ([{"key": "db", "mode": "exclusive|shared"}]) keep conflicting commands in the queue.
+//	@Description	Optional "labels" ({"env": "prod"}) can be used in selectors, "annotations" are free-form.
 //	@Tags			Commands creating
 //	@Accept			json
 //	@Produce		json
-//	@Param			command	body		string			true	"Create command"
-//	@Success		202		{object}	models.Message	"Command is being executed"
-//	@Success		202		{object}	models.Message	"Command is being queued"
-//	@Failure		400		{object}	models.Error	"Error response"
-//	@Failure		500		{object}	models.Error	"Error response on server side"
-//	@Router			/ [post]
+//	@Param			command			body		string			true	"Create command"
+//	@Param			Idempotency-Key	header		string			false	"Key to safely retry the request"
+//	@Param			X-Submitter		header		string			false	"Submitter of the command, used to filter the list"
+//	@Param			wait			query		bool			false	"Wait for the command to finish and return it with its output"
+//	@Param			timeout			query		string			false	"Time to wait with wait=true, e.g. 60s, 30s by default"
+//	@Success		200				{object}	models.Command	"Finished command, with wait=true"
+//	@Success		202				{object}	models.Message	"Command is being executed"
+//	@Success		202				{object}	models.Message	"Command is being queued"
+//	@Failure		400				{object}	models.Error	"Error response"
+//	@Failure		409				{object}	models.Error	"Idempotency key conflict"
+//	@Failure		500				{object}	models.Error	"Error response on server side"
+//	@Failure		503				{object}	models.Error	"Server is shutting down"
+//	@Router			/commands/ [post]
 func (h *CommandHandlers) CreateCommand(c *gin.Context) {
-	var command struct {
-		Script string `json:"script"`
-	}
+	var command commandRequest
 
 	if err := c.ShouldBindJSON(&command); err != nil {
 		c.JSON(400, gin.H{"error": "Invalid request body"})
@@ -61,31 +97,51 @@ func (h *CommandHandlers) CreateCommand(c *gin.Context) {
 		return
 	}
 
-	response, err := h.Service.ProcessCommand(command.Script)
+	command.IdempotencyKey = c.GetHeader("Idempotency-Key")
+	command.Submitter = strings.TrimSpace(c.GetHeader("X-Submitter"))
+	command.TraceParent = tracing.TraceParent(c.Request.Context())
+	if err := validateCommandOptions(command.CommandOptions); err != nil {
+		c.JSON(400, gin.H{"error": err.Error()})
+		return
+	}
+	wait, timeout, err := parseWait(c)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		c.JSON(400, gin.H{"error": err.Error()})
 		return
 	}
-	c.JSON(http.StatusAccepted, response)
+
+	response, err := h.Service.ProcessCommand(command.Script, command.CommandOptions)
+	if err != nil {
+		h.respondProcessError(c, err)
+		return
+	}
+	h.respondCreated(c, response, wait, timeout)
 }
 
 // CreateSudoCommand godoc
 //
 //	@Summary		Create a new sudo command
-//	@Description	Add a new sudo command to the system
+//	@Description	Add a new sudo command to the system.
+//	@Description	Optional "locks" ([{"key": "db", "mode": "exclusive|shared"}]) keep conflicting commands in the queue.
+//	@Description	Optional "labels" ({"env": "prod"}) can be used in selectors, "annotations" are free-form.
 //	@Tags			Commands creating
 //	@Accept			json
 //	@Produce		json
-//	@Param			command	body		string			true	"Create sudo command"
-//	@Success		202		{object}	models.Message	"Command is being executed"
-//	@Success		202		{object}	models.Message	"Command is being queued"
-//	@Failure		400		{object}	models.Error	"Error response"
-//	@Failure		500		{object}	models.Error	"Error response on server side"
-//	@Router			/sudo [post]
+//	@Param			command			body		string			true	"Create sudo command"
+//	@Param			Idempotency-Key	header		string			false	"Key to safely retry the request"
+//	@Param			X-Submitter		header		string			false	"Submitter of the command, used to filter the list"
+//	@Param			wait			query		bool			false	"Wait for the command to finish and return it with its output"
+//	@Param			timeout			query		string			false	"Time to wait with wait=true, e.g. 60s, 30s by default"
+//	@Success		200				{object}	models.Command	"Finished command, with wait=true"
+//	@Success		202				{object}	models.Message	"Command is being executed"
+//	@Success		202				{object}	models.Message	"Command is being queued"
+//	@Failure		400				{object}	models.Error	"Error response"
+//	@Failure		409				{object}	models.Error	"Idempotency key conflict"
+//	@Failure		500				{object}	models.Error	"Error response on server side"
+//	@Failure		503				{object}	models.Error	"Server is shutting down"
+//	@Router			/commands/sudo [post]
 func (h *CommandHandlers) CreateSudoCommand(c *gin.Context) {
-	var command struct {
-		Script string `json:"script"`
-	}
+	var command commandRequest
 
 	if err := c.ShouldBindJSON(&command); err != nil {
 		c.JSON(400, gin.H{"error": "Invalid request body"})
@@ -97,33 +153,162 @@ func (h *CommandHandlers) CreateSudoCommand(c *gin.Context) {
 		return
 	}
 
-	response, err := h.Service.ProcessCommand(command.Script)
+	command.IdempotencyKey = c.GetHeader("Idempotency-Key")
+	command.Submitter = strings.TrimSpace(c.GetHeader("X-Submitter"))
+	command.TraceParent = tracing.TraceParent(c.Request.Context())
+	if err := validateCommandOptions(command.CommandOptions); err != nil {
+		c.JSON(400, gin.H{"error": err.Error()})
+		return
+	}
+	wait, timeout, err := parseWait(c)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		c.JSON(400, gin.H{"error": err.Error()})
 		return
 	}
-	c.JSON(http.StatusAccepted, response)
+
+	response, err := h.Service.ProcessCommand(command.Script, command.CommandOptions)
+	if err != nil {
+		h.respondProcessError(c, err)
+		return
+	}
+	h.respondCreated(c, response, wait, timeout)
+}
+
+// validateCommandOptions checks the optional parameters of a command creation request.
+func validateCommandOptions(opts models.CommandOptions) error {
+	if len(opts.IdempotencyKey) > maxIdempotencyKeyLength {
+		return errors.New("Idempotency-Key is too long")
+	}
+	if len(opts.Submitter) > maxSubmitterLength {
+		return errors.New("X-Submitter is too long")
+	}
+	if err := labels.Validate(opts.Labels); err != nil {
+		return err
+	}
+	if err := labels.ValidateAnnotations(opts.Annotations); err != nil {
+		return err
+	}
+	if opts.CallbackURL != "" {
+		if err := validateWebhookURL(opts.CallbackURL); err != nil {
+			return err
+		}
+	}
+	for _, tag := range opts.Tags {
+		if !tagPattern.MatchString(tag) {
+			return errors.New("Tag must be 1-64 letters, digits, '_', '.', ':', '/' or '-'")
+		}
+	}
+
+	if opts.Weight < 0 {
+		return errors.New("Weight must be a positive number")
+	}
+	if opts.TTL < 0 {
+		return errors.New("TTL must be a positive number of seconds")
+	}
+	if opts.Queue != "" && !queueNamePattern.MatchString(opts.Queue) {
+		return errors.New(invalidQueueNameMessage)
+	}
+	if opts.MaxOutput < 0 {
+		return errors.New("Max output must be a positive number of bytes")
+	}
+	switch opts.OutputPolicy {
+	case "", models.OutputKeepHead, models.OutputKeepTail, models.OutputKeepHeadTail:
+	default:
+		return errors.New("Output policy must be 'head', 'tail' or 'head_tail'")
+	}
+
+	seen := make(map[string]bool, len(opts.Locks))
+	for _, lock := range opts.Locks {
+		if lock.Key == "" || len(lock.Key) > maxLockKeyLength {
+			return errors.New("Lock key must be between 1 and 255 characters")
+		}
+		if lock.Mode != "" && lock.Mode != models.LockExclusive && lock.Mode != models.LockShared {
+			return errors.New("Lock mode must be 'exclusive' or 'shared'")
+		}
+		if seen[lock.Key] {
+			return errors.New("Lock '" + lock.Key + "' is declared more than once")
+		}
+		seen[lock.Key] = true
+	}
+	return nil
+}
+
+// respondProcessError maps errors of command creation to HTTP responses.
+func (h *CommandHandlers) respondProcessError(c *gin.Context, err error) {
+	if errors.Is(err, services.ErrIdempotencyConflict) || errors.Is(err, services.ErrIdempotencyInProgress) {
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+		return
+	}
+	if errors.Is(err, services.ErrWeightExceedsCapacity) || errors.Is(err, services.ErrOutputLimitTooLarge) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if errors.Is(err, services.ErrShuttingDown) {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 }
 
 // GetCommandsList godoc
 //
-//	@Summary		Retrieve all commands
-//	@Description	Get a list of all commands processed by the system
+//	@Summary		Retrieve commands
+//	@Description	Get a page of the commands processed by the system. The output is left out unless
+//	@Description	requested with fields. The cursor of the next page is returned in the X-Next-Cursor header.
 //	@Tags			Getting commands
 //	@Produce		json
-//	@Success		200	{array}		models.Command	"List of commands"
-//	@Failure		500	{object}	models.Error	"Server error"
-//	@Router			/ [get]
+//	@Param			status			query		string			false	"Comma separated statuses"
+//	@Param			created_after	query		string			false	"RFC 3339 time, inclusive"
+//	@Param			created_before	query		string			false	"RFC 3339 time, exclusive"
+//	@Param			updated_after	query		string			false	"RFC 3339 time, inclusive"
+//	@Param			updated_before	query		string			false	"RFC 3339 time, exclusive"
+//	@Param			submitter		query		string			false	"Submitter of the command"
+//	@Param			tag				query		string			false	"Comma separated tags the command must all carry"
+//	@Param			selector		query		string			false	"Label selector, e.g. env=prod,team!=infra"
+//	@Param			script			query		string			false	"Substring of the script"
+//	@Param			exit_code		query		int				false	"Exit code of the command"
+//	@Param			sort			query		string			false	"Sort by id, created_at or updated_at"
+//	@Param			order			query		string			false	"asc or desc"
+//	@Param			limit			query		int				false	"Page size, 100 by default and at most 1000"
+//	@Param			cursor			query		string			false	"X-Next-Cursor of the previous page"
+//	@Param			fields			query		string			false	"Comma separated fields to return, e.g. ID,Status,Output"
+//	@Success		200				{array}		models.Command	"List of commands"
+//	@Failure		400				{object}	models.Error	"Invalid query parameters"
+//	@Failure		500				{object}	models.Error	"Server error"
+//	@Router			/commands/ [get]
 func (h *CommandHandlers) GetCommandsList(c *gin.Context) {
-	commands, err := h.Service.FetchCommands()
+	query, err := parseCommandQuery(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	fields, err := parseFields(c.Query("fields"))
 	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	query.WithOutput = fields["output"]
+
+	page, err := h.Service.FetchCommands(query)
+	if err != nil {
+		if errors.Is(err, services.ErrInvalidCursor) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid cursor"})
+			return
+		}
 		if h.Logger != nil {
 			h.Logger.Error("Failed to fetch commands", "error", err)
 		}
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch commands"})
 		return
 	}
-	c.JSON(http.StatusOK, commands)
+	if page.NextCursor != "" {
+		c.Header("X-Next-Cursor", page.NextCursor)
+	}
+	if fields == nil {
+		c.JSON(http.StatusOK, listCommands(page.Items))
+		return
+	}
+	c.JSON(http.StatusOK, selectFields(page.Items, fields))
 }
 
 // GetCommandByID godoc
@@ -137,7 +322,7 @@ func (h *CommandHandlers) GetCommandsList(c *gin.Context) {
 //	@Failure		500	{object}	models.Error	"Problem on server side"
 //	@Failure		404	{object}	models.Error	"Command not found"
 //	@Failure		400	{object}	models.Error	"Invalid ID supplied"
-//	@Router			/{id} [get]
+//	@Router			/commands/{id} [get]
 func (h *CommandHandlers) GetCommandByID(c *gin.Context) {
 	commandIDParam := c.Param("id")
 	commandID, err := strconv.Atoi(commandIDParam)
@@ -161,18 +346,56 @@ func (h *CommandHandlers) GetCommandByID(c *gin.Context) {
 	c.JSON(http.StatusOK, command)
 }
 
+// DeleteCommand godoc
+//
+//	@Summary		Delete a command
+//	@Description	Delete a command that is not running, with its queue entry, output and archived output
+//	@Tags			Fetching commands
+//	@Produce		json
+//	@Param			id	path		int				true	"Command ID"
+//	@Success		200	{object}	models.Message	"Command deleted"
+//	@Failure		400	{object}	models.Error	"Invalid ID supplied"
+//	@Failure		404	{object}	models.Error	"Command not found"
+//	@Failure		409	{object}	models.Error	"Command is running"
+//	@Failure		500	{object}	models.Error	"Problem on server side"
+//	@Router			/commands/{id} [delete]
+func (h *CommandHandlers) DeleteCommand(c *gin.Context) {
+	commandID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid command ID"})
+		return
+	}
+
+	if err := h.Service.DeleteCommand(commandID); err != nil {
+		switch {
+		case errors.Is(err, services.ErrNotFound):
+			c.JSON(http.StatusNotFound, gin.H{"error": "Command not found"})
+		case errors.Is(err, services.ErrCommandRunning):
+			c.JSON(http.StatusConflict, gin.H{"error": "Command is running, stop it first"})
+		default:
+			if h.Logger != nil {
+				h.Logger.Error("Failed to delete command", "error", err)
+			}
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete command"})
+		}
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "Command deleted", "id": commandID})
+}
+
 // StopCommand godoc
 //
 //	@Summary		Stop a command
-//	@Description	Stop a running command by its ID
+//	@Description	Stop a running command by its ID, a queued command is cancelled
 //	@Tags			Fetching commands
 //	@Produce		json
 //	@Param			id	path		int				true	"Command ID"
 //	@Success		200	{object}	models.Message	"Command stopped successfully"
 //	@Failure		500	{object}	models.Error	"Problem on server side"
 //	@Failure		404	{object}	models.Error	"Command not found"
+//	@Failure		409	{object}	models.Error	"Command is not running"
 //	@Failure		400	{object}	models.Error	"Invalid ID supplied"
-//	@Router			/{id}/stop [post]
+//	@Router			/commands/{id}/stop [post]
 func (h *CommandHandlers) StopCommand(c *gin.Context) {
 	commandIDParam := c.Param("id")
 	commandID, err := strconv.Atoi(commandIDParam)
@@ -185,6 +408,8 @@ func (h *CommandHandlers) StopCommand(c *gin.Context) {
 	if err != nil {
 		if errors.Is(err, services.ErrNotFound) {
 			c.JSON(http.StatusNotFound, gin.H{"error": "Command not found"})
+		} else if errors.Is(err, services.ErrInvalidTransition) {
+			c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
 		} else {
 			h.Logger.Error("Failed to stop command", "error", err)
 			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to stop command"})
@@ -197,12 +422,18 @@ func (h *CommandHandlers) StopCommand(c *gin.Context) {
 // GetQueueList godoc
 //
 //	@Summary		Retrieve command queue
-//	@Description	Get a list of all commands currently in the queue
+//	@Description	Get a list of all commands currently in the queue.
+//	@Description	BlockedBy holds the ID of the running command owning a conflicting lock.
+//	@Description	EstimatedStartAt is derived from the durations of similar completed scripts.
+//	@Description	The concurrency slots shared by the running commands are reported in the X-Capacity headers.
 //	@Tags			Queue
 //	@Produce		json
 //	@Success		200	{array}		models.Queue	"List of queued items"
+//	@Header			200	{integer}	X-Capacity-Total		"Total concurrency slots"
+//	@Header			200	{integer}	X-Capacity-Used			"Slots taken by the running commands"
+//	@Header			200	{integer}	X-Capacity-Available	"Free slots"
 //	@Failure		500	{object}	models.Error	"Server error"
-//	@Router			/queue [get]
+//	@Router			/commands/queue [get]
 func (h *CommandHandlers) GetQueueList(c *gin.Context) {
 	queue, err := h.Service.FetchQueueList()
 	if err != nil {
@@ -210,9 +441,57 @@ func (h *CommandHandlers) GetQueueList(c *gin.Context) {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve queue data"})
 		return
 	}
+	capacity, err := h.Service.FetchCapacity()
+	if err != nil {
+		if h.Logger != nil {
+			h.Logger.Error("Failed to retrieve capacity", "error", err)
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve queue data"})
+		return
+	}
+	if queue == nil {
+		queue = []models.Queue{}
+	}
+	c.Header("X-Capacity-Total", strconv.Itoa(capacity.Total))
+	c.Header("X-Capacity-Used", strconv.Itoa(capacity.Used))
+	c.Header("X-Capacity-Available", strconv.Itoa(capacity.Available))
 	c.JSON(http.StatusOK, queue)
 }
 
+// GetCapacity godoc
+//
+//	@Summary		Retrieve capacity usage
+//	@Description	Show the used and available concurrency slots shared by running commands
+//	@Tags			Queue
+//	@Produce		json
+//	@Success		200	{object}	models.Capacity	"Capacity usage"
+//	@Failure		500	{object}	models.Error	"Server error"
+//	@Router			/commands/capacity [get]
+func (h *CommandHandlers) GetCapacity(c *gin.Context) {
+	capacity, err := h.Service.FetchCapacity()
+	if err != nil {
+		if h.Logger != nil {
+			h.Logger.Error("Failed to retrieve capacity", "error", err)
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve capacity"})
+		return
+	}
+	c.JSON(http.StatusOK, capacity)
+}
+
+// GetAdmission godoc
+//
+//	@Summary		Retrieve admission decision
+//	@Description	Show whether the dispatcher currently starts queued commands
+//	@Description	and the host load metrics and thresholds behind the decision
+//	@Tags			Queue
+//	@Produce		json
+//	@Success		200	{object}	models.Admission	"Admission decision"
+//	@Router			/commands/admission [get]
+func (h *CommandHandlers) GetAdmission(c *gin.Context) {
+	c.JSON(http.StatusOK, h.Service.FetchAdmission())
+}
+
 // ForceStartCommand godoc
 //
 //	@Summary		Force start a command
@@ -222,7 +501,9 @@ func (h *CommandHandlers) GetQueueList(c *gin.Context) {
 //	@Param			id	path		int				true	"Command ID"
 //	@Success		200	{object}	models.Message	"Command started successfully"
 //	@Failure		404	{object}	models.Error	"Command not found"
+//	@Failure		409	{object}	models.Error	"Command is running or completed"
 //	@Failure		500	{object}	models.Error	"Server error"
+//	@Failure		503	{object}	models.Error	"Server is shutting down"
 //	@Failure		400	{object}	models.Error	"Invalid ID supplied"
 //	@Router			/commands/{id}/fstart [post]
 func (h *CommandHandlers) ForceStartCommand(c *gin.Context) {
@@ -237,6 +518,10 @@ func (h *CommandHandlers) ForceStartCommand(c *gin.Context) {
 	if err != nil {
 		if errors.Is(err, services.ErrNotFound) {
 			c.JSON(http.StatusNotFound, gin.H{"error": "Command not found"})
+		} else if errors.Is(err, services.ErrInvalidTransition) {
+			c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+		} else if errors.Is(err, services.ErrShuttingDown) {
+			c.JSON(http.StatusServiceUnavailable, gin.H{"error": err.Error()})
 		} else {
 			if h.Logger != nil { // Check if Logger is not nil before logging
 				h.Logger.Error("Failed to forcefully start command", "error", err)
@@ -0,0 +1,43 @@
+package handlers
+
+import (
+	"errors"
+	services "github.com/17HIERARCH70/BashAPI/internal/services/command"
+	"github.com/gin-gonic/gin"
+	"net/http"
+	"strconv"
+)
+
+// GetCommandEvents godoc
+//
+//	@Summary		Get command events
+//	@Description	List the status transitions of a command, oldest first, with the time, the actor and the reason of each.
+//	@Tags			Getting commands
+//	@Produce		json
+//	@Param			id	path		int					true	"Command ID"
+//	@Success		200	{array}		models.CommandEvent	"Status transitions"
+//	@Failure		400	{object}	models.Error		"Invalid ID supplied"
+//	@Failure		404	{object}	models.Error		"Command not found"
+//	@Failure		500	{object}	models.Error		"Problem on server side"
+//	@Router			/commands/{id}/events [get]
+func (h *CommandHandlers) GetCommandEvents(c *gin.Context) {
+	commandID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid command ID"})
+		return
+	}
+
+	events, err := h.Service.FetchCommandEvents(commandID)
+	if err != nil {
+		if errors.Is(err, services.ErrNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Command not found"})
+			return
+		}
+		if h.Logger != nil {
+			h.Logger.Error("Failed to fetch command events", "error", err)
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch command events"})
+		return
+	}
+	c.JSON(http.StatusOK, events)
+}
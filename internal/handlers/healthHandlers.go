@@ -0,0 +1,46 @@
+package handlers
+
+import (
+	"github.com/17HIERARCH70/BashAPI/internal/domain/models"
+	"github.com/gin-gonic/gin"
+	"net/http"
+)
+
+// checkShutdown is the name of the readiness check failing once the shutdown has begun.
+const checkShutdown = "shutdown"
+
+// BeginShutdown makes readiness fail, so that load balancers stop routing new traffic to
+// the server before its commands are stopped.
+func (h *CommandHandlers) BeginShutdown() {
+	h.shuttingDown.Store(true)
+}
+
+// Healthz reports that the process is alive. It checks no dependency, so that a database
+// outage does not get the server restarted.
+func (h *CommandHandlers) Healthz(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{"status": models.HealthOK})
+}
+
+// Readyz reports whether the server can take traffic: the database is reachable, its schema
+// is migrated, the dispatcher loop is alive and the shutdown has not begun. It responds with
+// 503 and the breakdown of the checks when any of them fails.
+func (h *CommandHandlers) Readyz(c *gin.Context) {
+	shutdown := models.HealthCheck{Name: checkShutdown, Status: models.HealthOK}
+	if h.shuttingDown.Load() {
+		shutdown.Status = models.HealthFailing
+		shutdown.Error = "server is shutting down"
+	}
+	readiness := models.Readiness{
+		Status: models.HealthOK,
+		Checks: append(h.Service.CheckReadiness(c.Request.Context()), shutdown),
+	}
+
+	code := http.StatusOK
+	for _, check := range readiness.Checks {
+		if check.Status != models.HealthOK {
+			readiness.Status = models.HealthFailing
+			code = http.StatusServiceUnavailable
+		}
+	}
+	c.JSON(code, readiness)
+}
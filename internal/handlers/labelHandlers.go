@@ -0,0 +1,99 @@
+package handlers
+
+import (
+	"errors"
+	"github.com/17HIERARCH70/BashAPI/internal/domain/models"
+	"github.com/17HIERARCH70/BashAPI/internal/lib/labels"
+	services "github.com/17HIERARCH70/BashAPI/internal/services/command"
+	"github.com/gin-gonic/gin"
+	"net/http"
+	"strconv"
+)
+
+// UpdateCommandLabels godoc
+//
+//	@Summary		Update command labels
+//	@Description	Set labels and annotations of a command. Keys with a null value are removed, keys
+//	@Description	that are not listed are kept. Labels can be selected by, annotations are free-form.
+//	@Tags			Commands
+//	@Accept			json
+//	@Produce		json
+//	@Param			id		path		int						true	"Command ID"
+//	@Param			update	body		models.LabelUpdate		true	"Labels and annotations to set or remove"
+//	@Success		200		{object}	models.CommandLabels	"Labels and annotations of the command"
+//	@Failure		400		{object}	models.Error			"Invalid ID or labels"
+//	@Failure		404		{object}	models.Error			"Command not found"
+//	@Failure		500		{object}	models.Error			"Problem on server side"
+//	@Router			/commands/{id}/labels [patch]
+func (h *CommandHandlers) UpdateCommandLabels(c *gin.Context) {
+	commandID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid command ID"})
+		return
+	}
+	var update models.LabelUpdate
+	if err := c.ShouldBindJSON(&update); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body"})
+		return
+	}
+
+	result, err := h.Service.UpdateLabels(commandID, update)
+	if err != nil {
+		switch {
+		case errors.Is(err, services.ErrNotFound):
+			c.JSON(http.StatusNotFound, gin.H{"error": "Command not found"})
+		case errors.Is(err, labels.ErrInvalidLabel):
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		default:
+			if h.Logger != nil {
+				h.Logger.Error("Failed to update command labels", "error", err)
+			}
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update command labels"})
+		}
+		return
+	}
+	c.JSON(http.StatusOK, result)
+}
+
+// StopCommands godoc
+//
+//	@Summary		Bulk stop commands
+//	@Description	Stop every running command whose labels match the selector, e.g. "deploy=1234"
+//	@Description	or "env=prod,team!=infra". Matching queued commands are cancelled.
+//	@Tags			Commands
+//	@Accept			json
+//	@Produce		json
+//	@Param			filter	body		models.SelectorFilter	true	"Label selector of the commands to stop"
+//	@Success		200		{object}	models.Message			"Commands stopped"
+//	@Failure		400		{object}	models.Error			"Invalid selector"
+//	@Failure		500		{object}	models.Error			"Problem on server side"
+//	@Router			/commands/stop [post]
+func (h *CommandHandlers) StopCommands(c *gin.Context) {
+	var filter models.SelectorFilter
+	if err := c.ShouldBindJSON(&filter); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": bindErrorMessage(err)})
+		return
+	}
+	if len(filter.Selector) == 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Selector is required"})
+		return
+	}
+
+	ids, err := h.Service.StopCommands(filter)
+	if err != nil {
+		if h.Logger != nil {
+			h.Logger.Error("Failed to stop commands", "error", err)
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to stop commands"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "Commands stopped", "stopped": ids})
+}
+
+// bindErrorMessage explains why a filter body was rejected, naming an invalid label selector.
+func bindErrorMessage(err error) string {
+	if errors.Is(err, labels.ErrInvalidSelector) {
+		return err.Error()
+	}
+	return "Invalid request body"
+}
@@ -0,0 +1,232 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/17HIERARCH70/BashAPI/internal/domain/models"
+	"github.com/17HIERARCH70/BashAPI/internal/lib/labels"
+	services "github.com/17HIERARCH70/BashAPI/internal/services/command"
+	"github.com/gin-gonic/gin"
+	"net/http"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	// maxListLimit is the largest page of the command list.
+	maxListLimit = 1000
+	// maxSearchLength limits the text accepted by the search endpoint.
+	maxSearchLength = 1024
+)
+
+// commandFields are the normalized names of the fields of models.Command.
+var commandFields = func() map[string]string {
+	fields := make(map[string]string)
+	commandType := reflect.TypeOf(models.Command{})
+	for i := 0; i < commandType.NumField(); i++ {
+		name := commandType.Field(i).Name
+		fields[normalizeField(name)] = name
+	}
+	return fields
+}()
+
+// normalizeField lets fields be named like the JSON keys or in snake case.
+func normalizeField(name string) string {
+	return strings.ToLower(strings.ReplaceAll(name, "_", ""))
+}
+
+// SearchCommands godoc
+//
+//	@Summary		Search commands
+//	@Description	Find the commands whose script or output contains the words of q, or q as a substring.
+//	@Description	The matches are highlighted with <mark> tags in the snippets. Takes the filters and the
+//	@Description	paging of the command list, newest first by default.
+//	@Tags			Getting commands
+//	@Produce		json
+//	@Param			q				query		string					true	"Words or substring to search for"
+//	@Param			status			query		string					false	"Comma separated statuses"
+//	@Param			created_after	query		string					false	"RFC 3339 time, inclusive"
+//	@Param			created_before	query		string					false	"RFC 3339 time, exclusive"
+//	@Param			updated_after	query		string					false	"RFC 3339 time, inclusive"
+//	@Param			updated_before	query		string					false	"RFC 3339 time, exclusive"
+//	@Param			submitter		query		string					false	"Submitter of the command"
+//	@Param			tag				query		string					false	"Comma separated tags the command must all carry"
+//	@Param			selector		query		string					false	"Label selector, e.g. env=prod,team!=infra"
+//	@Param			script			query		string					false	"Substring of the script"
+//	@Param			exit_code		query		int						false	"Exit code of the command"
+//	@Param			sort			query		string					false	"Sort by id, created_at or updated_at"
+//	@Param			order			query		string					false	"asc or desc"
+//	@Param			limit			query		int						false	"Page size, 100 by default and at most 1000"
+//	@Param			cursor			query		string					false	"X-Next-Cursor of the previous page"
+//	@Success		200				{array}		models.SearchResult		"Matching commands"
+//	@Failure		400				{object}	models.Error			"Invalid query parameters"
+//	@Failure		500				{object}	models.Error			"Server error"
+//	@Router			/commands/search [get]
+func (h *CommandHandlers) SearchCommands(c *gin.Context) {
+	text := strings.TrimSpace(c.Query("q"))
+	if text == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "q is required"})
+		return
+	}
+	if len(text) > maxSearchLength {
+		c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("q must not exceed %d characters", maxSearchLength)})
+		return
+	}
+	query, err := parseCommandQuery(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if c.Query("order") == "" {
+		query.Desc = true
+	}
+
+	page, err := h.Service.SearchCommands(models.SearchQuery{Text: text, CommandQuery: query})
+	if err != nil {
+		if errors.Is(err, services.ErrInvalidCursor) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid cursor"})
+			return
+		}
+		if h.Logger != nil {
+			h.Logger.Error("Failed to search commands", "error", err)
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to search commands"})
+		return
+	}
+	if page.NextCursor != "" {
+		c.Header("X-Next-Cursor", page.NextCursor)
+	}
+	c.JSON(http.StatusOK, page.Items)
+}
+
+// parseCommandQuery reads the filters, the sort order and the page of the command list.
+func parseCommandQuery(c *gin.Context) (models.CommandQuery, error) {
+	query := models.CommandQuery{
+		Statuses:       splitList(c.Query("status")),
+		Submitter:      c.Query("submitter"),
+		Tags:           splitList(c.Query("tag")),
+		ScriptContains: c.Query("script"),
+		Cursor:         c.Query("cursor"),
+		Sort:           c.DefaultQuery("sort", models.SortByID),
+	}
+
+	times := map[string]**time.Time{
+		"created_after":  &query.CreatedAfter,
+		"created_before": &query.CreatedBefore,
+		"updated_after":  &query.UpdatedAfter,
+		"updated_before": &query.UpdatedBefore,
+	}
+	for name, target := range times {
+		value := c.Query(name)
+		if value == "" {
+			continue
+		}
+		parsed, err := time.Parse(time.RFC3339, value)
+		if err != nil {
+			return query, fmt.Errorf("%s must be an RFC 3339 time", name)
+		}
+		*target = &parsed
+	}
+
+	selector, err := labels.Parse(c.Query("selector"))
+	if err != nil {
+		return query, err
+	}
+	query.Labels = selector
+
+	if value := c.Query("exit_code"); value != "" {
+		code, err := strconv.Atoi(value)
+		if err != nil {
+			return query, errors.New("exit_code must be a number")
+		}
+		query.ExitCode = &code
+	}
+
+	switch query.Sort {
+	case models.SortByID, models.SortByCreatedAt, models.SortByUpdatedAt:
+	default:
+		return query, errors.New("sort must be 'id', 'created_at' or 'updated_at'")
+	}
+	switch c.DefaultQuery("order", "asc") {
+	case "asc":
+	case "desc":
+		query.Desc = true
+	default:
+		return query, errors.New("order must be 'asc' or 'desc'")
+	}
+
+	if value := c.Query("limit"); value != "" {
+		limit, err := strconv.Atoi(value)
+		if err != nil || limit < 1 || limit > maxListLimit {
+			return query, fmt.Errorf("limit must be between 1 and %d", maxListLimit)
+		}
+		query.Limit = limit
+	}
+	return query, nil
+}
+
+// parseFields reads the fields parameter into a set of normalized field names,
+// or nil when every field but the output is wanted.
+func parseFields(value string) (map[string]bool, error) {
+	names := splitList(value)
+	if len(names) == 0 {
+		return nil, nil
+	}
+	fields := make(map[string]bool, len(names))
+	for _, name := range names {
+		normalized := normalizeField(name)
+		if _, ok := commandFields[normalized]; !ok {
+			return nil, fmt.Errorf("unknown field '%s'", name)
+		}
+		fields[normalized] = true
+	}
+	return fields, nil
+}
+
+// listedCommand renders a command of the list, which leaves out the output unless it is
+// selected with the fields parameter. The empty Output shadows the one of the command.
+type listedCommand struct {
+	models.Command
+	Output string `json:",omitempty"`
+}
+
+// listCommands renders commands with every field but the output.
+func listCommands(commands []models.Command) []listedCommand {
+	listed := make([]listedCommand, 0, len(commands))
+	for _, command := range commands {
+		listed = append(listed, listedCommand{Command: command})
+	}
+	return listed
+}
+
+// selectFields renders commands with the selected fields only.
+func selectFields(commands []models.Command, fields map[string]bool) []map[string]interface{} {
+	selected := make([]map[string]interface{}, 0, len(commands))
+	for _, command := range commands {
+		data, _ := json.Marshal(command)
+		var all map[string]interface{}
+		_ = json.Unmarshal(data, &all)
+
+		item := make(map[string]interface{}, len(fields))
+		for field := range fields {
+			name := commandFields[field]
+			item[name] = all[name]
+		}
+		selected = append(selected, item)
+	}
+	return selected
+}
+
+// splitList splits a comma separated parameter, ignoring empty items.
+func splitList(value string) []string {
+	var items []string
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
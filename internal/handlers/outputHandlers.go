@@ -0,0 +1,122 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"github.com/17HIERARCH70/BashAPI/internal/domain/models"
+	services "github.com/17HIERARCH70/BashAPI/internal/services/command"
+	"github.com/gin-gonic/gin"
+	"net/http"
+	"regexp"
+	"strconv"
+)
+
+// maxGrepLength limits the size of the regular expression accepted by the output endpoint.
+const maxGrepLength = 1024
+
+// GetCommandOutput godoc
+//
+//	@Summary		Get command output
+//	@Description	Read a byte range of the command output, its first or last lines, or the lines matching a regular expression. With raw=true the output is returned as a text/plain download.
+//	@Tags			Getting commands
+//	@Produce		json
+//	@Produce		plain
+//	@Param			id		path		int						true	"Command ID"
+//	@Param			offset	query		int						false	"Byte offset to start reading from"
+//	@Param			limit	query		int						false	"Maximum number of bytes to read, 0 reads to the end"
+//	@Param			head	query		int						false	"Return the first N lines of the range"
+//	@Param			tail	query		int						false	"Return the last N lines of the range"
+//	@Param			grep	query		string					false	"Return only the lines matching this regular expression"
+//	@Param			raw		query		bool					false	"Download the selected output as text/plain"
+//	@Success		200		{object}	models.CommandOutput	"Command output"
+//	@Failure		400		{object}	models.Error			"Invalid ID or query parameters"
+//	@Failure		404		{object}	models.Error			"Command not found"
+//	@Failure		500		{object}	models.Error			"Problem on server side"
+//	@Router			/commands/{id}/output [get]
+func (h *CommandHandlers) GetCommandOutput(c *gin.Context) {
+	commandID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid command ID"})
+		return
+	}
+
+	query, err := parseOutputQuery(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	raw, err := strconv.ParseBool(c.DefaultQuery("raw", "false"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "raw must be a boolean"})
+		return
+	}
+
+	output, err := h.Service.FetchCommandOutput(commandID, query)
+	if err != nil {
+		if errors.Is(err, services.ErrNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Command not found"})
+			return
+		}
+		if h.Logger != nil {
+			h.Logger.Error("Failed to fetch command output", "error", err)
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch command output"})
+		return
+	}
+
+	if raw {
+		c.Header("Content-Disposition", fmt.Sprintf(`attachment; filename="command-%d-output.txt"`, commandID))
+		c.Header("X-Output-Size", strconv.FormatInt(output.Size, 10))
+		c.Data(http.StatusOK, "text/plain; charset=utf-8", []byte(output.Output))
+		return
+	}
+	c.JSON(http.StatusOK, output)
+}
+
+// parseOutputQuery reads the range and line selection parameters of the output endpoint.
+func parseOutputQuery(c *gin.Context) (models.OutputQuery, error) {
+	var query models.OutputQuery
+	var err error
+
+	if query.Offset, err = nonNegativeQuery(c, "offset"); err != nil {
+		return query, err
+	}
+	if query.Limit, err = nonNegativeQuery(c, "limit"); err != nil {
+		return query, err
+	}
+	head, err := nonNegativeQuery(c, "head")
+	if err != nil {
+		return query, err
+	}
+	tail, err := nonNegativeQuery(c, "tail")
+	if err != nil {
+		return query, err
+	}
+	if head > 0 && tail > 0 {
+		return query, errors.New("head and tail cannot be combined")
+	}
+	query.Head, query.Tail = int(head), int(tail)
+
+	if pattern := c.Query("grep"); pattern != "" {
+		if len(pattern) > maxGrepLength {
+			return query, fmt.Errorf("grep must not exceed %d characters", maxGrepLength)
+		}
+		if query.Grep, err = regexp.Compile(pattern); err != nil {
+			return query, errors.New("grep is not a valid regular expression")
+		}
+	}
+	return query, nil
+}
+
+// nonNegativeQuery parses an optional non-negative integer query parameter.
+func nonNegativeQuery(c *gin.Context, name string) (int64, error) {
+	value := c.Query(name)
+	if value == "" {
+		return 0, nil
+	}
+	n, err := strconv.ParseInt(value, 10, 64)
+	if err != nil || n < 0 {
+		return 0, fmt.Errorf("%s must be a non-negative number", name)
+	}
+	return n, nil
+}
@@ -0,0 +1,150 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"github.com/17HIERARCH70/BashAPI/internal/domain/models"
+	services "github.com/17HIERARCH70/BashAPI/internal/services/command"
+	"github.com/gin-gonic/gin"
+	"math"
+	"net/http"
+	"strconv"
+)
+
+// moveRequest is the body of the move endpoint. Position is "front", "back" or a 1-based index.
+type moveRequest struct {
+	Position json.RawMessage `json:"position"`
+}
+
+// CancelCommand godoc
+//
+//	@Summary		Cancel a queued command
+//	@Description	Remove a waiting command from the queue and mark it as cancelled
+//	@Tags			Queue
+//	@Produce		json
+//	@Param			id	path		int				true	"Command ID"
+//	@Success		200	{object}	models.Message	"Command cancelled"
+//	@Failure		400	{object}	models.Error	"Invalid ID supplied"
+//	@Failure		404	{object}	models.Error	"Command not found"
+//	@Failure		409	{object}	models.Error	"Command is not waiting in the queue"
+//	@Failure		500	{object}	models.Error	"Problem on server side"
+//	@Router			/commands/{id}/cancel [post]
+func (h *CommandHandlers) CancelCommand(c *gin.Context) {
+	commandID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid command ID"})
+		return
+	}
+
+	if err := h.Service.CancelCommand(commandID); err != nil {
+		h.respondQueueError(c, "Failed to cancel command", err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "Command cancelled", "id": commandID})
+}
+
+// MoveQueuedCommand godoc
+//
+//	@Summary		Reorder the queue
+//	@Description	Move a waiting command to the front, to the back or to a 1-based position of the queue
+//	@Tags			Queue
+//	@Accept			json
+//	@Produce		json
+//	@Param			id		path		int				true	"Command ID"
+//	@Param			move	body		string			true	"Position: front, back or a 1-based index"
+//	@Success		200		{object}	models.Message	"Command moved"
+//	@Failure		400		{object}	models.Error	"Invalid ID or position"
+//	@Failure		404		{object}	models.Error	"Command not found"
+//	@Failure		409		{object}	models.Error	"Command is not waiting in the queue"
+//	@Failure		500		{object}	models.Error	"Problem on server side"
+//	@Router			/commands/{id}/move [post]
+func (h *CommandHandlers) MoveQueuedCommand(c *gin.Context) {
+	commandID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid command ID"})
+		return
+	}
+
+	var request moveRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body"})
+		return
+	}
+	position, ok := parsePosition(request.Position)
+	if !ok {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Position must be 'front', 'back' or a positive number"})
+		return
+	}
+
+	if err := h.Service.MoveQueuedCommand(commandID, position); err != nil {
+		h.respondQueueError(c, "Failed to move command", err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "Command moved", "id": commandID})
+}
+
+// CancelQueuedCommands godoc
+//
+//	@Summary		Bulk cancel queued commands
+//	@Description	Cancel every waiting command matching the filter. Set filters are combined with AND,
+//	@Description	"all": true is required to cancel the whole queue.
+//	@Tags			Queue
+//	@Accept			json
+//	@Produce		json
+//	@Param			filter	body		models.QueueFilter	true	"Filter of the commands to cancel"
+//	@Success		200		{object}	models.Message		"Commands cancelled"
+//	@Failure		400		{object}	models.Error		"Invalid filter"
+//	@Failure		500		{object}	models.Error		"Problem on server side"
+//	@Router			/commands/queue/cancel [post]
+func (h *CommandHandlers) CancelQueuedCommands(c *gin.Context) {
+	var filter models.QueueFilter
+	if err := c.ShouldBindJSON(&filter); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": bindErrorMessage(err)})
+		return
+	}
+	if filter.IsEmpty() {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "At least one filter or \"all\": true is required"})
+		return
+	}
+
+	ids, err := h.Service.CancelQueuedCommands(filter)
+	if err != nil {
+		h.Logger.Error("Failed to cancel queued commands", "error", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to cancel queued commands"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "Commands cancelled", "cancelled": ids})
+}
+
+// parsePosition turns "front", "back" or a positive number into a 1-based queue position.
+func parsePosition(raw json.RawMessage) (int, bool) {
+	var name string
+	if err := json.Unmarshal(raw, &name); err == nil {
+		switch name {
+		case "front":
+			return 1, true
+		case "back":
+			return math.MaxInt32, true
+		}
+		return 0, false
+	}
+
+	var position int
+	if err := json.Unmarshal(raw, &position); err != nil || position < 1 {
+		return 0, false
+	}
+	return position, true
+}
+
+// respondQueueError maps errors of single queue item operations to HTTP responses.
+func (h *CommandHandlers) respondQueueError(c *gin.Context, message string, err error) {
+	switch {
+	case errors.Is(err, services.ErrNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": "Command not found"})
+	case errors.Is(err, services.ErrNotQueued):
+		c.JSON(http.StatusConflict, gin.H{"error": "Command is not waiting in the queue"})
+	default:
+		h.Logger.Error(message, "error", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": message})
+	}
+}
@@ -0,0 +1,193 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/17HIERARCH70/BashAPI/internal/domain/models"
+	"github.com/17HIERARCH70/BashAPI/internal/lib/labels"
+	"github.com/gin-gonic/gin"
+	"golang.org/x/net/websocket"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// streamHeartbeat is how often an idle event stream sends a keep-alive, so that proxies do
+// not close it and dead clients are noticed.
+const streamHeartbeat = 15 * time.Second
+
+// StreamEvents godoc
+//
+//	@Summary		Stream command events
+//	@Description	Stream the lifecycle events of all commands on every replica as Server-Sent Events, or as
+//	@Description	JSON WebSocket messages when the request asks for a WebSocket upgrade. SSE clients resume
+//	@Description	after a reconnect with the Last-Event-ID header, the after parameter does the same for both.
+//	@Description	Events that occurred shortly before the resumed one may be sent again, event_id tells them apart.
+//	@Tags			Getting commands
+//	@Produce		text/event-stream
+//	@Param			namespace	query		string					false	"Only commands of this queue"
+//	@Param			selector	query		string					false	"Label selector, e.g. env=prod,team!=infra"
+//	@Param			type		query		string					false	"Comma-separated event types, e.g. completed,failed"
+//	@Param			after		query		int						false	"Replay the stored events after this event ID first"
+//	@Success		200			{object}	models.LifecycleEvent	"Stream of events"
+//	@Failure		400			{object}	models.Error			"Invalid filter"
+//	@Failure		500			{object}	models.Error			"Problem on server side"
+//	@Router			/events [get]
+func (h *CommandHandlers) StreamEvents(c *gin.Context) {
+	filter, err := parseEventFilter(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	// Subscribe before replaying, so that no event falls between the two
+	events, unsubscribe := h.Service.SubscribeEvents(filter)
+	defer unsubscribe()
+	var replayed []models.LifecycleEvent
+	if filter.After > 0 {
+		if replayed, err = h.Service.ReplayEvents(filter); err != nil {
+			if h.Logger != nil {
+				h.Logger.Error("Failed to replay command events", "error", err)
+			}
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to replay command events"})
+			return
+		}
+	}
+	// Streams are long-lived, the server write timeout must not cut them
+	_ = http.NewResponseController(c.Writer).SetWriteDeadline(time.Time{})
+
+	if strings.EqualFold(c.GetHeader("Upgrade"), "websocket") {
+		h.streamWebSocket(c, events, replayed)
+		return
+	}
+	h.streamSSE(c, events, replayed)
+}
+
+// streamSSE writes the events as Server-Sent Events until the client leaves or the
+// subscription ends.
+func (h *CommandHandlers) streamSSE(c *gin.Context, events <-chan models.LifecycleEvent, replayed []models.LifecycleEvent) {
+	c.Header("Content-Type", "text/event-stream")
+	c.Header("Cache-Control", "no-cache")
+	c.Header("Connection", "keep-alive")
+	c.Header("X-Accel-Buffering", "no")
+	c.Status(http.StatusOK)
+	c.Writer.Flush()
+
+	send := func(event models.LifecycleEvent) bool {
+		data, err := json.Marshal(event)
+		if err != nil {
+			return false
+		}
+		_, err = fmt.Fprintf(c.Writer, "id: %d\nevent: %s\ndata: %s\n\n", event.EventID, event.Event, data)
+		c.Writer.Flush()
+		return err == nil
+	}
+	forwardEvents(nil, c, events, replayed, send, func() bool {
+		_, err := fmt.Fprint(c.Writer, ": ping\n\n")
+		c.Writer.Flush()
+		return err == nil
+	})
+}
+
+// streamWebSocket writes the events as JSON WebSocket messages. Messages from the client
+// are ignored, reading them only tells when the connection closes. The request context is
+// not cancelled once the connection is hijacked.
+func (h *CommandHandlers) streamWebSocket(c *gin.Context, events <-chan models.LifecycleEvent, replayed []models.LifecycleEvent) {
+	server := websocket.Server{
+		// The stream is read-only and not cookie-authenticated, so any origin may read it
+		Handshake: func(*websocket.Config, *http.Request) error { return nil },
+		Handler: func(ws *websocket.Conn) {
+			defer ws.Close()
+			closed := make(chan struct{})
+			go func() {
+				defer close(closed)
+				var discard []byte
+				for websocket.Message.Receive(ws, &discard) == nil {
+				}
+			}()
+			forwardEvents(closed, c, events, replayed, func(event models.LifecycleEvent) bool {
+				return websocket.JSON.Send(ws, event) == nil
+			}, func() bool {
+				ws.PayloadType = websocket.PingFrame
+				_, err := ws.Write(nil)
+				return err == nil
+			})
+		},
+	}
+	server.ServeHTTP(c.Writer, c.Request)
+}
+
+// forwardEvents sends the replayed events, then the live ones not replayed already, with a
+// heartbeat while idle, until the client leaves, a send fails or the subscription ends.
+// Events commit in any order, so a live event may have a lower ID than the replayed ones.
+func forwardEvents(closed <-chan struct{}, c *gin.Context, events <-chan models.LifecycleEvent, replayed []models.LifecycleEvent,
+	send func(models.LifecycleEvent) bool, heartbeat func() bool) {
+	sent := make(map[int64]struct{}, len(replayed))
+	for _, event := range replayed {
+		if !send(event) {
+			return
+		}
+		sent[event.EventID] = struct{}{}
+	}
+
+	ticker := time.NewTicker(streamHeartbeat)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-c.Request.Context().Done():
+			return
+		case <-closed:
+			return
+		case event, ok := <-events:
+			if !ok {
+				return
+			}
+			if _, ok := sent[event.EventID]; ok {
+				delete(sent, event.EventID)
+				continue
+			}
+			if !send(event) {
+				return
+			}
+		case <-ticker.C:
+			if !heartbeat() {
+				return
+			}
+		}
+	}
+}
+
+// parseEventFilter reads the filter of the event stream.
+func parseEventFilter(c *gin.Context) (models.EventFilter, error) {
+	var filter models.EventFilter
+	if filter.Namespace = c.Query("namespace"); filter.Namespace != "" && !queueNamePattern.MatchString(filter.Namespace) {
+		return filter, fmt.Errorf("namespace: %s", invalidQueueNameMessage)
+	}
+	if value := c.Query("selector"); value != "" {
+		selector, err := labels.Parse(value)
+		if err != nil {
+			return filter, err
+		}
+		filter.Selector = selector
+	}
+	filter.Events = splitList(c.Query("type"))
+	for _, event := range filter.Events {
+		if !knownEvent(event) {
+			return filter, fmt.Errorf("Unknown event type '%s'", event)
+		}
+	}
+
+	after := c.Query("after")
+	if after == "" {
+		after = c.GetHeader("Last-Event-ID")
+	}
+	if after != "" {
+		id, err := strconv.ParseInt(after, 10, 64)
+		if err != nil || id < 0 {
+			return filter, fmt.Errorf("after must be a non-negative event ID")
+		}
+		filter.After = id
+	}
+	return filter, nil
+}
@@ -0,0 +1,136 @@
+package handlers
+
+import (
+	"errors"
+	services "github.com/17HIERARCH70/BashAPI/internal/services/command"
+	"github.com/gin-gonic/gin"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// defaultWaitTimeout is used when a waiting request sets no timeout.
+const defaultWaitTimeout = 30 * time.Second
+
+// WaitForCommand godoc
+//
+//	@Summary		Wait for a command
+//	@Description	Block until the command reaches a final status and return it. When the timeout elapses
+//	@Description	first, the command is returned as it is with 202. The timeout is capped by max_wait.
+//	@Tags			Getting commands
+//	@Produce		json
+//	@Param			id		path		int				true	"Command ID"
+//	@Param			timeout	query		string			false	"Time to wait, e.g. 60s or 60, 30s by default"
+//	@Success		200		{object}	models.Command	"Finished command"
+//	@Success		202		{object}	models.Command	"Command is still waiting or running"
+//	@Failure		400		{object}	models.Error	"Invalid ID or timeout"
+//	@Failure		404		{object}	models.Error	"Command not found"
+//	@Failure		500		{object}	models.Error	"Problem on server side"
+//	@Router			/commands/{id}/wait [get]
+func (h *CommandHandlers) WaitForCommand(c *gin.Context) {
+	commandID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid command ID"})
+		return
+	}
+	timeout, err := parseWaitTimeout(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	extendWriteDeadline(c, timeout)
+
+	command, finished, err := h.Service.WaitForCommand(c.Request.Context(), commandID, timeout)
+	if err != nil {
+		if errors.Is(err, services.ErrNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Command not found"})
+			return
+		}
+		if c.Request.Context().Err() != nil {
+			// The client is gone, nobody reads the response
+			return
+		}
+		if h.Logger != nil {
+			h.Logger.Error("Failed to wait for command", "error", err)
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to wait for command"})
+		return
+	}
+	if !finished {
+		c.JSON(http.StatusAccepted, command)
+		return
+	}
+	c.JSON(http.StatusOK, command)
+}
+
+// parseWait reads the wait and timeout parameters of the command creation endpoints.
+func parseWait(c *gin.Context) (bool, time.Duration, error) {
+	wait, err := strconv.ParseBool(c.DefaultQuery("wait", "false"))
+	if err != nil {
+		return false, 0, errors.New("wait must be a boolean")
+	}
+	timeout, err := parseWaitTimeout(c)
+	return wait, timeout, err
+}
+
+// parseWaitTimeout reads the timeout parameter as a duration or a number of seconds.
+func parseWaitTimeout(c *gin.Context) (time.Duration, error) {
+	value := c.Query("timeout")
+	if value == "" {
+		return defaultWaitTimeout, nil
+	}
+	timeout, err := time.ParseDuration(value)
+	if err != nil {
+		seconds, convErr := strconv.Atoi(value)
+		if convErr != nil {
+			return 0, errors.New("timeout must be a duration like 60s")
+		}
+		timeout = time.Duration(seconds) * time.Second
+	}
+	if timeout <= 0 {
+		return 0, errors.New("timeout must be positive")
+	}
+	return timeout, nil
+}
+
+// extendWriteDeadline keeps the server write timeout from cutting a long-polling response.
+func extendWriteDeadline(c *gin.Context, timeout time.Duration) {
+	// Not every writer supports deadlines, e.g. the recorder of the tests
+	_ = http.NewResponseController(c.Writer).SetWriteDeadline(time.Now().Add(timeout + 10*time.Second))
+}
+
+// respondCreated answers a command creation request. With wait=true it waits for the
+// command to finish and returns it, with its output and exit code, unless the timeout elapses.
+func (h *CommandHandlers) respondCreated(c *gin.Context, response gin.H, wait bool, timeout time.Duration) {
+	commandID, ok := responseCommandID(response)
+	if !wait || !ok {
+		c.JSON(http.StatusAccepted, response)
+		return
+	}
+	extendWriteDeadline(c, timeout)
+
+	command, finished, err := h.Service.WaitForCommand(c.Request.Context(), commandID, timeout)
+	if err != nil || !finished {
+		if err != nil && h.Logger != nil && c.Request.Context().Err() == nil {
+			h.Logger.Error("Failed to wait for created command", "commandID", commandID, "error", err)
+		}
+		if err == nil {
+			response["status"] = command.Status
+		}
+		c.JSON(http.StatusAccepted, response)
+		return
+	}
+	c.JSON(http.StatusOK, command)
+}
+
+// responseCommandID reads the command ID of a creation response, which is a float when the
+// response was replayed from an idempotency key.
+func responseCommandID(response gin.H) (int, bool) {
+	switch id := response["id"].(type) {
+	case int:
+		return id, true
+	case float64:
+		return int(id), true
+	}
+	return 0, false
+}
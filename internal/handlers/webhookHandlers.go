@@ -0,0 +1,213 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"github.com/17HIERARCH70/BashAPI/internal/domain/models"
+	services "github.com/17HIERARCH70/BashAPI/internal/services/command"
+	"github.com/gin-gonic/gin"
+	"net/http"
+	"net/url"
+	"strconv"
+)
+
+const (
+	// maxWebhookURLLength limits the URLs of webhooks and callbacks.
+	maxWebhookURLLength = 2048
+	// maxWebhookSecretLength limits the secrets of webhooks.
+	maxWebhookSecretLength = 256
+)
+
+// webhookRequest is the body of the webhook creation endpoint.
+type webhookRequest struct {
+	URL string `json:"url" binding:"required"`
+	// Secret signs the deliveries, the configured secret is used when empty.
+	Secret string `json:"secret"`
+	// Events lists the subscribed events, all of them when empty.
+	Events []string `json:"events"`
+}
+
+// CreateWebhook godoc
+//
+//	@Summary		Create a webhook
+//	@Description	Subscribe a URL to command events: queued, started, completed, failed, timeout, stopped,
+//	@Description	cancelled and expired, all of them when events is empty. Every delivery is a POST of a JSON
+//	@Description	payload signed with HMAC-SHA256 in the X-BashAPI-Signature header, retried with backoff.
+//	@Tags			Admin
+//	@Accept			json
+//	@Produce		json
+//	@Param			webhook	body		webhookRequest	true	"URL, secret and events of the webhook"
+//	@Success		201		{object}	models.Webhook	"Webhook created"
+//	@Failure		400		{object}	models.Error	"Invalid URL or events"
+//	@Failure		500		{object}	models.Error	"Problem on server side"
+//	@Router			/admin/webhooks [post]
+func (h *CommandHandlers) CreateWebhook(c *gin.Context) {
+	var request webhookRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": bindErrorMessage(err)})
+		return
+	}
+	if err := validateWebhookURL(request.URL); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if len(request.Secret) > maxWebhookSecretLength {
+		c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("Secret must not exceed %d characters", maxWebhookSecretLength)})
+		return
+	}
+	for _, event := range request.Events {
+		if !knownEvent(event) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("Unknown event '%s'", event)})
+			return
+		}
+	}
+
+	hook, err := h.Service.CreateWebhook(models.Webhook{URL: request.URL, Secret: request.Secret, Events: request.Events})
+	if err != nil {
+		if h.Logger != nil {
+			h.Logger.Error("Failed to create webhook", "error", err)
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create webhook"})
+		return
+	}
+	c.JSON(http.StatusCreated, hook)
+}
+
+// GetWebhooks godoc
+//
+//	@Summary		Get webhooks
+//	@Description	List the webhooks. Secrets are not returned.
+//	@Tags			Admin
+//	@Produce		json
+//	@Success		200	{array}		models.Webhook	"Webhooks"
+//	@Failure		500	{object}	models.Error	"Problem on server side"
+//	@Router			/admin/webhooks [get]
+func (h *CommandHandlers) GetWebhooks(c *gin.Context) {
+	webhooks, err := h.Service.FetchWebhooks()
+	if err != nil {
+		if h.Logger != nil {
+			h.Logger.Error("Failed to fetch webhooks", "error", err)
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch webhooks"})
+		return
+	}
+	for i := range webhooks {
+		webhooks[i].Secret = ""
+	}
+	c.JSON(http.StatusOK, webhooks)
+}
+
+// DeleteWebhook godoc
+//
+//	@Summary		Delete a webhook
+//	@Description	Delete a webhook together with its pending and past deliveries.
+//	@Tags			Admin
+//	@Produce		json
+//	@Param			id	path		int				true	"Webhook ID"
+//	@Success		200	{object}	models.Message	"Webhook deleted"
+//	@Failure		400	{object}	models.Error	"Invalid ID supplied"
+//	@Failure		404	{object}	models.Error	"Webhook not found"
+//	@Failure		500	{object}	models.Error	"Problem on server side"
+//	@Router			/admin/webhooks/{id} [delete]
+func (h *CommandHandlers) DeleteWebhook(c *gin.Context) {
+	webhookID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid webhook ID"})
+		return
+	}
+
+	if err := h.Service.DeleteWebhook(webhookID); err != nil {
+		if errors.Is(err, services.ErrWebhookNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Webhook not found"})
+			return
+		}
+		if h.Logger != nil {
+			h.Logger.Error("Failed to delete webhook", "error", err)
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete webhook"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "Webhook deleted", "id": webhookID})
+}
+
+// GetWebhookDeliveries godoc
+//
+//	@Summary		Get webhook deliveries
+//	@Description	List the latest deliveries of a webhook, newest first, with every attempt and its response.
+//	@Tags			Admin
+//	@Produce		json
+//	@Param			id		path		int						true	"Webhook ID"
+//	@Param			limit	query		int						false	"Maximum number of deliveries, 100 by default"
+//	@Success		200		{array}		models.WebhookDelivery	"Deliveries"
+//	@Failure		400		{object}	models.Error			"Invalid ID or limit"
+//	@Failure		500		{object}	models.Error			"Problem on server side"
+//	@Router			/admin/webhooks/{id}/deliveries [get]
+func (h *CommandHandlers) GetWebhookDeliveries(c *gin.Context) {
+	webhookID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid webhook ID"})
+		return
+	}
+	h.getDeliveries(c, models.DeliveryQuery{WebhookID: &webhookID})
+}
+
+// GetCommandDeliveries godoc
+//
+//	@Summary		Get command deliveries
+//	@Description	List the latest webhook and callback deliveries of a command's events, newest first, with every attempt.
+//	@Tags			Getting commands
+//	@Produce		json
+//	@Param			id		path		int						true	"Command ID"
+//	@Param			limit	query		int						false	"Maximum number of deliveries, 100 by default"
+//	@Success		200		{array}		models.WebhookDelivery	"Deliveries"
+//	@Failure		400		{object}	models.Error			"Invalid ID or limit"
+//	@Failure		500		{object}	models.Error			"Problem on server side"
+//	@Router			/commands/{id}/deliveries [get]
+func (h *CommandHandlers) GetCommandDeliveries(c *gin.Context) {
+	commandID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid command ID"})
+		return
+	}
+	h.getDeliveries(c, models.DeliveryQuery{CommandID: &commandID})
+}
+
+func (h *CommandHandlers) getDeliveries(c *gin.Context, query models.DeliveryQuery) {
+	limit, err := nonNegativeQuery(c, "limit")
+	if err != nil || limit > maxListLimit {
+		c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("limit must be between 0 and %d", maxListLimit)})
+		return
+	}
+	query.Limit = int(limit)
+
+	deliveries, err := h.Service.FetchDeliveries(query)
+	if err != nil {
+		if h.Logger != nil {
+			h.Logger.Error("Failed to fetch webhook deliveries", "error", err)
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch webhook deliveries"})
+		return
+	}
+	c.JSON(http.StatusOK, deliveries)
+}
+
+// validateWebhookURL accepts absolute http and https URLs.
+func validateWebhookURL(value string) error {
+	if len(value) > maxWebhookURLLength {
+		return fmt.Errorf("URL must not exceed %d characters", maxWebhookURLLength)
+	}
+	parsed, err := url.Parse(value)
+	if err != nil || (parsed.Scheme != "http" && parsed.Scheme != "https") || parsed.Host == "" {
+		return errors.New("URL must be an absolute http or https URL")
+	}
+	return nil
+}
+
+func knownEvent(event string) bool {
+	for _, known := range models.StatusEvents {
+		if known == event {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,177 @@
+package archive
+
+import (
+	"compress/gzip"
+	"errors"
+	"fmt"
+	"github.com/klauspost/compress/zstd"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// Supported compressions, also used as the file extension of archived objects.
+const (
+	Zstd = "zstd"
+	Gzip = "gzip"
+)
+
+var ErrUnknownCompression = errors.New("unknown archive compression")
+
+// Store keeps archived objects. Put returns a reference that is later passed to Open
+// and Delete, so a store may lay its objects out however it likes.
+type Store interface {
+	Put(name string, data io.Reader) (string, error)
+	Open(ref string) (io.ReadCloser, error)
+	Delete(ref string) error
+}
+
+// FileStore keeps compressed objects as files below Dir. References are paths relative
+// to Dir, their extension tells how the object is compressed.
+type FileStore struct {
+	Dir         string
+	Compression string
+}
+
+// NewFileStore creates a file store, checking the compression.
+func NewFileStore(dir, compression string) (*FileStore, error) {
+	if _, err := extension(compression); err != nil {
+		return nil, err
+	}
+	return &FileStore{Dir: dir, Compression: compression}, nil
+}
+
+// Put compresses data into a new file named after name. The file is written under a
+// temporary name and renamed once complete, so a reference never points to a partial object.
+func (s *FileStore) Put(name string, data io.Reader) (string, error) {
+	ext, err := extension(s.Compression)
+	if err != nil {
+		return "", err
+	}
+	ref := filepath.Clean(name) + ext
+	path, err := s.path(ref)
+	if err != nil {
+		return "", err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o750); err != nil {
+		return "", err
+	}
+
+	file, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".*.tmp")
+	if err != nil {
+		return "", err
+	}
+	defer os.Remove(file.Name())
+	defer file.Close()
+
+	if err := compress(s.Compression, file, data); err != nil {
+		return "", err
+	}
+	if err := file.Close(); err != nil {
+		return "", err
+	}
+	if err := os.Rename(file.Name(), path); err != nil {
+		return "", err
+	}
+	return ref, nil
+}
+
+// Open returns the decompressed content of an archived object.
+func (s *FileStore) Open(ref string) (io.ReadCloser, error) {
+	path, err := s.path(ref)
+	if err != nil {
+		return nil, err
+	}
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+
+	switch {
+	case strings.HasSuffix(ref, ".zst"):
+		decoder, err := zstd.NewReader(file)
+		if err != nil {
+			file.Close()
+			return nil, err
+		}
+		return &readCloser{Reader: decoder, close: func() error {
+			decoder.Close()
+			return file.Close()
+		}}, nil
+	case strings.HasSuffix(ref, ".gz"):
+		reader, err := gzip.NewReader(file)
+		if err != nil {
+			file.Close()
+			return nil, err
+		}
+		return &readCloser{Reader: reader, close: func() error {
+			reader.Close()
+			return file.Close()
+		}}, nil
+	default:
+		file.Close()
+		return nil, fmt.Errorf("%w: %s", ErrUnknownCompression, ref)
+	}
+}
+
+// Delete removes an archived object. Deleting a missing object is not an error.
+func (s *FileStore) Delete(ref string) error {
+	path, err := s.path(ref)
+	if err != nil {
+		return err
+	}
+	if err := os.Remove(path); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+	return nil
+}
+
+// path resolves a reference below Dir, rejecting references escaping it.
+func (s *FileStore) path(ref string) (string, error) {
+	if !filepath.IsLocal(ref) {
+		return "", fmt.Errorf("invalid archive reference %q", ref)
+	}
+	return filepath.Join(s.Dir, ref), nil
+}
+
+func extension(compression string) (string, error) {
+	switch compression {
+	case Zstd:
+		return ".zst", nil
+	case Gzip:
+		return ".gz", nil
+	default:
+		return "", fmt.Errorf("%w: %q", ErrUnknownCompression, compression)
+	}
+}
+
+func compress(compression string, w io.Writer, data io.Reader) error {
+	var encoder io.WriteCloser
+	var err error
+	switch compression {
+	case Zstd:
+		encoder, err = zstd.NewWriter(w)
+	case Gzip:
+		encoder = gzip.NewWriter(w)
+	default:
+		err = fmt.Errorf("%w: %q", ErrUnknownCompression, compression)
+	}
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(encoder, data); err != nil {
+		encoder.Close()
+		return err
+	}
+	return encoder.Close()
+}
+
+type readCloser struct {
+	io.Reader
+	close func() error
+}
+
+func (r *readCloser) Close() error {
+	return r.close()
+}
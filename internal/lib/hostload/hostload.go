@@ -0,0 +1,123 @@
+package hostload
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"strings"
+)
+
+// Metrics is a snapshot of the host load as reported by procfs.
+type Metrics struct {
+	CPUs         int
+	Load1        float64
+	Load5        float64
+	Load15       float64
+	MemTotal     uint64 // bytes
+	MemAvailable uint64 // bytes
+	// CPUPressure is the "some avg10" share of time tasks stalled on CPU, in percent.
+	// It is nil when the kernel does not expose /proc/pressure.
+	CPUPressure *float64
+}
+
+// Read collects the metrics from the procfs mounted at procRoot (usually /proc).
+func Read(procRoot string) (Metrics, error) {
+	m := Metrics{CPUs: runtime.NumCPU()}
+
+	data, err := os.ReadFile(filepath.Join(procRoot, "loadavg"))
+	if err != nil {
+		return m, err
+	}
+	if m.Load1, m.Load5, m.Load15, err = ParseLoadAvg(string(data)); err != nil {
+		return m, err
+	}
+
+	data, err = os.ReadFile(filepath.Join(procRoot, "meminfo"))
+	if err != nil {
+		return m, err
+	}
+	if m.MemTotal, m.MemAvailable, err = ParseMemInfo(string(data)); err != nil {
+		return m, err
+	}
+
+	// Pressure stall information is optional (kernel 4.20+ with PSI enabled)
+	data, err = os.ReadFile(filepath.Join(procRoot, "pressure", "cpu"))
+	if err == nil {
+		pressure, err := ParsePressure(string(data))
+		if err != nil {
+			return m, err
+		}
+		m.CPUPressure = &pressure
+	}
+
+	return m, nil
+}
+
+// ParseLoadAvg parses the content of /proc/loadavg.
+func ParseLoadAvg(data string) (load1, load5, load15 float64, err error) {
+	fields := strings.Fields(data)
+	if len(fields) < 3 {
+		return 0, 0, 0, fmt.Errorf("unexpected loadavg format: %q", data)
+	}
+	loads := make([]float64, 3)
+	for i := range loads {
+		if loads[i], err = strconv.ParseFloat(fields[i], 64); err != nil {
+			return 0, 0, 0, fmt.Errorf("invalid load average %q: %w", fields[i], err)
+		}
+	}
+	return loads[0], loads[1], loads[2], nil
+}
+
+// ParseMemInfo extracts MemTotal and MemAvailable, in bytes, from /proc/meminfo.
+func ParseMemInfo(data string) (total, available uint64, err error) {
+	var foundTotal, foundAvailable bool
+	scanner := bufio.NewScanner(strings.NewReader(data))
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) < 2 {
+			continue
+		}
+		var target *uint64
+		switch fields[0] {
+		case "MemTotal:":
+			target, foundTotal = &total, true
+		case "MemAvailable:":
+			target, foundAvailable = &available, true
+		default:
+			continue
+		}
+		value, err := strconv.ParseUint(fields[1], 10, 64)
+		if err != nil {
+			return 0, 0, fmt.Errorf("invalid meminfo value %q: %w", fields[1], err)
+		}
+		if len(fields) > 2 && fields[2] == "kB" {
+			value *= 1024
+		}
+		*target = value
+	}
+	if !foundTotal || !foundAvailable {
+		return 0, 0, errors.New("meminfo has no MemTotal or MemAvailable")
+	}
+	return total, available, nil
+}
+
+// ParsePressure returns the "some avg10" value from a /proc/pressure file.
+func ParsePressure(data string) (float64, error) {
+	scanner := bufio.NewScanner(strings.NewReader(data))
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) == 0 || fields[0] != "some" {
+			continue
+		}
+		for _, field := range fields[1:] {
+			if value, ok := strings.CutPrefix(field, "avg10="); ok {
+				return strconv.ParseFloat(value, 64)
+			}
+		}
+	}
+	return 0, fmt.Errorf("unexpected pressure format: %q", data)
+}
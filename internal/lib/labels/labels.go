@@ -0,0 +1,173 @@
+package labels
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// Operators of selector requirements.
+const (
+	Equals    = "="
+	NotEquals = "!="
+	Exists    = "exists"
+	NotExists = "!exists"
+)
+
+const (
+	// MaxLabels limits the number of labels, and of annotations, of one command.
+	MaxLabels = 64
+	// MaxAnnotationLength limits the size of an annotation value.
+	MaxAnnotationLength = 4096
+)
+
+var (
+	ErrInvalidSelector = errors.New("invalid label selector")
+	ErrInvalidLabel    = errors.New("invalid label")
+)
+
+var (
+	keyPattern   = regexp.MustCompile(`^[A-Za-z0-9_./-]{1,63}$`)
+	valuePattern = regexp.MustCompile(`^[A-Za-z0-9_.:/-]{0,63}$`)
+)
+
+// Requirement is one condition of a selector. Value is only used by Equals and NotEquals.
+type Requirement struct {
+	Key      string
+	Operator string
+	Value    string
+}
+
+// Selector selects the commands whose labels meet all of its requirements. It is written
+// as comma separated requirements: "key=value", "key!=value", "key" and "!key", the last
+// two testing whether the key is set. Like in Kubernetes, "key!=value" also matches
+// commands without the key.
+type Selector []Requirement
+
+// Parse parses a selector, an empty string selects everything.
+func Parse(value string) (Selector, error) {
+	var selector Selector
+	for _, term := range strings.Split(value, ",") {
+		term = strings.TrimSpace(term)
+		if term == "" {
+			continue
+		}
+		var req Requirement
+		switch {
+		case strings.Contains(term, "!="):
+			req.Key, req.Value, _ = strings.Cut(term, "!=")
+			req.Operator = NotEquals
+		case strings.Contains(term, "=="):
+			req.Key, req.Value, _ = strings.Cut(term, "==")
+			req.Operator = Equals
+		case strings.Contains(term, "="):
+			req.Key, req.Value, _ = strings.Cut(term, "=")
+			req.Operator = Equals
+		case strings.HasPrefix(term, "!"):
+			req.Key, req.Operator = term[1:], NotExists
+		default:
+			req.Key, req.Operator = term, Exists
+		}
+		req.Key, req.Value = strings.TrimSpace(req.Key), strings.TrimSpace(req.Value)
+		if !keyPattern.MatchString(req.Key) || !valuePattern.MatchString(req.Value) {
+			return nil, fmt.Errorf("%w: %q", ErrInvalidSelector, term)
+		}
+		selector = append(selector, req)
+	}
+	return selector, nil
+}
+
+// Matches reports whether labels meet every requirement of the selector.
+func (s Selector) Matches(labels map[string]string) bool {
+	for _, req := range s {
+		value, ok := labels[req.Key]
+		switch req.Operator {
+		case Equals:
+			if !ok || value != req.Value {
+				return false
+			}
+		case NotEquals:
+			if ok && value == req.Value {
+				return false
+			}
+		case Exists:
+			if !ok {
+				return false
+			}
+		case NotExists:
+			if ok {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// String formats the selector the way Parse reads it.
+func (s Selector) String() string {
+	terms := make([]string, 0, len(s))
+	for _, req := range s {
+		switch req.Operator {
+		case Exists:
+			terms = append(terms, req.Key)
+		case NotExists:
+			terms = append(terms, "!"+req.Key)
+		default:
+			terms = append(terms, req.Key+req.Operator+req.Value)
+		}
+	}
+	return strings.Join(terms, ",")
+}
+
+// MarshalJSON writes the selector as its string form.
+func (s Selector) MarshalJSON() ([]byte, error) {
+	return json.Marshal(s.String())
+}
+
+// UnmarshalJSON reads a selector from its string form.
+func (s *Selector) UnmarshalJSON(data []byte) error {
+	var value string
+	if err := json.Unmarshal(data, &value); err != nil {
+		return err
+	}
+	selector, err := Parse(value)
+	if err != nil {
+		return err
+	}
+	*s = selector
+	return nil
+}
+
+// Validate checks the labels of a command, which have to be usable in selectors.
+func Validate(labels map[string]string) error {
+	if len(labels) > MaxLabels {
+		return fmt.Errorf("%w: at most %d labels are allowed", ErrInvalidLabel, MaxLabels)
+	}
+	for key, value := range labels {
+		if !keyPattern.MatchString(key) {
+			return fmt.Errorf("%w: key %q must be 1-63 letters, digits, '_', '.', '/' or '-'", ErrInvalidLabel, key)
+		}
+		if !valuePattern.MatchString(value) {
+			return fmt.Errorf("%w: value of %q must be at most 63 letters, digits, '_', '.', ':', '/' or '-'", ErrInvalidLabel, key)
+		}
+	}
+	return nil
+}
+
+// ValidateAnnotations checks the annotations of a command, whose values are free-form.
+func ValidateAnnotations(annotations map[string]string) error {
+	if len(annotations) > MaxLabels {
+		return fmt.Errorf("%w: at most %d annotations are allowed", ErrInvalidLabel, MaxLabels)
+	}
+	for key, value := range annotations {
+		if !keyPattern.MatchString(key) {
+			return fmt.Errorf("%w: annotation key %q must be 1-63 letters, digits, '_', '.', '/' or '-'", ErrInvalidLabel, key)
+		}
+		if len(value) > MaxAnnotationLength {
+			return fmt.Errorf("%w: annotation %q exceeds %d bytes", ErrInvalidLabel, key, MaxAnnotationLength)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,122 @@
+package lines
+
+import (
+	"bytes"
+	"regexp"
+)
+
+// Head keeps the first N lines matching Match from text written in order.
+// N <= 0 keeps every matching line, a nil Match matches every line.
+type Head struct {
+	N     int
+	Match *regexp.Regexp
+
+	partial []byte
+	lines   [][]byte
+}
+
+// Write feeds the next piece of text. Pieces may split lines anywhere.
+func (h *Head) Write(p []byte) (int, error) {
+	n := len(p)
+	for len(p) > 0 && !h.Done() {
+		i := bytes.IndexByte(p, '\n')
+		if i < 0 {
+			h.partial = append(h.partial, p...)
+			break
+		}
+		h.add(append(h.partial, p[:i]...))
+		h.partial = nil
+		p = p[i+1:]
+	}
+	return n, nil
+}
+
+// Done reports whether N lines have been collected and the rest of the text can be skipped.
+func (h *Head) Done() bool {
+	return h.N > 0 && len(h.lines) >= h.N
+}
+
+// Lines returns the collected lines, including a last line without a trailing newline.
+func (h *Head) Lines() [][]byte {
+	if len(h.partial) > 0 && !h.Done() {
+		h.add(h.partial)
+		h.partial = nil
+	}
+	return h.lines
+}
+
+func (h *Head) add(line []byte) {
+	if h.Match == nil || h.Match.Match(line) {
+		h.lines = append(h.lines, line)
+	}
+}
+
+// Tail keeps the last N lines matching Match from text fed backwards, from its end to its start.
+// N <= 0 keeps every matching line, a nil Match matches every line.
+type Tail struct {
+	N     int
+	Match *regexp.Regexp
+
+	partial     []byte
+	sawNewline  bool
+	reversed    [][]byte
+	pastLastEnd bool
+}
+
+// Prepend feeds the piece of text directly preceding everything fed so far.
+func (t *Tail) Prepend(p []byte) {
+	for len(p) > 0 && !t.Done() {
+		i := bytes.LastIndexByte(p, '\n')
+		if i < 0 {
+			t.partial = append(append([]byte(nil), p...), t.partial...)
+			break
+		}
+		t.sawNewline = true
+		t.add(append(append([]byte(nil), p[i+1:]...), t.partial...))
+		t.partial = nil
+		p = p[:i]
+	}
+}
+
+// Done reports whether N lines have been collected and the rest of the text can be skipped.
+func (t *Tail) Done() bool {
+	return t.N > 0 && len(t.reversed) >= t.N
+}
+
+// Lines returns the collected lines in their original order. It must be called once the
+// start of the text has been fed or Done reports true.
+func (t *Tail) Lines() [][]byte {
+	if (len(t.partial) > 0 || t.sawNewline) && !t.Done() {
+		t.add(t.partial)
+		t.partial = nil
+		t.sawNewline = false
+	}
+	result := make([][]byte, len(t.reversed))
+	for i, line := range t.reversed {
+		result[len(t.reversed)-1-i] = line
+	}
+	return result
+}
+
+func (t *Tail) add(line []byte) {
+	// The empty text after the final newline is not a line
+	if !t.pastLastEnd {
+		t.pastLastEnd = true
+		if len(line) == 0 {
+			return
+		}
+	}
+	if t.Match == nil || t.Match.Match(line) {
+		t.reversed = append(t.reversed, line)
+	}
+}
+
+// Join renders lines as text, each terminated by a newline.
+func Join(lines [][]byte) []byte {
+	var buf bytes.Buffer
+	for _, line := range lines {
+		buf.Write(line)
+		buf.WriteByte('\n')
+	}
+	return buf.Bytes()
+}
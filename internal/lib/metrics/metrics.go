@@ -0,0 +1,165 @@
+package metrics
+
+import (
+	"github.com/jackc/pgx/v4/pgxpool"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+)
+
+// namespace prefixes the names of all metrics of the service.
+const namespace = "bashapi"
+
+// durationBuckets span 100ms to about 55 minutes, which covers both short scripts and
+// commands waiting behind long ones.
+var durationBuckets = prometheus.ExponentialBuckets(0.1, 2, 16)
+
+// Metrics updated by the command service and the HTTP middleware. Commands are counted by
+// the replica that changed their status, so the series of all replicas add up.
+var (
+	CommandsFinished = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "commands_finished_total",
+		Help:      "Commands that reached a final status, by queue and status.",
+	}, []string{"queue", "status"})
+
+	QueueWait = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "command_queue_wait_seconds",
+		Help:      "Time commands waited in the queue before they started.",
+		Buckets:   durationBuckets,
+	}, []string{"queue"})
+
+	ExecutionDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "command_execution_duration_seconds",
+		Help:      "Time from the start of commands to their final status.",
+		Buckets:   durationBuckets,
+	}, []string{"queue", "status"})
+
+	HTTPRequests = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "http_requests_total",
+		Help:      "HTTP requests by method, route and status code.",
+	}, []string{"method", "route", "code"})
+
+	HTTPDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "http_request_duration_seconds",
+		Help:      "Latency of HTTP requests by method and route.",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"method", "route"})
+)
+
+// NewRegistry returns a registry with the metrics of the service, the Go runtime and the
+// process, and the given collectors.
+func NewRegistry(extra ...prometheus.Collector) (*prometheus.Registry, error) {
+	registry := prometheus.NewRegistry()
+	all := append([]prometheus.Collector{
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+		CommandsFinished, QueueWait, ExecutionDuration, HTTPRequests, HTTPDuration,
+	}, extra...)
+	for _, collector := range all {
+		if err := registry.Register(collector); err != nil {
+			return nil, err
+		}
+	}
+	return registry, nil
+}
+
+// CommandStats are the current numbers of commands reported as gauges.
+type CommandStats struct {
+	Running int
+	Queued  int
+	// Paused are the queued commands of paused queues.
+	Paused    int
+	UsedSlots int
+	Slots     int
+}
+
+var (
+	runningDesc   = prometheus.NewDesc(namespace+"_commands_running", "Commands currently running.", nil, nil)
+	queuedDesc    = prometheus.NewDesc(namespace+"_commands_queued", "Commands waiting in the queue.", nil, nil)
+	pausedDesc    = prometheus.NewDesc(namespace+"_commands_paused", "Queued commands held back by a paused queue.", nil, nil)
+	usedSlotsDesc = prometheus.NewDesc(namespace+"_concurrency_slots_used", "Concurrency slots taken by running commands.", nil, nil)
+	slotsDesc     = prometheus.NewDesc(namespace+"_concurrency_slots", "Concurrency slots shared by running commands.", nil, nil)
+	statsUpDesc   = prometheus.NewDesc(namespace+"_command_stats_up", "Whether the command gauges could be read from the database.", nil, nil)
+)
+
+// commandCollector reads the command gauges on every scrape. They describe the shared
+// database, so every replica reports the same values.
+type commandCollector struct {
+	fetch func() (CommandStats, error)
+}
+
+// NewCommandCollector returns a collector of the gauges returned by fetch.
+func NewCommandCollector(fetch func() (CommandStats, error)) prometheus.Collector {
+	return &commandCollector{fetch: fetch}
+}
+
+func (c *commandCollector) Describe(ch chan<- *prometheus.Desc) {
+	for _, desc := range []*prometheus.Desc{runningDesc, queuedDesc, pausedDesc, usedSlotsDesc, slotsDesc, statsUpDesc} {
+		ch <- desc
+	}
+}
+
+func (c *commandCollector) Collect(ch chan<- prometheus.Metric) {
+	stats, err := c.fetch()
+	if err != nil {
+		ch <- prometheus.MustNewConstMetric(statsUpDesc, prometheus.GaugeValue, 0)
+		return
+	}
+	ch <- prometheus.MustNewConstMetric(statsUpDesc, prometheus.GaugeValue, 1)
+	ch <- prometheus.MustNewConstMetric(runningDesc, prometheus.GaugeValue, float64(stats.Running))
+	ch <- prometheus.MustNewConstMetric(queuedDesc, prometheus.GaugeValue, float64(stats.Queued))
+	ch <- prometheus.MustNewConstMetric(pausedDesc, prometheus.GaugeValue, float64(stats.Paused))
+	ch <- prometheus.MustNewConstMetric(usedSlotsDesc, prometheus.GaugeValue, float64(stats.UsedSlots))
+	ch <- prometheus.MustNewConstMetric(slotsDesc, prometheus.GaugeValue, float64(stats.Slots))
+}
+
+func poolDesc(name, help string) *prometheus.Desc {
+	return prometheus.NewDesc(namespace+"_db_pool_"+name, help, nil, nil)
+}
+
+var (
+	acquiredConnsDesc     = poolDesc("acquired_connections", "Connections currently in use.")
+	idleConnsDesc         = poolDesc("idle_connections", "Idle connections in the pool.")
+	constructingConnsDesc = poolDesc("constructing_connections", "Connections being established.")
+	totalConnsDesc        = poolDesc("connections", "Connections in the pool.")
+	maxConnsDesc          = poolDesc("max_connections", "Maximum size of the pool.")
+	acquiresDesc          = poolDesc("acquires_total", "Successful connection acquisitions.")
+	emptyAcquiresDesc     = poolDesc("empty_acquires_total", "Acquisitions that had to wait for a connection.")
+	canceledAcquiresDesc  = poolDesc("canceled_acquires_total", "Acquisitions canceled before a connection was available.")
+	acquireDurationDesc   = poolDesc("acquire_duration_seconds_total", "Total time spent acquiring connections.")
+)
+
+// poolCollector reports the statistics of a pgx connection pool.
+type poolCollector struct {
+	stat func() *pgxpool.Stat
+}
+
+// NewPoolCollector returns a collector of the statistics returned by stat, usually the
+// Stat method of the pool.
+func NewPoolCollector(stat func() *pgxpool.Stat) prometheus.Collector {
+	return &poolCollector{stat: stat}
+}
+
+func (c *poolCollector) Describe(ch chan<- *prometheus.Desc) {
+	for _, desc := range []*prometheus.Desc{acquiredConnsDesc, idleConnsDesc, constructingConnsDesc, totalConnsDesc,
+		maxConnsDesc, acquiresDesc, emptyAcquiresDesc, canceledAcquiresDesc, acquireDurationDesc} {
+		ch <- desc
+	}
+}
+
+func (c *poolCollector) Collect(ch chan<- prometheus.Metric) {
+	stat := c.stat()
+	ch <- prometheus.MustNewConstMetric(acquiredConnsDesc, prometheus.GaugeValue, float64(stat.AcquiredConns()))
+	ch <- prometheus.MustNewConstMetric(idleConnsDesc, prometheus.GaugeValue, float64(stat.IdleConns()))
+	ch <- prometheus.MustNewConstMetric(constructingConnsDesc, prometheus.GaugeValue, float64(stat.ConstructingConns()))
+	ch <- prometheus.MustNewConstMetric(totalConnsDesc, prometheus.GaugeValue, float64(stat.TotalConns()))
+	ch <- prometheus.MustNewConstMetric(maxConnsDesc, prometheus.GaugeValue, float64(stat.MaxConns()))
+	ch <- prometheus.MustNewConstMetric(acquiresDesc, prometheus.CounterValue, float64(stat.AcquireCount()))
+	ch <- prometheus.MustNewConstMetric(emptyAcquiresDesc, prometheus.CounterValue, float64(stat.EmptyAcquireCount()))
+	ch <- prometheus.MustNewConstMetric(canceledAcquiresDesc, prometheus.CounterValue, float64(stat.CanceledAcquireCount()))
+	ch <- prometheus.MustNewConstMetric(acquireDurationDesc, prometheus.CounterValue, stat.AcquireDuration().Seconds())
+}
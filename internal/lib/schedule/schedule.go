@@ -0,0 +1,85 @@
+package schedule
+
+import (
+	"container/heap"
+	"time"
+)
+
+// Running is a command occupying slots until it is expected to finish.
+type Running struct {
+	Weight     int
+	FinishesAt time.Time
+}
+
+// Pending is a queued command in dispatch order.
+type Pending struct {
+	Weight   int
+	Duration time.Duration
+	// Held commands (e.g. of a paused queue) are not started and get no estimate.
+	Held bool
+}
+
+// EstimateStarts simulates the dispatcher over capacity slots and returns the expected
+// start time of every pending command, or nil for held ones and those that never fit.
+// Like the dispatcher, a command that does not fit yet does not block lighter ones behind it
+// from being started earlier.
+func EstimateStarts(now time.Time, capacity int, running []Running, pending []Pending) []*time.Time {
+	starts := make([]*time.Time, len(pending))
+
+	finishing := &finishHeap{}
+	free := capacity
+	for _, r := range running {
+		heap.Push(finishing, r)
+		free -= r.Weight
+	}
+
+	clock := now
+	waiting := make([]int, 0, len(pending))
+	for i, p := range pending {
+		if !p.Held && p.Weight <= capacity {
+			waiting = append(waiting, i)
+		}
+	}
+
+	for len(waiting) > 0 {
+		// Start everything that fits at the current moment, in queue order
+		rest := waiting[:0]
+		for _, i := range waiting {
+			p := pending[i]
+			if p.Weight > free {
+				rest = append(rest, i)
+				continue
+			}
+			start := clock
+			starts[i] = &start
+			free -= p.Weight
+			heap.Push(finishing, Running{Weight: p.Weight, FinishesAt: clock.Add(p.Duration)})
+		}
+		waiting = rest
+		if len(waiting) == 0 || finishing.Len() == 0 {
+			break
+		}
+
+		// Advance to the next moment a command finishes and releases its slots
+		next := heap.Pop(finishing).(Running)
+		if next.FinishesAt.After(clock) {
+			clock = next.FinishesAt
+		}
+		free += next.Weight
+	}
+	return starts
+}
+
+// finishHeap orders running commands by their expected finish time.
+type finishHeap []Running
+
+func (h finishHeap) Len() int           { return len(h) }
+func (h finishHeap) Less(i, j int) bool { return h[i].FinishesAt.Before(h[j].FinishesAt) }
+func (h finishHeap) Swap(i, j int)      { h[i], h[j] = h[j], h[i] }
+func (h *finishHeap) Push(x any)        { *h = append(*h, x.(Running)) }
+func (h *finishHeap) Pop() any {
+	old := *h
+	item := old[len(old)-1]
+	*h = old[:len(old)-1]
+	return item
+}
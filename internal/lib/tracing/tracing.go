@@ -0,0 +1,81 @@
+package tracing
+
+import (
+	"context"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.24.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// instrumentationName names the tracer of the service.
+const instrumentationName = "github.com/17HIERARCH70/BashAPI"
+
+// traceParentKey is the W3C trace context header, also the environment variable of scripts.
+const traceParentKey = "traceparent"
+
+// Options configures the export of traces.
+type Options struct {
+	// Endpoint is the URL of an OTLP/HTTP collector, e.g. http://localhost:4318.
+	Endpoint    string
+	ServiceName string
+	// SampleRatio is the share of new traces recorded, traces continued from a caller
+	// follow the decision of the caller.
+	SampleRatio float64
+}
+
+// Setup installs a global tracer provider exporting to the OTLP endpoint and the W3C trace
+// context propagator. The returned function flushes the pending spans and stops the export.
+func Setup(ctx context.Context, opts Options) (func(context.Context) error, error) {
+	exporter, err := otlptracehttp.New(ctx, otlptracehttp.WithEndpointURL(opts.Endpoint))
+	if err != nil {
+		return nil, err
+	}
+	res, err := resource.Merge(resource.Default(),
+		resource.NewWithAttributes(semconv.SchemaURL, semconv.ServiceName(opts.ServiceName)))
+	if err != nil {
+		return nil, err
+	}
+
+	provider := sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(exporter),
+		sdktrace.WithResource(res),
+		sdktrace.WithSampler(sdktrace.ParentBased(sdktrace.TraceIDRatioBased(opts.SampleRatio))),
+	)
+	otel.SetTracerProvider(provider)
+	otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator(propagation.TraceContext{}, propagation.Baggage{}))
+	return provider.Shutdown, nil
+}
+
+// Tracer returns the tracer of the service. Spans are dropped until Setup is called.
+func Tracer() trace.Tracer {
+	return otel.Tracer(instrumentationName)
+}
+
+// TraceParent returns the W3C traceparent of the span in ctx, empty without a sampled span.
+func TraceParent(ctx context.Context) string {
+	carrier := propagation.MapCarrier{}
+	propagation.TraceContext{}.Inject(ctx, carrier)
+	return carrier.Get(traceParentKey)
+}
+
+// ContextWithTraceParent returns a context continuing the trace of a traceparent, which is
+// how a trace survives the time a command spends in the queue.
+func ContextWithTraceParent(ctx context.Context, traceParent string) context.Context {
+	if traceParent == "" {
+		return ctx
+	}
+	return propagation.TraceContext{}.Extract(ctx, propagation.MapCarrier{traceParentKey: traceParent})
+}
+
+// Environment returns the TRACEPARENT variable continuing the span in ctx in a script, or
+// nothing when there is no span to continue.
+func Environment(ctx context.Context) []string {
+	if traceParent := TraceParent(ctx); traceParent != "" {
+		return []string{"TRACEPARENT=" + traceParent}
+	}
+	return nil
+}
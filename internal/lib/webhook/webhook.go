@@ -0,0 +1,52 @@
+package webhook
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Headers of a webhook delivery.
+const (
+	EventHeader     = "X-BashAPI-Event"
+	DeliveryHeader  = "X-BashAPI-Delivery"
+	TimestampHeader = "X-BashAPI-Timestamp"
+	SignatureHeader = "X-BashAPI-Signature"
+)
+
+const signaturePrefix = "sha256="
+
+// Sign returns the signature of a delivery body sent at timestamp, the Unix time in the
+// timestamp header. The HMAC-SHA256 covers "<timestamp>.<body>" so that a captured delivery
+// cannot be replayed later with another timestamp.
+func Sign(secret []byte, timestamp int64, body []byte) string {
+	mac := hmac.New(sha256.New, secret)
+	mac.Write([]byte(strconv.FormatInt(timestamp, 10)))
+	mac.Write([]byte("."))
+	mac.Write(body)
+	return signaturePrefix + hex.EncodeToString(mac.Sum(nil))
+}
+
+// Verify checks the signature of a delivery body in constant time.
+func Verify(secret []byte, timestamp int64, body []byte, signature string) bool {
+	if !strings.HasPrefix(signature, signaturePrefix) {
+		return false
+	}
+	return hmac.Equal([]byte(Sign(secret, timestamp, body)), []byte(signature))
+}
+
+// Backoff returns the delay before retrying after the given failed attempt, starting at base
+// and doubling with every attempt up to max.
+func Backoff(attempt int, base, max time.Duration) time.Duration {
+	delay := base
+	for i := 1; i < attempt && delay < max; i++ {
+		delay *= 2
+	}
+	if delay > max {
+		return max
+	}
+	return delay
+}
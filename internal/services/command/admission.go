@@ -0,0 +1,69 @@
+package services
+
+import (
+	"fmt"
+	"github.com/17HIERARCH70/BashAPI/internal/domain/models"
+	"github.com/17HIERARCH70/BashAPI/internal/lib/hostload"
+	"time"
+)
+
+// FetchAdmission reads the host load and decides whether queued commands may start.
+// When the metrics cannot be read the dispatcher fails open, so a broken procfs
+// never stalls the queue.
+func (s *CommandService) FetchAdmission() models.Admission {
+	cfg := s.Config.Commands.Admission
+	admission := models.Admission{
+		Enabled: cfg.Enabled,
+		Admit:   true,
+		Reasons: []string{},
+		Thresholds: models.AdmissionThresholds{
+			MaxLoadPerCPU:        cfg.MaxLoadPerCPU,
+			MinAvailableMemoryMB: cfg.MinAvailableMemoryMB,
+			MaxCPUPressure:       cfg.MaxCPUPressure,
+		},
+		CheckedAt: time.Now(),
+	}
+
+	procPath := cfg.ProcPath
+	if procPath == "" {
+		procPath = "/proc"
+	}
+	m, err := hostload.Read(procPath)
+	if err != nil {
+		admission.Error = err.Error()
+		if cfg.Enabled {
+			s.Logger.Warn("Failed to read host load, admitting commands", "error", err)
+		}
+		return admission
+	}
+
+	metrics := &models.HostMetrics{
+		CPUs:              m.CPUs,
+		Load1:             m.Load1,
+		Load5:             m.Load5,
+		Load15:            m.Load15,
+		LoadPerCPU:        m.Load1 / float64(max(m.CPUs, 1)),
+		MemTotalBytes:     m.MemTotal,
+		MemAvailableBytes: m.MemAvailable,
+		CPUPressure:       m.CPUPressure,
+	}
+	admission.Metrics = metrics
+	if !cfg.Enabled {
+		return admission
+	}
+
+	if cfg.MaxLoadPerCPU > 0 && metrics.LoadPerCPU > cfg.MaxLoadPerCPU {
+		admission.Reasons = append(admission.Reasons,
+			fmt.Sprintf("load per CPU %.2f exceeds %.2f", metrics.LoadPerCPU, cfg.MaxLoadPerCPU))
+	}
+	if minAvailable := uint64(cfg.MinAvailableMemoryMB) << 20; minAvailable > 0 && metrics.MemAvailableBytes < minAvailable {
+		admission.Reasons = append(admission.Reasons,
+			fmt.Sprintf("available memory %d MB is below %d MB", metrics.MemAvailableBytes>>20, cfg.MinAvailableMemoryMB))
+	}
+	if cfg.MaxCPUPressure > 0 && metrics.CPUPressure != nil && *metrics.CPUPressure > cfg.MaxCPUPressure {
+		admission.Reasons = append(admission.Reasons,
+			fmt.Sprintf("CPU pressure %.2f%% exceeds %.2f%%", *metrics.CPUPressure, cfg.MaxCPUPressure))
+	}
+	admission.Admit = len(admission.Reasons) == 0
+	return admission
+}
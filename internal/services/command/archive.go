@@ -0,0 +1,138 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"time"
+)
+
+var ErrArchiveUnavailable = errors.New("output archive is not configured")
+
+// archiveReadSize is the size of the pieces an archived output is read in.
+const archiveReadSize = 64 * 1024
+
+// StartArchiver periodically moves outputs of old finished commands to the archive
+// store until ctx is cancelled.
+func (s *CommandService) StartArchiver(ctx context.Context) {
+	interval := time.Duration(s.Config.Commands.Archive.Interval) * time.Second
+	if interval <= 0 {
+		interval = time.Hour
+	}
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			if archived, err := s.archiveOutputs(); err != nil {
+				s.Logger.Error("Failed to archive command outputs", "error", err)
+			} else if archived > 0 {
+				s.Logger.Info("Command outputs archived", "count", archived)
+			}
+			select {
+			case <-ctx.Done():
+				s.Logger.Info("Archiver stopped")
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+}
+
+// archiveOutputs archives one batch of outputs of commands finished more than after_days ago.
+func (s *CommandService) archiveOutputs() (int, error) {
+	if s.Archive == nil {
+		return 0, ErrArchiveUnavailable
+	}
+	cfg := s.Config.Commands.Archive
+	cutoff := time.Now().AddDate(0, 0, -cfg.AfterDays)
+	batch := cfg.BatchSize
+	if batch <= 0 {
+		batch = 100
+	}
+
+	outputs, err := s.Store.FindArchivable(context.Background(), cutoff, batch)
+	if err != nil {
+		return 0, err
+	}
+
+	archived := 0
+	for _, o := range outputs {
+		if err := s.archiveOutput(o.ID, o.FinishedAt, outputLayout(o.Layout)); err != nil {
+			s.Logger.Error("Failed to archive command output", "commandID", o.ID, "error", err)
+			continue
+		}
+		archived++
+	}
+	return archived, nil
+}
+
+// archiveOutput writes the kept output of a command to the archive store, then records the
+// reference and deletes the chunks in one step.
+func (s *CommandService) archiveOutput(id int, finishedAt time.Time, layout outputLayout) error {
+	reader, writer := io.Pipe()
+	go func() {
+		writer.CloseWithError(s.scanKept(id, layout, 0, layout.length(), false, func(data []byte) bool {
+			_, err := writer.Write(data)
+			return err == nil
+		}))
+	}()
+	ref, err := s.Archive.Put(fmt.Sprintf("%s/command-%d.out", finishedAt.UTC().Format("2006-01"), id), reader)
+	reader.Close()
+	if err != nil {
+		return err
+	}
+
+	recorded, err := s.Store.SetOutputArchive(context.Background(), id, ref)
+	if err != nil || !recorded {
+		// The output archived in the meantime keeps its own archive
+		s.deleteArchive(ref)
+	}
+	return err
+}
+
+func (s *CommandService) deleteArchive(ref string) {
+	if err := s.Archive.Delete(ref); err != nil {
+		s.Logger.Error("Failed to delete archived output", "ref", ref, "error", err)
+	}
+}
+
+// scanArchive passes the archived output in [from, to) to fn like scanOutput. Archives can
+// only be read from their start, so a backward scan reads the whole range at once.
+func (s *CommandService) scanArchive(ref string, from, to int64, backwards bool, fn func(data []byte) bool) error {
+	if s.Archive == nil {
+		return ErrArchiveUnavailable
+	}
+	archived, err := s.Archive.Open(ref)
+	if err != nil {
+		return err
+	}
+	defer archived.Close()
+
+	if _, err := io.CopyN(io.Discard, archived, from); err != nil {
+		return err
+	}
+	data := io.LimitReader(archived, to-from)
+	if backwards {
+		content, err := io.ReadAll(data)
+		if err != nil {
+			return err
+		}
+		fn(content)
+		return nil
+	}
+
+	buf := make([]byte, archiveReadSize)
+	for {
+		n, err := data.Read(buf)
+		if n > 0 && !fn(append([]byte(nil), buf[:n]...)) {
+			return nil
+		}
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+	}
+}
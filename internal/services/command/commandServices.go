@@ -1,449 +1,472 @@
 package services
 
 import (
-	"bytes"
 	context2 "context"
 	"errors"
 	"fmt"
 	"github.com/17HIERARCH70/BashAPI/internal/config"
 	"github.com/17HIERARCH70/BashAPI/internal/domain/models"
+	"github.com/17HIERARCH70/BashAPI/internal/lib/archive"
+	"github.com/17HIERARCH70/BashAPI/internal/lib/tracing"
+	"github.com/17HIERARCH70/BashAPI/internal/storage"
 	"github.com/gin-gonic/gin"
-	"github.com/jackc/pgx/v4"
-	"github.com/jackc/pgx/v4/pgxpool"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
 	"golang.org/x/net/context"
 	"log/slog"
 	"os"
 	"os/exec"
+	"sync"
+	"sync/atomic"
 	"syscall"
 	"time"
 )
 
 type ICommandService interface {
-	ProcessCommand(script string) (gin.H, error)
-	FetchCommands() ([]models.Command, error)
+	ProcessCommand(script string, opts models.CommandOptions) (gin.H, error)
+	FetchCommands(query models.CommandQuery) (models.CommandPage, error)
+	SearchCommands(query models.SearchQuery) (models.SearchPage, error)
 	FetchCommandByID(id int) (models.Command, error)
+	WaitForCommand(ctx context2.Context, id int, timeout time.Duration) (models.Command, bool, error)
 	StopCommand(id int) error
+	StopCommands(filter models.SelectorFilter) ([]int, error)
+	UpdateLabels(id int, update models.LabelUpdate) (models.CommandLabels, error)
 	FetchQueueList() ([]models.Queue, error)
 	ForceStartCommand(id int) (gin.H, error)
+	FetchCommandEvents(id int) ([]models.CommandEvent, error)
 	StopAllRunningCommands() error
+	FetchCapacity() (models.Capacity, error)
+	FetchAdmission() models.Admission
+	PauseQueue(name, reason string) error
+	ResumeQueue(name string) error
+	FetchQueuePauses() ([]models.QueuePause, error)
+	CancelCommand(id int) error
+	MoveQueuedCommand(id, position int) error
+	CancelQueuedCommands(filter models.QueueFilter) ([]int, error)
+	DeleteCommand(id int) error
+	PurgeCommands(filter models.PurgeFilter) (models.PurgeResult, error)
+	FetchCommandOutput(id int, query models.OutputQuery) (models.CommandOutput, error)
+	CreateWebhook(hook models.Webhook) (models.Webhook, error)
+	FetchWebhooks() ([]models.Webhook, error)
+	DeleteWebhook(id int) error
+	FetchDeliveries(query models.DeliveryQuery) ([]models.WebhookDelivery, error)
+	SubscribeEvents(filter models.EventFilter) (<-chan models.LifecycleEvent, func())
+	ReplayEvents(filter models.EventFilter) ([]models.LifecycleEvent, error)
+	CheckReadiness(ctx context2.Context) []models.HealthCheck
 }
 
 var _ ICommandService = &CommandService{}
 
 type CommandService struct {
-	DB     *pgxpool.Pool
+	// Store keeps the commands, their queue, their events and the deliveries of their events.
+	Store  storage.CommandStore
 	Logger *slog.Logger
 	Config *config.Config
+	// Archive keeps archived outputs, nil when no archive is configured.
+	Archive archive.Store
+
+	dispatchMu sync.Mutex
+	wake       chan struct{}
+	// dispatcherBeat is the time of the last pass of the dispatcher loop in Unix nanoseconds,
+	// 0 while the loop is not running.
+	dispatcherBeat atomic.Int64
+	// executing counts the commands executed by this process.
+	executing atomic.Int64
+	// shuttingDown refuses new commands once the running ones are being shut down.
+	shuttingDown atomic.Bool
+
+	watchMu  sync.Mutex
+	watchers map[int][]chan struct{}
+
+	streamMu    sync.Mutex
+	subscribers map[*eventSubscription]struct{}
 }
 
-func NewCommandService(db *pgxpool.Pool, logger *slog.Logger, config *config.Config) *CommandService {
+// NewCommandService creates a service keeping commands in the store.
+func NewCommandService(store storage.CommandStore, logger *slog.Logger, config *config.Config) *CommandService {
 	return &CommandService{
-		DB:     db,
+		Store:  store,
 		Logger: logger,
 		Config: config,
+		wake:   make(chan struct{}, 1),
 	}
 }
 
-var ErrNotFound = errors.New("command not found")
+var (
+	ErrNotFound              = storage.ErrNotFound
+	ErrWeightExceedsCapacity = errors.New("command weight exceeds the total capacity")
+	ErrQueueNotPaused        = errors.New("queue is not paused")
+	ErrNotQueued             = storage.ErrNotQueued
+	ErrOutputLimitTooLarge   = errors.New("max_output exceeds the configured output size limit")
+	ErrInvalidCursor         = storage.ErrInvalidCursor
+	ErrShuttingDown          = errors.New("server is shutting down")
+)
 
-// ProcessCommand manages the creation and execution of a sudo command.
-func (s *CommandService) ProcessCommand(script string) (gin.H, error) {
-	if s.manageQueue() {
-		id, err := s.createCommandQueueRecord(script)
-		if err != nil {
-			return nil, err
-		}
-		return gin.H{"message": "Command is being queued", "id": id}, nil
-	} else {
-		id, err := s.createCommandRecord(script, "running")
-		if err != nil {
-			return nil, err
-		}
-		go s.executeCommand(id, script)
-		return gin.H{"message": "Command is being executed", "id": id}, nil
+// outputWaitDelay is how long the output of an exited or killed command is still read while
+// its background children keep the pipes open.
+const outputWaitDelay = 5 * time.Second
+
+// ProcessCommand manages the creation and execution of a command.
+// Requests carrying an idempotency key are deduplicated before anything is created.
+func (s *CommandService) ProcessCommand(script string, opts models.CommandOptions) (gin.H, error) {
+	if s.shuttingDown.Load() {
+		return nil, ErrShuttingDown
 	}
+	// Retries are recognised by the request as sent, the defaults may change in between
+	requested := opts
+	if opts.Weight == 0 {
+		opts.Weight = 1
+	}
+	if opts.Weight > s.capacity() {
+		return nil, ErrWeightExceedsCapacity
+	}
+	if opts.Queue == "" {
+		opts.Queue = models.DefaultQueue
+	}
+	if opts.TTL == 0 {
+		opts.TTL = s.Config.Commands.QueueTTL
+	}
+	if err := s.normalizeOutputLimit(&opts); err != nil {
+		return nil, err
+	}
+
+	if opts.IdempotencyKey != "" {
+		return s.processIdempotentCommand(script, requested, opts)
+	}
+	return s.processCommand(script, opts)
 }
 
-// FetchCommands retrieves a list of all commands.
-func (s *CommandService) FetchCommands() ([]models.Command, error) {
-	var commands []models.Command
-	rows, err := s.DB.Query(context.Background(), "SELECT id, script, status, pid, output, created_at, updated_at FROM commands.commands ORDER BY id")
+// processCommand queues the command and lets the dispatcher start it right away when it fits.
+func (s *CommandService) processCommand(script string, opts models.CommandOptions) (gin.H, error) {
+	s.dispatchMu.Lock()
+	defer s.dispatchMu.Unlock()
+
+	id, err := s.createCommandQueueRecord(script, opts)
 	if err != nil {
 		return nil, err
 	}
-	defer rows.Close()
+	if started := s.dispatchQueueLocked(); started[id] {
+		return gin.H{"message": "Command is being executed", "id": id}, nil
+	}
 
-	for rows.Next() {
-		var cmd models.Command
-		if err := rows.Scan(&cmd.ID, &cmd.Script, &cmd.Status, &cmd.PID, &cmd.Output, &cmd.CreatedAt, &cmd.UpdatedAt); err != nil {
-			s.Logger.Error("Error scanning command", "error", err)
-			continue
-		}
-		commands = append(commands, cmd)
+	response := gin.H{"message": "Command is being queued", "id": id}
+	pause, err := s.Store.FindQueuePause(context.Background(), opts.Queue)
+	if err != nil {
+		s.Logger.Error("Failed to check queue pause", "queue", opts.Queue, "error", err)
 	}
-	if err = rows.Err(); err != nil {
-		return nil, err
+	if pause != nil {
+		response["message"] = "Command is queued behind a paused queue"
+		response["queue"] = opts.Queue
+		response["paused"] = true
+		response["reason"] = pause.Reason
 	}
-	return commands, nil
-}
-
-// FetchCommandByID retrieves a command by its ID.
-func (s *CommandService) FetchCommandByID(id int) (models.Command, error) {
-	var command models.Command
-	err := s.DB.QueryRow(context.Background(),
-		"SELECT id, script, status, pid, output, created_at, updated_at FROM commands.commands WHERE id = $1",
-		id).Scan(&command.ID, &command.Script, &command.Status, &command.PID, &command.Output, &command.CreatedAt, &command.UpdatedAt)
 
+	queue, err := s.FetchQueueList()
 	if err != nil {
-		if errors.Is(err, pgx.ErrNoRows) {
-			return models.Command{}, ErrNotFound
+		s.Logger.Error("Failed to estimate queued command start", "commandID", id, "error", err)
+		return response, nil
+	}
+	for _, item := range queue {
+		if item.CommandId == id {
+			response["position"] = item.Position
+			response["ahead"] = item.Ahead
+			response["estimated_start_at"] = item.EstimatedStartAt
+			break
 		}
-		return models.Command{}, err
 	}
-	return command, nil
+	return response, nil
 }
 
-// StopCommand stops a command by its ID.
-func (s *CommandService) StopCommand(id int) error {
-	var pid *int // Use *int to properly handle NULL values
-	err := s.DB.QueryRow(context.Background(), "SELECT pid FROM commands.commands WHERE id = $1", id).Scan(&pid)
+// FetchCommands retrieves one page of the command list matching the query.
+func (s *CommandService) FetchCommands(query models.CommandQuery) (models.CommandPage, error) {
+	commands, layouts, next, err := s.Store.FetchCommands(context.Background(), &query)
 	if err != nil {
-		if errors.Is(err, pgx.ErrNoRows) {
-			return ErrNotFound // No command with the given ID was found
-		}
-		return err // Handle other errors (e.g., SQL errors)
+		return models.CommandPage{}, err
 	}
-
-	if pid == nil {
-		return errors.New("no PID found for the command; it may not have been started or already stopped")
+	page := models.CommandPage{Items: commands, NextCursor: next}
+	for i := range page.Items {
+		layout := outputLayout(layouts[i])
+		if !query.WithOutput {
+			layout.describe(&page.Items[i])
+			continue
+		}
+		if err := s.setOutput(&page.Items[i], layout); err != nil {
+			return models.CommandPage{}, err
+		}
 	}
+	return page, nil
+}
 
-	process, err := os.FindProcess(*pid)
+// FetchCommandByID retrieves a command by its ID.
+func (s *CommandService) FetchCommandByID(id int) (models.Command, error) {
+	command, layout, err := s.Store.FetchCommand(context.Background(), id)
 	if err != nil {
-		return err // Failed to find the process, handle error
+		return models.Command{}, err
 	}
+	if err := s.setOutput(&command, outputLayout(layout)); err != nil {
+		return models.Command{}, err
+	}
+	return command, nil
+}
 
-	if err := process.Signal(syscall.SIGINT); err != nil {
-		return err // Error sending the interrupt signal
+// setOutput fills the output fields of a command from its stored chunks.
+func (s *CommandService) setOutput(command *models.Command, layout outputLayout) error {
+	output, err := s.inlineOutput(command.ID, layout)
+	if err != nil {
+		return err
 	}
+	command.Output = output
+	layout.describe(command)
+	return nil
+}
 
-	return s.updateCommandStatusManually(id, "stopped")
+// StopCommand stops a command by its ID. A command still waiting in the queue is cancelled instead.
+func (s *CommandService) StopCommand(id int) error {
+	return s.stopCommand(id, actorAPI, "stopped by request")
 }
 
-// FetchQueueList retrieves all queue items ordered by QueueId.
-func (s *CommandService) FetchQueueList() ([]models.Queue, error) {
-	var queue []models.Queue
-	rows, err := s.DB.Query(context.Background(), "SELECT queue_id, command_id, status FROM commands.queue ORDER BY queue_id")
+// stopCommand interrupts a running command, recording the actor and the reason in its event history.
+func (s *CommandService) stopCommand(id int, actor, reason string) error {
+	command, _, err := s.Store.FetchCommand(context.Background(), id)
 	if err != nil {
-		return nil, err
+		return err
 	}
-	defer rows.Close()
+	pid, status := command.PID, command.Status
 
-	for rows.Next() {
-		var q models.Queue
-		if err := rows.Scan(&q.QueueId, &q.CommandId, &q.Status); err != nil {
-			continue // Optionally handle partial data or halt processing
-		}
-		queue = append(queue, q)
+	if status == models.StatusWaiting {
+		return s.CancelCommand(id)
 	}
-	if err = rows.Err(); err != nil {
-		return nil, err
+	if status != models.StatusRunning {
+		// The PID of a finished command may already belong to another process
+		return fmt.Errorf("%w: %s -> %s", ErrInvalidTransition, status, models.StatusStopped)
 	}
-	return queue, nil
-}
 
-// ForceStartCommand forcefully starts a command by its ID, ignoring queue constraints.
-func (s *CommandService) ForceStartCommand(id int) (gin.H, error) {
-	var script, currentStatus string
-	err := s.DB.QueryRow(context.Background(), "SELECT script, status FROM commands.commands WHERE id = $1", id).Scan(&script, &currentStatus)
-	if err != nil {
-		if errors.Is(err, pgx.ErrNoRows) {
-			return nil, ErrNotFound
-		}
-		return nil, err
+	if pid == nil {
+		return errors.New("no PID found for the command; it may not have been started or already stopped")
 	}
 
-	if currentStatus == "running" || currentStatus == "completed" {
-		return gin.H{"error": "Command is already " + currentStatus}, nil
+	// Record the stop first, so that the exit caused by the signal is not taken for an error
+	if err := s.changeStatus(id, statusChange(models.StatusStopped, actor, reason)); err != nil {
+		return err
 	}
 
-	// Обновляем статус команды на 'running' и удаляем из очереди
-	tx, err := s.DB.Begin(context.Background())
-	if err != nil {
-		s.Logger.Error("Failed to begin transaction", "error", err)
-		return nil, err
+	process, err := os.FindProcess(*pid)
+	if err == nil {
+		err = process.Signal(syscall.SIGINT)
 	}
-
-	_, err = tx.Exec(context.Background(), "UPDATE commands.commands SET status = 'running' WHERE id = $1", id)
 	if err != nil {
-		_ = tx.Rollback(context.Background())
-		s.Logger.Error("Failed to update command status", "error", err)
-		return nil, err
+		s.Logger.Warn("Failed to interrupt stopped command, it has probably exited", "commandID", id, "error", err)
 	}
+	s.notifyDispatcher()
+	return nil
+}
 
-	_, err = tx.Exec(context.Background(), "DELETE FROM commands.queue WHERE command_id = $1", id)
+// FetchQueueList retrieves all queue items in dispatch order with their position
+// and estimated start time, along with whether their named queue is paused and the
+// running command holding a conflicting lock, if any.
+func (s *CommandService) FetchQueueList() ([]models.Queue, error) {
+	queue, err := s.Store.FetchQueue(context.Background())
 	if err != nil {
-		_ = tx.Rollback(context.Background())
-		s.Logger.Error("Failed to delete command from queue", "commandID", id, "error", err)
 		return nil, err
 	}
+	if err := s.estimateQueue(queue); err != nil {
+		s.Logger.Error("Failed to estimate queue start times", "error", err)
+	}
+	return queue, nil
+}
 
-	err = tx.Commit(context.Background())
+// ForceStartCommand forcefully starts a command by its ID, ignoring queue constraints.
+// Commands that finished without completing are started again.
+func (s *CommandService) ForceStartCommand(id int) (gin.H, error) {
+	if s.shuttingDown.Load() {
+		return nil, ErrShuttingDown
+	}
+	execution, err := s.Store.StartCommand(context.Background(), id, statusChange(models.StatusRunning, actorAPI, "forced start"))
 	if err != nil {
-		s.Logger.Error("Failed to commit transaction", "error", err)
 		return nil, err
 	}
+	s.statusChanged(id, models.StatusRunning, execution.Transition)
 
-	go s.executeCommand(id, script)
+	go s.executeCommand(id, execution.Script, execution.TraceContext)
 	return gin.H{"message": "Command is being forcibly started", "id": id}, nil
 }
 
 // StopAllRunningCommands to stop all running commands
 func (s *CommandService) StopAllRunningCommands() error {
-	var commandIDs []int
-	rows, err := s.DB.Query(context.Background(), "SELECT id FROM commands.commands WHERE status = 'running'")
-	if err != nil {
-		return err
-	}
-	defer rows.Close()
-
-	for rows.Next() {
-		var id int
-		if err := rows.Scan(&id); err != nil {
-			s.Logger.Error("Error scanning command", "error", err)
-			continue
-		}
-		commandIDs = append(commandIDs, id)
-	}
-
-	for _, id := range commandIDs {
-		if err := s.StopCommand(id); err != nil {
-			s.Logger.Error("Failed to stop command", "commandID", id, "error", err)
-		}
-	}
-
-	return nil
+	return s.stopRunningCommands("server shutdown")
 }
 
-// manageQueue checking if count bigger than maxConcurrent, returning bool
-func (s *CommandService) manageQueue() bool {
-	count, err := s.getRunningCommandsCount()
+// getUsedCapacity gets the total weight of running commands.
+func (s *CommandService) getUsedCapacity() (int, error) {
+	stats, err := s.Store.CommandStats(context.Background())
 	if err != nil {
-		s.Logger.Error("Error getting running commands count", "error", err)
-		return true
-	}
-	return count >= s.Config.Commands.MaxConcurrent
-}
-
-// getRunningCommandsCount gets the count of running commands.
-func (s *CommandService) getRunningCommandsCount() (int, error) {
-	var count int
-	err := s.DB.QueryRow(context.Background(), "SELECT COUNT(*) FROM commands.commands WHERE status = 'running'").Scan(&count)
-	if err != nil {
-		s.Logger.Error("Failed to get running commands count", "error", err)
+		s.Logger.Error("Failed to get used capacity", "error", err)
 		return 0, err
 	}
-	return count, nil
+	return stats.UsedSlots, nil
 }
 
-// createCommandQueueRecord creates a new record in the queue table for the given script.
-func (s *CommandService) createCommandQueueRecord(script string) (int, error) {
-	// Start a transaction
-	tx, err := s.DB.Begin(context.Background())
-	if err != nil {
-		s.Logger.Error("Failed to start transaction", "error", err)
-		return 0, err
-	}
-	defer func(tx pgx.Tx, ctx context2.Context) {
-		_ = tx.Rollback(ctx)
-	}(tx, context.Background())
-
-	// Create the command record and get the ID
-	var commandID int
-	err = tx.QueryRow(context.Background(),
-		"INSERT INTO commands.commands (script, status) VALUES ($1, 'waiting') RETURNING id",
-		script).Scan(&commandID)
-	if err != nil {
-		s.Logger.Error("Failed to create command record", "error", err)
-		return 0, err
+// capacity returns the number of slots shared by running commands.
+// Without an explicit capacity every command is worth one of MaxConcurrent slots.
+func (s *CommandService) capacity() int {
+	if s.Config.Commands.Capacity > 0 {
+		return s.Config.Commands.Capacity
 	}
+	return s.Config.Commands.MaxConcurrent
+}
 
-	// Insert into queue table
-	_, err = tx.Exec(context.Background(), "INSERT INTO commands.queue (command_id, status) VALUES ($1, 'waiting')", commandID)
+// FetchCapacity reports how many concurrency slots are used and still available.
+func (s *CommandService) FetchCapacity() (models.Capacity, error) {
+	used, err := s.getUsedCapacity()
 	if err != nil {
-		s.Logger.Error("Failed to enqueue command", "error", err)
-		return 0, err
+		return models.Capacity{}, err
 	}
-
-	// Commit the transaction
-	if err := tx.Commit(context.Background()); err != nil {
-		s.Logger.Error("Failed to commit transaction", "error", err)
-		return 0, err
-	}
-
-	// Launch the execution manager asynchronously
-	go s.manageExecution(commandID)
-	return commandID, nil
+	total := s.capacity()
+	return models.Capacity{Total: total, Used: used, Available: max(total-used, 0)}, nil
 }
 
-// manageExecution checks every 10 sec and manages the queue.
-func (s *CommandService) manageExecution(commandID int) {
-	for {
-		time.Sleep(10 * time.Second) // Check every 10 seconds
-
-		status := s.manageQueue()
-		if status == false {
-			// Remove command from the queue
-			_, err := s.DB.Exec(context.Background(), "DELETE FROM commands.queue WHERE command_id = $1", commandID)
-			if err != nil {
-				s.Logger.Error("Failed to delete command from queue", "commandID", commandID, "error", err)
-				continue
-			}
-
-			// Update the command status to running
-			_, err = s.DB.Exec(context.Background(), "UPDATE commands.commands SET status = 'running' WHERE id = $1", commandID)
-			if err != nil {
-				s.Logger.Error("Failed to update command status to running", "commandID", commandID, "error", err)
-				continue
-			}
-
-			// Retrieve the script for the command
-			var script string
-			err = s.DB.QueryRow(context.Background(), "SELECT script FROM commands.commands WHERE id = $1", commandID).Scan(&script)
-			if err != nil {
-				s.Logger.Error("Failed to retrieve script for execution", "commandID", commandID, "error", err)
-				continue
-			}
-
-			// Execute the command
-			go s.executeCommand(commandID, script)
-			break
-		}
-	}
-}
+// createCommandQueueRecord stores a new waiting command for the given script, together
+// with the locks the command will hold while running, and appends it to the queue.
+func (s *CommandService) createCommandQueueRecord(script string, opts models.CommandOptions) (int, error) {
+	// Continue the trace of the request, the command stores the context of this span
+	ctx, span := startSpan(opts.TraceParent, "createCommandQueueRecord")
+	var err error
+	defer func() {
+		endSpan(span, err)
+	}()
 
-// createCommandRecordWithStatus inserts a command record with the given script and status.
-func (s *CommandService) createCommandRecordWithStatus(script string, status string) (int, error) {
+	var expiresAt *time.Time
+	if ttl := opts.TTL; ttl > 0 {
+		deadline := time.Now().Add(time.Duration(ttl) * time.Second)
+		expiresAt = &deadline
+	}
 	var commandID int
-	err := s.DB.QueryRow(context.Background(),
-		"INSERT INTO commands.commands (script, status) VALUES ($1, $2) RETURNING id",
-		script, status).Scan(&commandID)
-
+	commandID, err = s.Store.CreateCommand(ctx, storage.NewCommand{
+		Script:       script,
+		Options:      opts,
+		TraceContext: tracing.TraceParent(ctx),
+		ExpiresAt:    expiresAt,
+	})
 	if err != nil {
-		s.Logger.Error("Failed to create command record", "error", err)
+		s.Logger.Error("Failed to create command", "error", err)
 		return 0, err
 	}
+	span.SetAttributes(attribute.Int("command.id", commandID), attribute.String("command.queue", opts.Queue))
 	return commandID, nil
 }
 
-// executeCommand main func to execute bash scripts
-func (s *CommandService) executeCommand(commandID int, script string) {
-	done := make(chan struct{})
-	finished := make(chan struct{})
-	errChan := make(chan error, 1) // Channel to capture errors from cmd.Wait()
+// executeCommand main func to execute bash scripts. The run is traced as part of the trace
+// of the command, which the script may continue through the TRACEPARENT variable.
+func (s *CommandService) executeCommand(commandID int, script, traceContext string) {
+	s.executing.Add(1)
+	defer s.executing.Add(-1)
+	ctx, span := startSpan(traceContext, "executeCommand", trace.WithAttributes(attribute.Int("command.id", commandID)))
+	var runErr error
+	defer func() {
+		endSpan(span, runErr)
+	}()
 
 	cmd := exec.Command("bash", "-c", script)
-	var output bytes.Buffer
-	cmd.Stdout = &output
-	cmd.Stderr = &output
+	// Background children keeping the output pipes open must not hold up a killed command
+	cmd.WaitDelay = outputWaitDelay
+	if env := tracing.Environment(ctx); env != nil {
+		cmd.Env = append(os.Environ(), env...)
+	}
+	output := s.newOutputWriter(commandID, func() {
+		if cmd.Process != nil {
+			s.Logger.Info("Command killed after exceeding the output limit", "commandID", commandID)
+			cmd.Process.Kill()
+		}
+	})
+	cmd.Stdout = output
+	cmd.Stderr = output
 
 	// Start command execution
 	if err := cmd.Start(); err != nil {
+		runErr = err
 		s.Logger.Error("Failed to start command", "error", err)
-		s.updateCommandStatus(commandID, "error", output.String())
+		fmt.Fprintln(output, err)
+		output.Close()
+		s.indexOutput(commandID)
+		s.updateCommandStatus(commandID, models.StatusError, err.Error(), nil)
+		s.notifyDispatcher()
 		return
 	}
 
 	pid := cmd.Process.Pid
-	if _, err := s.DB.Exec(context.Background(), "UPDATE commands.commands SET pid = $1 WHERE id = $2", pid, commandID); err != nil {
+	if err := s.Store.SetPID(context.Background(), commandID, pid); err != nil {
 		s.Logger.Error("Failed to save command PID", "error", err)
-		return
 	}
 
+	// Wait runs in its own goroutine to catch the timeout, it is always received from so
+	// that the process state is only read once Wait has returned
+	done := make(chan error, 1)
 	go func() {
-		defer close(done)
-		for {
-			time.Sleep(3 * time.Second)
-			s.updateCommandOutput(commandID, output.String())
-			select {
-			case <-finished:
-				return
-			default:
-			}
-		}
+		done <- cmd.Wait()
 	}()
-
-	timer := time.AfterFunc(time.Duration(s.Config.Commands.Timeout)*time.Second, func() {
+	timer := time.NewTimer(time.Duration(s.Config.Commands.Timeout) * time.Second)
+	defer timer.Stop()
+
+	var err error
+	timedOut := false
+	select {
+	case err = <-done:
+	case <-timer.C:
+		timedOut = true
 		cmd.Process.Kill()
-		s.Logger.Info("Command killed due to timeout", "commandID", commandID, "timeout", s.Config.Commands.Timeout, "output", output.String())
-		s.updateCommandStatus(commandID, "timeout", "Command execution timed out")
-		errChan <- fmt.Errorf("timeout")
-	})
-
-	// Handling cmd.Wait() in a separate goroutine to catch the timeout
-	go func() {
-		errChan <- cmd.Wait()
-		timer.Stop()
-	}()
-
-	err := <-errChan
-	close(finished)
-
-	if err != nil {
-		if err.Error() == "timeout" {
-			// Specific logging for timeout scenario
-			s.Logger.Error("Command terminated after reaching timeout", "commandID", commandID)
-		} else {
-			s.Logger.Error("Command execution failed", "error", err)
-			s.updateCommandStatus(commandID, "error", output.String())
-		}
-	} else {
-		s.updateCommandStatus(commandID, "completed", output.String())
+		s.Logger.Info("Command killed due to timeout", "commandID", commandID, "timeout", s.Config.Commands.Timeout)
+		err = <-done
 	}
-
-	<-done // Ensure all output updates are finished
-}
-
-// createCommandRecord starting logging in db
-func (s *CommandService) createCommandRecord(script, status string) (int, error) {
-	var commandID int
-	err := s.DB.QueryRow(context.Background(),
-		"INSERT INTO commands.commands (script, status) VALUES ($1, $2) RETURNING id",
-		script, status).Scan(&commandID)
-
-	if err != nil {
-		return 0, err
+	if errors.Is(err, exec.ErrWaitDelay) {
+		// The script itself succeeded, only the output of its background children is cut off
+		err = nil
 	}
-	return commandID, nil
-}
+	runErr = err
+	if code := exitCode(cmd); code != nil {
+		span.SetAttributes(attribute.Int("command.exit_code", *code))
+	}
+	if timedOut {
+		// Wait has returned, so the notice follows the last output of the script
+		fmt.Fprintln(output, "Command execution timed out")
+	}
+	// Ensure all buffered output is stored before the final status is visible
+	output.Close()
+	s.indexOutput(commandID)
 
-// updateCommandOutput updating command output in database
-func (s *CommandService) updateCommandOutput(commandID int, output string) {
-	_, err := s.DB.Exec(context.Background(),
-		"UPDATE commands.commands SET output = $1 WHERE id = $2",
-		output, commandID)
-	if err != nil {
-		s.Logger.Error("Failed to update command output", "error", err)
+	switch {
+	case timedOut:
+		s.Logger.Error("Command terminated after reaching timeout", "commandID", commandID)
+		s.updateCommandStatus(commandID, models.StatusTimeout, fmt.Sprintf("killed after %d seconds", s.Config.Commands.Timeout), nil)
+	case err != nil:
+		s.Logger.Error("Command execution failed", "error", err)
+		s.updateCommandStatus(commandID, models.StatusError, err.Error(), exitCode(cmd))
+	default:
+		s.updateCommandStatus(commandID, models.StatusCompleted, "", exitCode(cmd))
 	}
+
+	s.notifyDispatcher()
 }
 
-// updateCommandStatus updating status code of script in db
-func (s *CommandService) updateCommandStatus(commandID int, status string, output string) {
-	_, err := s.DB.Exec(context.Background(),
-		"UPDATE commands.commands SET status = $1, output = $2 WHERE id = $3",
-		status, output, commandID)
+// updateCommandStatus records the final status of an executed command. A status set in
+// the meantime, e.g. by stopping the command, is kept.
+func (s *CommandService) updateCommandStatus(commandID int, status, reason string, exitCode *int) {
+	change := statusChange(status, actorExecutor, reason)
+	change.ExitCode = exitCode
+	err := s.changeStatus(commandID, change)
+	if errors.Is(err, ErrInvalidTransition) {
+		s.Logger.Info("Command status was already changed", "commandID", commandID, "status", status, "error", err)
+		return
+	}
 	if err != nil {
 		s.Logger.Error("Failed to update command status", "error", err)
 	}
 }
 
-// updateCommandStatusManually manually updating the status of the command in the database
-func (s *CommandService) updateCommandStatusManually(id int, status string) error {
-	_, err := s.DB.Exec(context.Background(),
-		"UPDATE commands.commands SET status = $1 WHERE id = $2",
-		status, id)
-	if err != nil {
-		return err
+// exitCode returns the exit code of a command that has exited, or nil when it was killed by a signal.
+func exitCode(cmd *exec.Cmd) *int {
+	if cmd.ProcessState == nil || cmd.ProcessState.ExitCode() < 0 {
+		return nil
 	}
-	return nil
+	code := cmd.ProcessState.ExitCode()
+	return &code
 }
@@ -0,0 +1,115 @@
+package services
+
+import (
+	"context"
+	"github.com/17HIERARCH70/BashAPI/internal/domain/models"
+	"github.com/17HIERARCH70/BashAPI/internal/storage"
+	"time"
+)
+
+// dispatchInterval is how often the dispatcher re-checks the queue without being woken up.
+const dispatchInterval = 10 * time.Second
+
+// StartDispatcher runs the loop that moves queued commands to execution until ctx is cancelled.
+func (s *CommandService) StartDispatcher(ctx context.Context) {
+	go func() {
+		ticker := time.NewTicker(dispatchInterval)
+		defer ticker.Stop()
+		for {
+			s.dispatchQueue()
+			s.dispatcherBeat.Store(time.Now().UnixNano())
+			select {
+			case <-ctx.Done():
+				s.dispatcherBeat.Store(0)
+				s.Logger.Info("Dispatcher stopped")
+				return
+			case <-ticker.C:
+			case <-s.wake:
+			}
+		}
+	}()
+}
+
+// notifyDispatcher asks the dispatcher for an extra pass, e.g. after a command released its slot.
+func (s *CommandService) notifyDispatcher() {
+	select {
+	case s.wake <- struct{}{}:
+	default:
+	}
+}
+
+// dispatchQueue starts as many queued commands as the limits allow.
+func (s *CommandService) dispatchQueue() map[int]bool {
+	s.dispatchMu.Lock()
+	defer s.dispatchMu.Unlock()
+	return s.dispatchQueueLocked()
+}
+
+// dispatchQueueLocked walks the queue in order and starts every command whose weight
+// fits into the free capacity, whose named queue is not paused and that has no conflicting
+// lock. Commands that do not fit stay queued without holding back the ones behind them.
+// It returns the IDs of the started commands.
+func (s *CommandService) dispatchQueueLocked() map[int]bool {
+	started := make(map[int]bool)
+	s.expireQueuedCommands()
+
+	used, err := s.getUsedCapacity()
+	if err != nil {
+		return started
+	}
+	capacity := s.capacity()
+	if used >= capacity {
+		return started
+	}
+
+	if admission := s.FetchAdmission(); !admission.Admit {
+		s.Logger.Debug("Host is overloaded, holding back queued commands", "reasons", admission.Reasons)
+		return started
+	}
+
+	queue, err := s.Store.FetchQueue(context.Background())
+	if err != nil {
+		s.Logger.Error("Failed to fetch queue for dispatching", "error", err)
+		return started
+	}
+
+	for _, item := range queue {
+		if used >= capacity {
+			break
+		}
+		if item.Paused || used+item.Weight > capacity {
+			continue
+		}
+		// Commands started earlier in this pass may hold conflicting locks by now
+		blockedBy, err := s.Store.FindBlockingCommand(context.Background(), item.CommandId)
+		if err != nil {
+			s.Logger.Error("Failed to check command locks", "commandID", item.CommandId, "error", err)
+			continue
+		}
+		if blockedBy != nil {
+			continue
+		}
+
+		execution, err := s.startQueuedCommand(item.CommandId)
+		if err != nil {
+			s.Logger.Error("Failed to start queued command", "commandID", item.CommandId, "error", err)
+			continue
+		}
+		go s.executeCommand(item.CommandId, execution.Script, execution.TraceContext)
+		started[item.CommandId] = true
+		used += item.Weight
+	}
+	return started
+}
+
+// startQueuedCommand removes the command from the queue and marks it as running, then records
+// the time it waited in its trace.
+func (s *CommandService) startQueuedCommand(commandID int) (storage.Execution, error) {
+	execution, err := s.Store.StartCommand(context.Background(), commandID, statusChange(models.StatusRunning, actorDispatcher, ""))
+	if err != nil {
+		return execution, err
+	}
+	s.statusChanged(commandID, models.StatusRunning, execution.Transition)
+	recordQueueWait(commandID, execution.Queue, execution.TraceContext, execution.CreatedAt)
+	return execution, nil
+}
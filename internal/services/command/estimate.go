@@ -0,0 +1,71 @@
+package services
+
+import (
+	"context"
+	"github.com/17HIERARCH70/BashAPI/internal/domain/models"
+	"github.com/17HIERARCH70/BashAPI/internal/lib/schedule"
+	"time"
+)
+
+// durationEstimator memoizes expected durations per script for one estimation run.
+type durationEstimator struct {
+	s     *CommandService
+	cache map[string]time.Duration
+}
+
+// expected returns the historical average duration of the script. Without any history
+// the command timeout is used, which makes the estimate an upper bound.
+func (e *durationEstimator) expected(script string) time.Duration {
+	if d, ok := e.cache[script]; ok {
+		return d
+	}
+
+	timeout := time.Duration(e.s.Config.Commands.Timeout) * time.Second
+	d := timeout
+	average, ok, err := e.s.Store.AverageDuration(context.Background(), script)
+	if err != nil {
+		e.s.Logger.Error("Failed to estimate command duration", "error", err)
+	} else if ok {
+		d = min(average, timeout)
+	}
+	e.cache[script] = d
+	return d
+}
+
+// estimateQueue fills in the position, the number of commands ahead and the expected
+// start time of every queue item, taking the elapsed time of running commands into account.
+func (s *CommandService) estimateQueue(queue []models.Queue) error {
+	now := time.Now()
+	estimator := &durationEstimator{s: s, cache: make(map[string]time.Duration)}
+
+	commands, err := s.Store.FetchRunningCommands(context.Background())
+	if err != nil {
+		return err
+	}
+	var running []schedule.Running
+	for _, command := range commands {
+		startedAt := command.UpdatedAt
+		if command.StartedAt != nil {
+			startedAt = *command.StartedAt
+		}
+		// A command running longer than expected is assumed to finish any moment now
+		finishesAt := startedAt.Add(estimator.expected(command.Script))
+		if finishesAt.Before(now) {
+			finishesAt = now
+		}
+		running = append(running, schedule.Running{Weight: command.Weight, FinishesAt: finishesAt})
+	}
+
+	pending := make([]schedule.Pending, len(queue))
+	for i, q := range queue {
+		pending[i] = schedule.Pending{Weight: q.Weight, Duration: estimator.expected(q.Script), Held: q.Paused}
+	}
+	starts := schedule.EstimateStarts(now, s.capacity(), running, pending)
+
+	for i := range queue {
+		queue[i].Position = i + 1
+		queue[i].Ahead = i
+		queue[i].EstimatedStartAt = starts[i]
+	}
+	return nil
+}
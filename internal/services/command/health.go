@@ -0,0 +1,82 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/17HIERARCH70/BashAPI/internal/domain/models"
+	"github.com/17HIERARCH70/BashAPI/internal/storage"
+	"time"
+)
+
+// Readiness check names.
+const (
+	checkDatabase   = "database"
+	checkMigrations = "migrations"
+	checkDispatcher = "dispatcher"
+)
+
+// healthCheckTimeout bounds each database check, so that a hanging database fails the
+// probe instead of stalling it.
+const healthCheckTimeout = 2 * time.Second
+
+// dispatcherStaleAfter is how long the dispatcher loop may go without a pass before it is
+// considered stuck. It passes at least every dispatchInterval.
+const dispatcherStaleAfter = 3 * dispatchInterval
+
+// CheckReadiness checks that the database is reachable, that its schema is migrated to the
+// version the code relies on and that the dispatcher loop is alive.
+func (s *CommandService) CheckReadiness(ctx context.Context) []models.HealthCheck {
+	return []models.HealthCheck{
+		s.checkDatabase(ctx),
+		s.checkMigrations(ctx),
+		s.checkDispatcher(),
+	}
+}
+
+func (s *CommandService) checkDatabase(ctx context.Context) models.HealthCheck {
+	ctx, cancel := context.WithTimeout(ctx, healthCheckTimeout)
+	defer cancel()
+	started := time.Now()
+	if err := s.Store.Ping(ctx); err != nil {
+		return failedCheck(checkDatabase, err)
+	}
+	return models.HealthCheck{Name: checkDatabase, Status: models.HealthOK,
+		Detail: fmt.Sprintf("ping took %dms", time.Since(started).Milliseconds())}
+}
+
+// checkMigrations checks the version of the schema of a store versioned by migrations.
+func (s *CommandService) checkMigrations(ctx context.Context) models.HealthCheck {
+	checker, ok := s.Store.(storage.SchemaChecker)
+	if !ok {
+		return models.HealthCheck{Name: checkMigrations, Status: models.HealthOK, Detail: "not used by the memory storage"}
+	}
+	ctx, cancel := context.WithTimeout(ctx, healthCheckTimeout)
+	defer cancel()
+	detail, err := checker.CheckSchema(ctx)
+	check := models.HealthCheck{Name: checkMigrations, Status: models.HealthOK, Detail: detail}
+	if err != nil {
+		check.Status = models.HealthFailing
+		check.Error = err.Error()
+	}
+	return check
+}
+
+func (s *CommandService) checkDispatcher() models.HealthCheck {
+	beat := s.dispatcherBeat.Load()
+	if beat == 0 {
+		return failedCheck(checkDispatcher, errors.New("dispatcher is not running"))
+	}
+	since := time.Since(time.Unix(0, beat))
+	detail := fmt.Sprintf("last pass %s ago", since.Round(time.Millisecond))
+	if since > dispatcherStaleAfter {
+		check := failedCheck(checkDispatcher, errors.New("dispatcher loop is stuck"))
+		check.Detail = detail
+		return check
+	}
+	return models.HealthCheck{Name: checkDispatcher, Status: models.HealthOK, Detail: detail}
+}
+
+func failedCheck(name string, err error) models.HealthCheck {
+	return models.HealthCheck{Name: name, Status: models.HealthFailing, Error: err.Error()}
+}
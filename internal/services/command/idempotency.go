@@ -0,0 +1,105 @@
+package services
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"github.com/17HIERARCH70/BashAPI/internal/domain/models"
+	"github.com/17HIERARCH70/BashAPI/internal/storage"
+	"github.com/gin-gonic/gin"
+	"time"
+)
+
+var (
+	ErrIdempotencyConflict   = storage.ErrIdempotencyConflict
+	ErrIdempotencyInProgress = storage.ErrIdempotencyInProgress
+)
+
+// processIdempotentCommand creates the command with opts only once per idempotency key.
+// A repeated request with an identical body gets the original response back,
+// a repeated request with a different body is rejected with ErrIdempotencyConflict.
+// Requests are compared by the options requested, before the defaults were applied.
+func (s *CommandService) processIdempotentCommand(script string, requested, opts models.CommandOptions) (gin.H, error) {
+	key := opts.IdempotencyKey
+	hash, err := requestHash(script, requested)
+	if err != nil {
+		return nil, err
+	}
+
+	response, err := s.reserveIdempotencyKey(key, hash)
+	if err != nil {
+		return nil, err
+	}
+	if response != nil {
+		s.Logger.Info("Replaying response for idempotency key", "key", key)
+		return response, nil
+	}
+
+	response, err = s.processCommand(script, opts)
+	if err != nil {
+		s.releaseIdempotencyKey(key)
+		return nil, err
+	}
+	s.completeIdempotencyKey(key, response)
+	return response, nil
+}
+
+// requestHash fingerprints the request body so retries can be told apart from key reuse.
+func requestHash(script string, opts models.CommandOptions) (string, error) {
+	body, err := json.Marshal(struct {
+		Script  string                `json:"script"`
+		Options models.CommandOptions `json:"options"`
+	}{script, opts})
+	if err != nil {
+		return "", err
+	}
+	sum := sha256.Sum256(body)
+	return hex.EncodeToString(sum[:]), nil
+}
+
+// reserveIdempotencyKey claims the key for this request. It returns the stored
+// response when the key was already used for an identical, finished request.
+func (s *CommandService) reserveIdempotencyKey(key, hash string) (gin.H, error) {
+	// Keys outside the retention window are forgotten
+	expiredBefore := time.Now().Add(-time.Duration(s.Config.Commands.IdempotencyTTL) * time.Second)
+	stored, err := s.Store.ReserveIdempotencyKey(context.Background(), key, hash, expiredBefore)
+	if err != nil {
+		if !errors.Is(err, ErrIdempotencyConflict) && !errors.Is(err, ErrIdempotencyInProgress) {
+			s.Logger.Error("Failed to reserve idempotency key", "error", err)
+		}
+		return nil, err
+	}
+	if stored == nil {
+		return nil, nil
+	}
+
+	var response gin.H
+	if err := json.Unmarshal(stored, &response); err != nil {
+		return nil, err
+	}
+	return response, nil
+}
+
+// completeIdempotencyKey stores the response so that retries can be answered with it.
+func (s *CommandService) completeIdempotencyKey(key string, response gin.H) {
+	stored, err := json.Marshal(response)
+	if err != nil {
+		s.Logger.Error("Failed to encode idempotent response", "key", key, "error", err)
+		s.releaseIdempotencyKey(key)
+		return
+	}
+
+	commandID, _ := response["id"].(int)
+	if err := s.Store.CompleteIdempotencyKey(context.Background(), key, commandID, stored); err != nil {
+		s.Logger.Error("Failed to store idempotent response", "key", key, "error", err)
+	}
+}
+
+// releaseIdempotencyKey frees the key after a failed request so that it can be retried.
+func (s *CommandService) releaseIdempotencyKey(key string) {
+	if err := s.Store.ReleaseIdempotencyKey(context.Background(), key); err != nil {
+		s.Logger.Error("Failed to release idempotency key", "key", key, "error", err)
+	}
+}
@@ -0,0 +1,62 @@
+package services
+
+import (
+	"context"
+	"github.com/17HIERARCH70/BashAPI/internal/domain/models"
+	"github.com/17HIERARCH70/BashAPI/internal/lib/labels"
+)
+
+// UpdateLabels sets and removes labels and annotations of a command and returns the result.
+func (s *CommandService) UpdateLabels(id int, update models.LabelUpdate) (models.CommandLabels, error) {
+	return s.Store.UpdateLabels(context.Background(), id, func(current models.CommandLabels) (models.CommandLabels, error) {
+		result := models.CommandLabels{
+			Labels:      mergeLabels(current.Labels, update.Labels),
+			Annotations: mergeLabels(current.Annotations, update.Annotations),
+		}
+		if err := labels.Validate(result.Labels); err != nil {
+			return models.CommandLabels{}, err
+		}
+		if err := labels.ValidateAnnotations(result.Annotations); err != nil {
+			return models.CommandLabels{}, err
+		}
+		return result, nil
+	})
+}
+
+// mergeLabels applies an update to labels, a nil value removes the key.
+func mergeLabels(current map[string]string, update map[string]*string) map[string]string {
+	merged := make(map[string]string, len(current)+len(update))
+	for key, value := range current {
+		merged[key] = value
+	}
+	for key, value := range update {
+		if value == nil {
+			delete(merged, key)
+		} else {
+			merged[key] = *value
+		}
+	}
+	return merged
+}
+
+// StopCommands stops every running command matching the label selector and cancels the
+// waiting ones, returning the IDs of the commands stopped or cancelled.
+func (s *CommandService) StopCommands(filter models.SelectorFilter) ([]int, error) {
+	matched, err := s.Store.MatchCommands(context.Background(),
+		[]string{models.StatusWaiting, models.StatusRunning}, filter.Selector)
+	if err != nil {
+		return nil, err
+	}
+
+	stopped := []int{}
+	for _, id := range matched {
+		if err := s.StopCommand(id); err != nil {
+			// The command may have finished in the meantime
+			s.Logger.Warn("Failed to stop command selected by labels", "commandID", id, "error", err)
+			continue
+		}
+		stopped = append(stopped, id)
+	}
+	s.Logger.Info("Commands stopped by label selector", "selector", filter.Selector.String(), "count", len(stopped))
+	return stopped, nil
+}
@@ -0,0 +1,30 @@
+package services
+
+import (
+	"context"
+	"github.com/17HIERARCH70/BashAPI/internal/domain/models"
+	"github.com/17HIERARCH70/BashAPI/internal/lib/metrics"
+	"github.com/17HIERARCH70/BashAPI/internal/storage"
+)
+
+// observeTransition records a status change made by this replica in the metrics: the queue
+// wait when a queued command starts, the final status and the execution time when it finishes.
+func observeTransition(to string, t storage.Transition) {
+	if t.From == models.StatusWaiting && to == models.StatusRunning && t.StartedAt != nil {
+		metrics.QueueWait.WithLabelValues(t.Queue).Observe(t.StartedAt.Sub(t.CreatedAt).Seconds())
+	}
+	if !models.IsFinished(to) {
+		return
+	}
+	metrics.CommandsFinished.WithLabelValues(t.Queue, to).Inc()
+	if t.From == models.StatusRunning && t.StartedAt != nil && t.FinishedAt != nil {
+		metrics.ExecutionDuration.WithLabelValues(t.Queue, to).Observe(t.FinishedAt.Sub(*t.StartedAt).Seconds())
+	}
+}
+
+// FetchCommandStats counts the running, queued and paused commands and the used slots.
+func (s *CommandService) FetchCommandStats() (metrics.CommandStats, error) {
+	stats, err := s.Store.CommandStats(context.Background())
+	stats.Slots = s.capacity()
+	return stats, err
+}
@@ -0,0 +1,427 @@
+package services
+
+import (
+	"github.com/17HIERARCH70/BashAPI/internal/domain/models"
+	"github.com/17HIERARCH70/BashAPI/internal/lib/lines"
+	"github.com/17HIERARCH70/BashAPI/internal/storage"
+	"golang.org/x/net/context"
+	"sync"
+	"time"
+)
+
+// outputWriter appends command output to the chunks of the command. Bytes are
+// buffered and flushed as a new chunk once flush_bytes accumulate or every
+// flush_interval seconds. It is safe for concurrent use by stdout and stderr.
+//
+// With an output limit the writer keeps the first headLimit bytes and the last tailLimit
+// bytes of the stream. Dropped head bytes are never stored, chunks falling out of the
+// tail are deleted and the dropped range is recorded as the gap of the command.
+type outputWriter struct {
+	s         *CommandService
+	commandID int
+	// onLimit is called once when the output first exceeds the limit and the command
+	// asked to be killed.
+	onLimit func()
+
+	mu      sync.Mutex
+	pending []byte
+	seq     int
+	// offset is the stream offset of the first pending byte.
+	offset int64
+
+	limit     int64
+	headLimit int64
+	tailLimit int64
+	kill      bool
+	truncated bool
+	gapStart  int64
+	gapEnd    int64
+	// dropped counts the bytes past the head that were discarded without being stored.
+	dropped int64
+	// dirty is set when the truncation state changed without pending bytes to flush.
+	dirty bool
+
+	stop chan struct{}
+	done chan struct{}
+}
+
+// newOutputWriter creates a writer continuing after any chunks already stored for the command.
+func (s *CommandService) newOutputWriter(commandID int, onLimit func()) *outputWriter {
+	w := &outputWriter{
+		s:         s,
+		commandID: commandID,
+		onLimit:   onLimit,
+		stop:      make(chan struct{}),
+		done:      make(chan struct{}),
+	}
+	state, err := s.Store.FetchOutputWriter(context.Background(), commandID)
+	if err != nil {
+		s.Logger.Error("Failed to read command output position", "commandID", commandID, "error", err)
+	}
+	w.seq, w.offset, w.limit, w.kill = state.NextSeq, state.Layout.Size, state.Limit, state.Kill
+	w.truncated, w.gapStart, w.gapEnd = state.Layout.Truncated, state.Layout.GapStart, state.Layout.GapEnd
+	w.dropped = state.Layout.Dropped - (w.gapEnd - w.gapStart)
+
+	switch state.Policy {
+	case models.OutputKeepTail:
+		w.tailLimit = w.limit
+	case models.OutputKeepHeadTail:
+		w.headLimit = w.limit / 2
+		w.tailLimit = w.limit - w.headLimit
+	default:
+		w.headLimit = w.limit
+	}
+	go w.flushPeriodically()
+	return w
+}
+
+// Write buffers p and flushes it once the buffer reaches flush_bytes.
+func (w *outputWriter) Write(p []byte) (int, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	n := len(p)
+	if w.limit > 0 {
+		p = w.limitLocked(p)
+	}
+	w.pending = append(w.pending, p...)
+	if len(w.pending) >= w.s.outputFlushBytes() {
+		w.flushLocked()
+	}
+	return n, nil
+}
+
+// limitLocked buffers the part of p that still fits in the head and returns the rest,
+// which belongs to the tail, or nil when there is no tail to keep.
+func (w *outputWriter) limitLocked(p []byte) []byte {
+	if end := w.offset + int64(len(w.pending)); end < w.headLimit {
+		take := w.headLimit - end
+		if take > int64(len(p)) {
+			take = int64(len(p))
+		}
+		w.pending = append(w.pending, p[:take]...)
+		p = p[take:]
+		// Flush the complete head on its own so that no chunk crosses the gap
+		if end+take == w.headLimit {
+			w.flushLocked()
+		}
+	}
+	if len(p) == 0 || w.tailLimit > 0 {
+		return p
+	}
+
+	w.gapStart, w.gapEnd = w.headLimit, w.headLimit
+	w.dropped += int64(len(p))
+	w.markTruncatedLocked()
+	return nil
+}
+
+func (w *outputWriter) markTruncatedLocked() {
+	w.dirty = true
+	if w.truncated {
+		return
+	}
+	w.truncated = true
+	if w.kill && w.onLimit != nil {
+		w.onLimit()
+	}
+}
+
+// Close stops the periodic flushing and stores whatever is still buffered.
+func (w *outputWriter) Close() error {
+	close(w.stop)
+	<-w.done
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.flushLocked()
+	return nil
+}
+
+func (w *outputWriter) flushPeriodically() {
+	defer close(w.done)
+	ticker := time.NewTicker(w.s.outputFlushInterval())
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			w.mu.Lock()
+			w.flushLocked()
+			w.mu.Unlock()
+		case <-w.stop:
+			return
+		}
+	}
+}
+
+// flushLocked stores the buffered bytes as the next chunk, moving the gap forward and
+// deleting chunks that fell out of the tail. On failure the bytes stay buffered and are
+// retried with the next flush.
+func (w *outputWriter) flushLocked() {
+	if len(w.pending) == 0 && !w.dirty {
+		return
+	}
+	data, byteOffset := w.pending, w.offset
+	end := w.offset + int64(len(w.pending))
+
+	if w.tailLimit > 0 && w.offset >= w.headLimit {
+		tailStart := w.headLimit
+		if w.gapEnd > tailStart {
+			tailStart = w.gapEnd
+		}
+		if end-tailStart > w.tailLimit {
+			w.gapStart, w.gapEnd = w.headLimit, end-w.tailLimit
+			w.markTruncatedLocked()
+			// Pending bytes may already be out of the tail themselves
+			if w.gapEnd > byteOffset {
+				data = data[w.gapEnd-byteOffset:]
+				byteOffset = w.gapEnd
+			}
+		}
+	}
+
+	err := w.s.Store.AppendOutput(context.Background(), w.commandID,
+		storage.OutputChunk{Seq: w.seq, Offset: byteOffset, Data: data},
+		storage.OutputLayout{
+			Size:      end,
+			Truncated: w.truncated,
+			GapStart:  w.gapStart,
+			GapEnd:    w.gapEnd,
+			Dropped:   w.dropped + w.gapEnd - w.gapStart,
+		})
+	if err != nil {
+		w.s.Logger.Error("Failed to append command output", "commandID", w.commandID, "error", err)
+		return
+	}
+	if len(data) > 0 {
+		w.seq++
+	}
+	w.offset = end
+	w.pending = w.pending[:0]
+	w.dirty = false
+}
+
+// outputLayout is the stored layout of an output, which readers see as the bytes before
+// GapStart, the truncation marker and the bytes from GapEnd up to Size.
+type outputLayout storage.OutputLayout
+
+// describe sets the output size, truncation and archive time of a command.
+func (l outputLayout) describe(command *models.Command) {
+	command.OutputSize = l.length()
+	command.OutputTruncated = l.Truncated
+	command.OutputArchivedAt = l.ArchivedAt
+}
+
+// outputSegment is a stored stream range, or the truncation marker.
+type outputSegment struct {
+	from, to int64
+	marker   bool
+}
+
+func (l outputLayout) segments() []outputSegment {
+	if !l.Truncated {
+		return []outputSegment{{from: 0, to: l.Size}}
+	}
+	return []outputSegment{
+		{from: 0, to: l.GapStart},
+		{marker: true},
+		{from: l.GapEnd, to: l.Size},
+	}
+}
+
+func (seg outputSegment) length() int64 {
+	if seg.marker {
+		return int64(len(models.OutputTruncationMarker))
+	}
+	return seg.to - seg.from
+}
+
+// length is the size of the kept output as seen by readers.
+func (l outputLayout) length() int64 {
+	var n int64
+	for _, seg := range l.segments() {
+		n += seg.length()
+	}
+	return n
+}
+
+// FetchCommandOutput returns a byte range of a command output, or the lines of that
+// range selected by head, tail and grep.
+func (s *CommandService) FetchCommandOutput(id int, query models.OutputQuery) (models.CommandOutput, error) {
+	stored, err := s.Store.FetchOutputLayout(context.Background(), id)
+	if err != nil {
+		return models.CommandOutput{}, err
+	}
+	layout := outputLayout(stored)
+	result := models.CommandOutput{
+		CommandID: id,
+		Size:      layout.length(),
+		Truncated: layout.Truncated,
+		Dropped:   layout.Dropped,
+		Offset:    query.Offset,
+	}
+
+	from, to := query.Offset, result.Size
+	if query.Limit > 0 && from+query.Limit < to {
+		to = from + query.Limit
+	}
+	if from >= to {
+		if query.IsLineMode() {
+			s.setLines(&result, nil)
+		} else {
+			next := from
+			result.NextOffset = &next
+		}
+		return result, nil
+	}
+
+	switch {
+	case query.Tail > 0:
+		tail := &lines.Tail{N: query.Tail, Match: query.Grep}
+		err = s.scanKept(id, layout, from, to, true, func(data []byte) bool {
+			tail.Prepend(data)
+			return !tail.Done()
+		})
+		s.setLines(&result, tail.Lines())
+	case query.IsLineMode():
+		head := &lines.Head{N: query.Head, Match: query.Grep}
+		err = s.scanKept(id, layout, from, to, false, func(data []byte) bool {
+			head.Write(data)
+			return !head.Done()
+		})
+		s.setLines(&result, head.Lines())
+	default:
+		output := make([]byte, 0, to-from)
+		err = s.scanKept(id, layout, from, to, false, func(data []byte) bool {
+			output = append(output, data...)
+			return true
+		})
+		next := from + int64(len(output))
+		result.NextOffset = &next
+		result.Output = string(output)
+	}
+	if err != nil {
+		return models.CommandOutput{}, err
+	}
+	return result, nil
+}
+
+func (s *CommandService) setLines(result *models.CommandOutput, selected [][]byte) {
+	count := len(selected)
+	result.Lines = &count
+	result.Output = string(lines.Join(selected))
+}
+
+// inlineOutput returns the beginning of the kept output for the Output field of a command.
+func (s *CommandService) inlineOutput(id int, layout outputLayout) (string, error) {
+	to := layout.length()
+	if limit := int64(s.outputInlineLimit()); to > limit {
+		to = limit
+	}
+	output := make([]byte, 0, to)
+	err := s.scanKept(id, layout, 0, to, false, func(data []byte) bool {
+		output = append(output, data...)
+		return true
+	})
+	return string(output), err
+}
+
+// scanKept passes the kept output in [from, to) to fn like the ScanOutput of the store,
+// where offsets count the head, the truncation marker and the tail of a truncated output
+// one after another. An archived output already holds them in this order.
+func (s *CommandService) scanKept(id int, layout outputLayout, from, to int64, backwards bool, fn func(data []byte) bool) error {
+	if layout.Archive != nil {
+		return s.scanArchive(*layout.Archive, from, to, backwards, fn)
+	}
+
+	segments := layout.segments()
+	starts := make([]int64, len(segments))
+	var position int64
+	for i, seg := range segments {
+		starts[i] = position
+		position += seg.length()
+	}
+
+	stopped := false
+	visit := func(data []byte) bool {
+		stopped = !fn(data)
+		return !stopped
+	}
+	for i := range segments {
+		if backwards {
+			i = len(segments) - 1 - i
+		}
+		seg := segments[i]
+		lo, hi := from-starts[i], to-starts[i]
+		if lo < 0 {
+			lo = 0
+		}
+		if hi > seg.length() {
+			hi = seg.length()
+		}
+		if lo >= hi {
+			continue
+		}
+
+		if seg.marker {
+			visit([]byte(models.OutputTruncationMarker)[lo:hi])
+		} else if err := s.Store.ScanOutput(context.Background(), id, seg.from+lo, seg.from+hi, backwards, visit); err != nil {
+			return err
+		}
+		if stopped {
+			return nil
+		}
+	}
+	return nil
+}
+
+// normalizeOutputLimit applies the configured output limit defaults to the options of a new command.
+func (s *CommandService) normalizeOutputLimit(opts *models.CommandOptions) error {
+	cfg := s.Config.Commands.Output
+	if opts.MaxOutput == 0 {
+		opts.MaxOutput = cfg.MaxSize
+	}
+	if cfg.MaxSizeLimit > 0 {
+		if opts.MaxOutput == 0 {
+			opts.MaxOutput = cfg.MaxSizeLimit
+		}
+		if opts.MaxOutput > cfg.MaxSizeLimit {
+			return ErrOutputLimitTooLarge
+		}
+	}
+	if opts.OutputPolicy == "" {
+		opts.OutputPolicy = cfg.Policy
+	}
+	opts.KillOnOutputLimit = opts.KillOnOutputLimit || cfg.KillOnLimit
+	return nil
+}
+
+func (s *CommandService) outputFlushBytes() int {
+	if s.Config.Commands.Output.FlushBytes > 0 {
+		return s.Config.Commands.Output.FlushBytes
+	}
+	return 64 * 1024
+}
+
+func (s *CommandService) outputFlushInterval() time.Duration {
+	if s.Config.Commands.Output.FlushInterval > 0 {
+		return time.Duration(s.Config.Commands.Output.FlushInterval) * time.Second
+	}
+	return 3 * time.Second
+}
+
+// outputInlineLimit is the number of output bytes returned in a command record.
+func (s *CommandService) outputInlineLimit() int {
+	if s.Config.Commands.Output.InlineLimit > 0 {
+		return s.Config.Commands.Output.InlineLimit
+	}
+	return 1024 * 1024
+}
+
+// outputSearchLimit is the number of output bytes indexed for the search.
+func (s *CommandService) outputSearchLimit() int {
+	if s.Config.Commands.Output.SearchLimit > 0 {
+		return s.Config.Commands.Output.SearchLimit
+	}
+	return 256 * 1024
+}
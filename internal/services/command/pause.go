@@ -0,0 +1,37 @@
+package services
+
+import (
+	"context"
+	"github.com/17HIERARCH70/BashAPI/internal/domain/models"
+)
+
+// PauseQueue stops the dispatcher from starting commands of the named queue,
+// or of every queue for models.AllQueues. Running commands are not affected.
+func (s *CommandService) PauseQueue(name, reason string) error {
+	if err := s.Store.PauseQueue(context.Background(), name, reason); err != nil {
+		s.Logger.Error("Failed to pause queue", "queue", name, "error", err)
+		return err
+	}
+	s.Logger.Info("Queue paused", "queue", name, "reason", reason)
+	return nil
+}
+
+// ResumeQueue lets the dispatcher start commands of a paused queue again.
+func (s *CommandService) ResumeQueue(name string) error {
+	paused, err := s.Store.ResumeQueue(context.Background(), name)
+	if err != nil {
+		s.Logger.Error("Failed to resume queue", "queue", name, "error", err)
+		return err
+	}
+	if !paused {
+		return ErrQueueNotPaused
+	}
+	s.Logger.Info("Queue resumed", "queue", name)
+	s.notifyDispatcher()
+	return nil
+}
+
+// FetchQueuePauses lists the paused queues.
+func (s *CommandService) FetchQueuePauses() ([]models.QueuePause, error) {
+	return s.Store.FetchQueuePauses(context.Background())
+}
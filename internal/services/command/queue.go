@@ -0,0 +1,68 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"github.com/17HIERARCH70/BashAPI/internal/domain/models"
+	"github.com/17HIERARCH70/BashAPI/internal/lib/metrics"
+	"github.com/17HIERARCH70/BashAPI/internal/storage"
+)
+
+// CancelCommand removes a waiting command from the queue and marks it as cancelled.
+func (s *CommandService) CancelCommand(id int) error {
+	transition, err := s.Store.DequeueCommand(context.Background(), id, statusChange(models.StatusCancelled, actorAPI, ""))
+	if err != nil {
+		if errors.Is(err, ErrInvalidTransition) {
+			return ErrNotQueued
+		}
+		return err
+	}
+	s.statusChanged(id, models.StatusCancelled, transition)
+	s.Logger.Info("Queued command cancelled", "commandID", id)
+	return nil
+}
+
+// MoveQueuedCommand puts a waiting command at the given 1-based position of the queue.
+// Positions past the end of the queue move the command to the back.
+func (s *CommandService) MoveQueuedCommand(id, position int) error {
+	position, err := s.Store.MoveQueuedCommand(context.Background(), id, position)
+	if err != nil {
+		return err
+	}
+	s.Logger.Info("Queued command moved", "commandID", id, "position", position)
+	s.notifyDispatcher()
+	return nil
+}
+
+// CancelQueuedCommands cancels every waiting command matching the filter and returns their IDs.
+func (s *CommandService) CancelQueuedCommands(filter models.QueueFilter) ([]int, error) {
+	cancelled, err := s.Store.CancelQueuedCommands(context.Background(), filter, storage.StatusChange{
+		To: models.StatusCancelled, Actor: actorAPI, Reason: "bulk cancel",
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	ids := []int{}
+	for _, command := range cancelled {
+		ids = append(ids, command.ID)
+		metrics.CommandsFinished.WithLabelValues(command.Queue, models.StatusCancelled).Inc()
+	}
+	s.Logger.Info("Queued commands cancelled", "count", len(ids))
+	return ids, nil
+}
+
+// expireQueuedCommands moves commands that waited longer than their TTL to the expired status.
+func (s *CommandService) expireQueuedCommands() {
+	expired, err := s.Store.ExpireQueuedCommands(context.Background(), storage.StatusChange{
+		To: models.StatusExpired, Actor: actorDispatcher, Reason: "queue ttl elapsed",
+	})
+	if err != nil {
+		s.Logger.Error("Failed to expire queued commands", "error", err)
+		return
+	}
+	for _, command := range expired {
+		s.Logger.Info("Queued command expired", "commandID", command.ID)
+		metrics.CommandsFinished.WithLabelValues(command.Queue, models.StatusExpired).Inc()
+	}
+}
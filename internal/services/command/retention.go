@@ -0,0 +1,136 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"github.com/17HIERARCH70/BashAPI/internal/config"
+	"github.com/17HIERARCH70/BashAPI/internal/domain/models"
+	"time"
+)
+
+var ErrCommandRunning = errors.New("command is running, stop it first")
+
+// StartJanitor periodically applies the retention rules until ctx is cancelled.
+func (s *CommandService) StartJanitor(ctx context.Context) {
+	interval := time.Duration(s.Config.Commands.Retention.Interval) * time.Second
+	if interval <= 0 {
+		interval = time.Hour
+	}
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			s.applyRetention()
+			select {
+			case <-ctx.Done():
+				s.Logger.Info("Janitor stopped")
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+}
+
+// applyRetention deletes the commands falling out of any retention rule.
+func (s *CommandService) applyRetention() {
+	for _, rule := range s.Config.Commands.Retention.Rules {
+		for _, filter := range retentionFilters(rule, time.Now()) {
+			result, err := s.PurgeCommands(filter)
+			if err != nil {
+				s.Logger.Error("Failed to apply retention rule", "queue", rule.Queue, "error", err)
+				continue
+			}
+			if result.Count > 0 {
+				s.Logger.Info("Old commands deleted by retention", "queue", rule.Queue, "count", result.Count)
+			}
+		}
+	}
+}
+
+// retentionFilters turns a rule into one purge per limit, as a command is deleted once
+// it exceeds either of them.
+func retentionFilters(rule config.RetentionRule, now time.Time) []models.PurgeFilter {
+	base := models.PurgeFilter{All: true, Statuses: rule.Statuses}
+	if rule.Queue != models.AllQueues {
+		base.Queue = rule.Queue
+	}
+
+	var filters []models.PurgeFilter
+	if rule.MaxAgeDays > 0 {
+		byAge := base
+		cutoff := now.AddDate(0, 0, -rule.MaxAgeDays)
+		byAge.FinishedBefore = &cutoff
+		filters = append(filters, byAge)
+	}
+	if rule.MaxCount > 0 {
+		byCount := base
+		byCount.KeepLast = rule.MaxCount
+		filters = append(filters, byCount)
+	}
+	return filters
+}
+
+// DeleteCommand deletes a command that is not running, together with its queue row,
+// its output and its archived output.
+func (s *CommandService) DeleteCommand(id int) error {
+	cmd, _, err := s.Store.FetchCommand(context.Background(), id)
+	if err != nil {
+		return err
+	}
+	if cmd.Status == models.StatusRunning {
+		return ErrCommandRunning
+	}
+
+	deleted, _, err := s.deleteCommands([]int{id})
+	if err != nil {
+		return err
+	}
+	if len(deleted) == 0 {
+		// The command was started or deleted in the meantime
+		return ErrCommandRunning
+	}
+	s.notifyDispatcher()
+	return nil
+}
+
+// PurgeCommands deletes every command matching the filter, or only lists them in a dry run.
+func (s *CommandService) PurgeCommands(filter models.PurgeFilter) (models.PurgeResult, error) {
+	if len(filter.Statuses) == 0 {
+		filter.Statuses = models.FinishedStatuses
+	}
+	ids, archived, err := s.Store.FindPurgeable(context.Background(), filter)
+	if err != nil {
+		return models.PurgeResult{}, err
+	}
+	result := models.PurgeResult{DryRun: filter.DryRun, IDs: ids, ArchivedOutputs: archived}
+
+	if !filter.DryRun && len(result.IDs) > 0 {
+		deleted, archives, err := s.deleteCommands(result.IDs)
+		if err != nil {
+			return models.PurgeResult{}, err
+		}
+		result.IDs, result.ArchivedOutputs = deleted, archives
+		s.notifyDispatcher()
+	}
+	result.Count = len(result.IDs)
+	return result, nil
+}
+
+// deleteCommands deletes the commands that are not running with everything stored for them,
+// then removes their archived outputs. It returns the deleted IDs and the number of removed
+// archives.
+func (s *CommandService) deleteCommands(ids []int) ([]int, int, error) {
+	deleted, archives, err := s.Store.DeleteCommands(context.Background(), ids)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	for _, ref := range archives {
+		if s.Archive == nil {
+			s.Logger.Warn("Archived output left behind, no archive is configured", "ref", ref)
+			continue
+		}
+		s.deleteArchive(ref)
+	}
+	return deleted, len(archives), nil
+}
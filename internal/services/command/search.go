@@ -0,0 +1,55 @@
+package services
+
+import (
+	"context"
+	"github.com/17HIERARCH70/BashAPI/internal/domain/models"
+	"strings"
+)
+
+// SearchCommands retrieves one page of the commands whose script or indexed output matches
+// the query text, with highlighted snippets.
+func (s *CommandService) SearchCommands(query models.SearchQuery) (models.SearchPage, error) {
+	results, layouts, next, err := s.Store.SearchCommands(context.Background(), &query)
+	if err != nil {
+		return models.SearchPage{}, err
+	}
+	for i := range results {
+		outputLayout(layouts[i]).describe(&results[i].Command)
+	}
+	return models.SearchPage{Items: results, NextCursor: next}, nil
+}
+
+// indexOutput stores the beginning of the output of a finished command for the search.
+func (s *CommandService) indexOutput(commandID int) {
+	ctx := context.Background()
+	stored, err := s.Store.FetchOutputLayout(ctx, commandID)
+	if err != nil {
+		s.Logger.Error("Failed to index command output", "commandID", commandID, "error", err)
+		return
+	}
+	layout := outputLayout(stored)
+	to := layout.length()
+	if to == 0 {
+		return
+	}
+	if limit := int64(s.outputSearchLimit()); to > limit {
+		to = limit
+	}
+
+	content := make([]byte, 0, to)
+	err = s.scanKept(commandID, layout, 0, to, false, func(data []byte) bool {
+		content = append(content, data...)
+		return true
+	})
+	if err == nil {
+		err = s.Store.IndexOutput(ctx, commandID, searchText(content))
+	}
+	if err != nil {
+		s.Logger.Error("Failed to index command output", "commandID", commandID, "error", err)
+	}
+}
+
+// searchText turns output into text PostgreSQL accepts, dropping invalid UTF-8 and NUL bytes.
+func searchText(data []byte) string {
+	return strings.ReplaceAll(strings.ToValidUTF8(string(data), ""), "\x00", "")
+}
@@ -0,0 +1,126 @@
+package services
+
+import (
+	"context"
+	"github.com/17HIERARCH70/BashAPI/internal/domain/models"
+	"os"
+	"syscall"
+	"time"
+)
+
+// Shutdown polling and the time given to interrupted commands to store their output.
+const (
+	shutdownPollInterval = 500 * time.Millisecond
+	interruptGracePeriod = 5 * time.Second
+)
+
+// ShutdownRunningCommands refuses new commands and ends the running ones the way the
+// configured shutdown mode says. The outcome is recorded in the event history of every
+// command: the wait mode lets commands finish until the deadline and stops the rest, the
+// requeue mode puts them back at the front of the queue to run again on the next start.
+func (s *CommandService) ShutdownRunningCommands() error {
+	s.shuttingDown.Store(true)
+
+	cfg := s.Config.Commands.Shutdown
+	var err error
+	switch cfg.Mode {
+	case models.ShutdownWait:
+		timeout := time.Duration(cfg.Timeout) * time.Second
+		s.Logger.Info("Waiting for running commands to finish", "running", s.executing.Load(), "timeout", timeout)
+		if s.waitForExecutions(timeout) {
+			return nil
+		}
+		err = s.stopRunningCommands("server shutdown, commands did not finish within " + timeout.String())
+	case models.ShutdownRequeue:
+		err = s.requeueRunningCommands()
+	default:
+		if cfg.Mode != models.ShutdownStop {
+			s.Logger.Warn("Unknown shutdown mode, stopping running commands", "mode", cfg.Mode)
+		}
+		err = s.stopRunningCommands("server shutdown")
+	}
+	// Let the interrupted commands store their output before the database is closed
+	s.waitForExecutions(interruptGracePeriod)
+	return err
+}
+
+// waitForExecutions waits until no command is executed by this process, at most for
+// timeout. It reports whether all of them finished.
+func (s *CommandService) waitForExecutions(timeout time.Duration) bool {
+	deadline := time.Now().Add(timeout)
+	for s.executing.Load() > 0 {
+		if time.Now().After(deadline) {
+			return false
+		}
+		time.Sleep(shutdownPollInterval)
+	}
+	return true
+}
+
+// runningCommandIDs returns the IDs of the running commands.
+func (s *CommandService) runningCommandIDs() ([]int, error) {
+	running, err := s.Store.FetchRunningCommands(context.Background())
+	if err != nil {
+		return nil, err
+	}
+	commandIDs := make([]int, 0, len(running))
+	for _, command := range running {
+		commandIDs = append(commandIDs, command.ID)
+	}
+	return commandIDs, nil
+}
+
+// stopRunningCommands stops all running commands with the reason recorded in their history.
+func (s *CommandService) stopRunningCommands(reason string) error {
+	commandIDs, err := s.runningCommandIDs()
+	if err != nil {
+		return err
+	}
+	for _, id := range commandIDs {
+		if err := s.stopCommand(id, actorShutdown, reason); err != nil {
+			s.Logger.Error("Failed to stop command", "commandID", id, "error", err)
+		}
+	}
+	return nil
+}
+
+// requeueRunningCommands puts all running commands back at the front of the queue and
+// interrupts them. The queue outlives the server, so they run again on the next start.
+func (s *CommandService) requeueRunningCommands() error {
+	commandIDs, err := s.runningCommandIDs()
+	if err != nil {
+		return err
+	}
+	for _, id := range commandIDs {
+		if err := s.requeueCommand(id); err != nil {
+			s.Logger.Error("Failed to requeue command", "commandID", id, "error", err)
+			continue
+		}
+		s.Logger.Info("Command requeued", "commandID", id)
+	}
+	return nil
+}
+
+// requeueCommand moves a running command back to the queue ahead of the waiting commands
+// and interrupts its process. The status is changed first, so that the exit caused by the
+// signal is not taken for an error.
+func (s *CommandService) requeueCommand(id int) error {
+	transition, pid, err := s.Store.RequeueCommand(context.Background(), id,
+		statusChange(models.StatusWaiting, actorShutdown, "requeued at server shutdown"))
+	if err != nil {
+		return err
+	}
+	s.statusChanged(id, models.StatusWaiting, transition)
+
+	if pid == nil {
+		return nil
+	}
+	process, err := os.FindProcess(*pid)
+	if err == nil {
+		err = process.Signal(syscall.SIGINT)
+	}
+	if err != nil {
+		s.Logger.Warn("Failed to interrupt requeued command, it has probably exited", "commandID", id, "error", err)
+	}
+	return nil
+}
@@ -0,0 +1,72 @@
+package services
+
+import (
+	"context"
+	"github.com/17HIERARCH70/BashAPI/internal/domain/models"
+	"github.com/17HIERARCH70/BashAPI/internal/storage"
+)
+
+var ErrInvalidTransition = storage.ErrInvalidTransition
+
+// Actors recorded in the event history.
+const (
+	actorAPI        = storage.ActorAPI
+	actorDispatcher = storage.ActorDispatcher
+	actorExecutor   = storage.ActorExecutor
+	actorShutdown   = storage.ActorShutdown
+)
+
+// transitions lists the statuses every status may change to. Commands that finished
+// without completing can be started again by force, completed ones cannot. Running
+// commands go back to the queue when they are requeued at shutdown.
+var transitions = map[string][]string{
+	models.StatusWaiting:   {models.StatusRunning, models.StatusCancelled, models.StatusExpired},
+	models.StatusRunning:   {models.StatusCompleted, models.StatusError, models.StatusTimeout, models.StatusStopped, models.StatusWaiting},
+	models.StatusError:     {models.StatusRunning},
+	models.StatusTimeout:   {models.StatusRunning},
+	models.StatusStopped:   {models.StatusRunning},
+	models.StatusCancelled: {models.StatusRunning},
+	models.StatusExpired:   {models.StatusRunning},
+}
+
+// sourceStatuses returns the statuses a command may change to status from.
+func sourceStatuses(status string) []string {
+	var sources []string
+	for from, targets := range transitions {
+		for _, to := range targets {
+			if to == status {
+				sources = append(sources, from)
+			}
+		}
+	}
+	return sources
+}
+
+// statusChange returns the change of a command to status, which is allowed from every
+// status listed in transitions as leading to it.
+func statusChange(to, actor, reason string) storage.StatusChange {
+	return storage.StatusChange{To: to, From: sourceStatuses(to), Actor: actor, Reason: reason}
+}
+
+// changeStatus moves a command to change.To when its current status allows it and records
+// the transition in its event history, see storage.CommandStore.ChangeStatus.
+func (s *CommandService) changeStatus(id int, change storage.StatusChange) error {
+	transition, err := s.Store.ChangeStatus(context.Background(), id, change)
+	if err != nil {
+		return err
+	}
+	s.statusChanged(id, change.To, transition)
+	return nil
+}
+
+// statusChanged wakes up the requests waiting for the command and records the transition
+// in the metrics, after a status change made by this replica.
+func (s *CommandService) statusChanged(id int, to string, transition storage.Transition) {
+	s.notifyStatus(id)
+	observeTransition(to, transition)
+}
+
+// FetchCommandEvents retrieves the status transitions of a command, oldest first.
+func (s *CommandService) FetchCommandEvents(id int) ([]models.CommandEvent, error) {
+	return s.Store.FetchCommandEvents(context.Background(), id)
+}
@@ -0,0 +1,177 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"github.com/17HIERARCH70/BashAPI/internal/domain/models"
+	"time"
+)
+
+const (
+	// subscriberBuffer is the number of events a subscriber may lag behind before it is dropped.
+	subscriberBuffer = 256
+	// maxReplayedEvents limits the stored events replayed to a subscriber or after a reconnect.
+	maxReplayedEvents = 1000
+	// listenRetryDelay is the pause before listening again after the connection failed.
+	listenRetryDelay = time.Second
+)
+
+// eventSubscription is a consumer of the event stream.
+type eventSubscription struct {
+	filter models.EventFilter
+	events chan models.LifecycleEvent
+}
+
+// SubscribeEvents returns the lifecycle events matching the filter as they happen on any
+// replica, and a function ending the subscription. The channel is closed when the
+// subscriber falls too far behind, it may reconnect from the last event it received.
+func (s *CommandService) SubscribeEvents(filter models.EventFilter) (<-chan models.LifecycleEvent, func()) {
+	sub := &eventSubscription{filter: filter, events: make(chan models.LifecycleEvent, subscriberBuffer)}
+	s.streamMu.Lock()
+	if s.subscribers == nil {
+		s.subscribers = make(map[*eventSubscription]struct{})
+	}
+	s.subscribers[sub] = struct{}{}
+	s.streamMu.Unlock()
+
+	return sub.events, func() {
+		s.streamMu.Lock()
+		defer s.streamMu.Unlock()
+		if _, ok := s.subscribers[sub]; ok {
+			delete(s.subscribers, sub)
+			close(sub.events)
+		}
+	}
+}
+
+// publishEvent passes an event to the matching subscribers.
+func (s *CommandService) publishEvent(event models.LifecycleEvent) {
+	s.streamMu.Lock()
+	defer s.streamMu.Unlock()
+	for sub := range s.subscribers {
+		if !sub.filter.Matches(event) {
+			continue
+		}
+		select {
+		case sub.events <- event:
+		default:
+			s.Logger.Warn("Event stream subscriber is too slow, dropping it", "eventID", event.EventID)
+			delete(s.subscribers, sub)
+			close(sub.events)
+		}
+	}
+}
+
+// ReplayEvents returns the stored events after filter.After matching the filter, oldest first.
+// The events that occurred shortly before filter.After are replayed again, since they may
+// have committed after it, so a subscriber must skip the ones it has seen.
+func (s *CommandService) ReplayEvents(filter models.EventFilter) ([]models.LifecycleEvent, error) {
+	ctx := context.Background()
+	if filter.After > 0 && filter.Since.IsZero() {
+		event, err := s.Store.FetchLifecycleEvent(ctx, filter.After)
+		if err != nil && !errors.Is(err, ErrNotFound) {
+			return nil, err
+		}
+		if err == nil {
+			filter.Since = event.OccurredAt.Add(-eventSettleDelay)
+		}
+	}
+	return s.Store.FetchLifecycleEvents(ctx, filter, maxReplayedEvents)
+}
+
+// deliveredEvents remembers the recently published events. Events are announced in commit
+// order rather than in ID order, so the replay after a reconnect starts shortly before the
+// newest event and skips the ones published already.
+type deliveredEvents struct {
+	// ids are the published events that occurred within eventSettleDelay of the newest one.
+	ids    map[int64]time.Time
+	newest models.LifecycleEvent
+}
+
+// add records an event and reports whether it was not published yet.
+func (d *deliveredEvents) add(event models.LifecycleEvent) bool {
+	if _, ok := d.ids[event.EventID]; ok {
+		return false
+	}
+	if d.ids == nil {
+		d.ids = make(map[int64]time.Time)
+	}
+	d.ids[event.EventID] = event.OccurredAt
+	if event.EventID > d.newest.EventID {
+		d.newest = event
+	}
+	settled := d.newest.OccurredAt.Add(-eventSettleDelay)
+	for id, at := range d.ids {
+		if at.Before(settled) {
+			delete(d.ids, id)
+		}
+	}
+	return true
+}
+
+// missed returns the filter of the events to replay after a reconnect.
+func (d *deliveredEvents) missed() models.EventFilter {
+	return models.EventFilter{After: d.newest.EventID, Since: d.newest.OccurredAt.Add(-eventSettleDelay)}
+}
+
+// StartEventStream listens to the command events announced by every replica and publishes
+// them to the subscribers until ctx is cancelled. Events announced while the connection
+// was lost are replayed from the store once it is back.
+func (s *CommandService) StartEventStream(ctx context.Context) {
+	go func() {
+		var delivered deliveredEvents
+		for {
+			err := s.listenEvents(ctx, &delivered)
+			if ctx.Err() != nil {
+				s.Logger.Info("Event stream stopped")
+				return
+			}
+			s.Logger.Error("Lost the event stream connection", "error", err)
+			select {
+			case <-ctx.Done():
+				s.Logger.Info("Event stream stopped")
+				return
+			case <-time.After(listenRetryDelay):
+			}
+		}
+	}()
+}
+
+// listenEvents publishes the announced events until listening fails.
+func (s *CommandService) listenEvents(ctx context.Context, delivered *deliveredEvents) error {
+	replayMissed := func() error {
+		if delivered.newest.EventID == 0 {
+			return nil
+		}
+		missed, err := s.Store.FetchLifecycleEvents(ctx, delivered.missed(), maxReplayedEvents)
+		if err != nil {
+			return err
+		}
+		for _, event := range missed {
+			s.deliverEvent(event, delivered)
+		}
+		return nil
+	}
+	return s.Store.ListenEvents(ctx, replayMissed, func(id int64) error {
+		event, err := s.Store.FetchLifecycleEvent(ctx, id)
+		if errors.Is(err, ErrNotFound) {
+			// The command was deleted in the meantime
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		s.deliverEvent(event, delivered)
+		return nil
+	})
+}
+
+// deliverEvent publishes an event not published yet and wakes up the local waiters of the
+// command, which otherwise only learn about changes made by other replicas when polling.
+func (s *CommandService) deliverEvent(event models.LifecycleEvent, delivered *deliveredEvents) {
+	if !delivered.add(event) {
+		return
+	}
+	s.publishEvent(event)
+	s.notifyStatus(event.CommandID)
+}
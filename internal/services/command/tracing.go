@@ -0,0 +1,33 @@
+package services
+
+import (
+	"context"
+	"github.com/17HIERARCH70/BashAPI/internal/lib/tracing"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+	"time"
+)
+
+// startSpan starts a span continuing the trace of traceParent, the stored trace context of
+// a command.
+func startSpan(traceParent, name string, opts ...trace.SpanStartOption) (context.Context, trace.Span) {
+	return tracing.Tracer().Start(tracing.ContextWithTraceParent(context.Background(), traceParent), name, opts...)
+}
+
+// endSpan ends a span, marking it failed when err is set.
+func endSpan(span trace.Span, err error) {
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End()
+}
+
+// recordQueueWait adds a span covering the time a command waited in the queue, from its
+// creation until now.
+func recordQueueWait(commandID int, queue, traceParent string, createdAt time.Time) {
+	_, span := startSpan(traceParent, "command.queued", trace.WithTimestamp(createdAt),
+		trace.WithAttributes(attribute.Int("command.id", commandID), attribute.String("command.queue", queue)))
+	span.End()
+}
@@ -0,0 +1,85 @@
+package services
+
+import (
+	"context"
+	"github.com/17HIERARCH70/BashAPI/internal/domain/models"
+	"time"
+)
+
+// waitPollInterval is how often a waiting request re-reads the status, which catches changes
+// made by other replicas or committed after the in-process notification.
+const waitPollInterval = time.Second
+
+// WaitForCommand blocks until the command reaches a final status, the timeout elapses or ctx
+// is cancelled, and returns the command with whether it finished. The timeout is capped at
+// the configured max_wait.
+func (s *CommandService) WaitForCommand(ctx context.Context, id int, timeout time.Duration) (models.Command, bool, error) {
+	if maxWait := time.Duration(s.Config.Commands.MaxWait) * time.Second; maxWait > 0 && timeout > maxWait {
+		timeout = maxWait
+	}
+	deadline := time.NewTimer(timeout)
+	defer deadline.Stop()
+	poll := time.NewTicker(waitPollInterval)
+	defer poll.Stop()
+
+	for {
+		changed, unwatch := s.watchStatus(id)
+		command, _, err := s.Store.FetchCommand(ctx, id)
+		if err != nil || models.IsFinished(command.Status) {
+			unwatch()
+			break
+		}
+		select {
+		case <-changed:
+		case <-poll.C:
+		case <-deadline.C:
+			unwatch()
+			command, err := s.FetchCommandByID(id)
+			return command, false, err
+		case <-ctx.Done():
+			unwatch()
+			return models.Command{}, false, ctx.Err()
+		}
+		unwatch()
+	}
+
+	command, err := s.FetchCommandByID(id)
+	return command, err == nil && models.IsFinished(command.Status), err
+}
+
+// watchStatus returns a channel closed on the next status change of the command made by
+// this process or, through the event stream, by another replica, and a function releasing it.
+func (s *CommandService) watchStatus(id int) (<-chan struct{}, func()) {
+	changed := make(chan struct{})
+	s.watchMu.Lock()
+	if s.watchers == nil {
+		s.watchers = make(map[int][]chan struct{})
+	}
+	s.watchers[id] = append(s.watchers[id], changed)
+	s.watchMu.Unlock()
+
+	return changed, func() {
+		s.watchMu.Lock()
+		defer s.watchMu.Unlock()
+		watchers := s.watchers[id]
+		for i, watcher := range watchers {
+			if watcher == changed {
+				s.watchers[id] = append(watchers[:i], watchers[i+1:]...)
+				break
+			}
+		}
+		if len(s.watchers[id]) == 0 {
+			delete(s.watchers, id)
+		}
+	}
+}
+
+// notifyStatus wakes up the requests waiting for a status change of the command.
+func (s *CommandService) notifyStatus(id int) {
+	s.watchMu.Lock()
+	defer s.watchMu.Unlock()
+	for _, watcher := range s.watchers[id] {
+		close(watcher)
+	}
+	delete(s.watchers, id)
+}
@@ -0,0 +1,220 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"github.com/17HIERARCH70/BashAPI/internal/domain/models"
+	"github.com/17HIERARCH70/BashAPI/internal/lib/webhook"
+	"github.com/17HIERARCH70/BashAPI/internal/storage"
+	"io"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+)
+
+var ErrWebhookNotFound = storage.ErrWebhookNotFound
+
+const (
+	// fanOutBatch is the number of command events turned into deliveries per transaction.
+	fanOutBatch = 500
+	// eventSettleDelay holds back the newest events, so that an event committed after one with
+	// a higher ID is not skipped by the cursor.
+	eventSettleDelay = 2 * time.Second
+	// maxErrorLength limits the response excerpt stored for a failed attempt.
+	maxErrorLength = 512
+)
+
+// StartWebhooks periodically turns new command events into deliveries and sends the due
+// ones until ctx is cancelled.
+func (s *CommandService) StartWebhooks(ctx context.Context) {
+	cfg := s.Config.Commands.Webhooks
+	interval := time.Duration(cfg.Interval) * time.Second
+	if interval <= 0 {
+		interval = 2 * time.Second
+	}
+	client := &http.Client{Timeout: time.Duration(cfg.Timeout) * time.Second}
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			if err := s.fanOutEvents(); err != nil {
+				s.Logger.Error("Failed to queue webhook deliveries", "error", err)
+			}
+			if err := s.deliverWebhooks(ctx, client); err != nil {
+				s.Logger.Error("Failed to deliver webhooks", "error", err)
+			}
+			select {
+			case <-ctx.Done():
+				s.Logger.Info("Webhook delivery stopped")
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+}
+
+// fanOutEvents creates a delivery for every command event after the cursor and every
+// subscriber: the matching webhooks and the callback URL of the command.
+func (s *CommandService) fanOutEvents() error {
+	ctx := context.Background()
+	webhooks, err := s.FetchWebhooks()
+	if err != nil {
+		return err
+	}
+
+	return s.Store.FanOutEvents(ctx, time.Now().Add(-eventSettleDelay), fanOutBatch,
+		func(events []storage.PendingEvent) ([]storage.NewDelivery, error) {
+			var deliveries []storage.NewDelivery
+			for _, event := range events {
+				payload, err := json.Marshal(event.Event)
+				if err != nil {
+					return nil, err
+				}
+				for _, hook := range webhooks {
+					if !subscribed(hook.Events, event.Event.Event) {
+						continue
+					}
+					id := hook.ID
+					deliveries = append(deliveries, storage.NewDelivery{WebhookID: &id, Event: event.Event, URL: hook.URL, Payload: payload})
+				}
+				if event.CallbackURL != "" {
+					deliveries = append(deliveries, storage.NewDelivery{Event: event.Event, URL: event.CallbackURL, Payload: payload})
+				}
+			}
+			return deliveries, nil
+		})
+}
+
+// subscribed reports whether a webhook listening to events receives event.
+func subscribed(events []string, event string) bool {
+	if len(events) == 0 {
+		return true
+	}
+	for _, e := range events {
+		if e == event {
+			return true
+		}
+	}
+	return false
+}
+
+// deliverWebhooks claims one batch of due deliveries and sends them concurrently. Claimed
+// deliveries are leased past the request timeout, so other replicas skip them meanwhile and
+// a delivery interrupted by a crash is retried once the lease expires.
+func (s *CommandService) deliverWebhooks(ctx context.Context, client *http.Client) error {
+	cfg := s.Config.Commands.Webhooks
+	batch := cfg.BatchSize
+	if batch <= 0 {
+		batch = 50
+	}
+	lease := time.Duration(2*cfg.Timeout+cfg.Interval) * time.Second
+
+	due, err := s.Store.ClaimDeliveries(context.Background(), batch, lease)
+	if err != nil {
+		return err
+	}
+
+	var wg sync.WaitGroup
+	for _, d := range due {
+		wg.Add(1)
+		go func(d storage.DueDelivery) {
+			defer wg.Done()
+			statusCode, elapsed, sendErr := s.sendDelivery(ctx, client, d)
+			if err := s.recordAttempt(d, statusCode, sendErr, elapsed); err != nil {
+				s.Logger.Error("Failed to record webhook attempt", "deliveryID", d.ID, "error", err)
+			}
+		}(d)
+	}
+	wg.Wait()
+	return nil
+}
+
+// sendDelivery posts the payload of a delivery, signed with its secret. It returns the
+// response status, nil when no response came, the request duration and the reason of a failure.
+func (s *CommandService) sendDelivery(ctx context.Context, client *http.Client, d storage.DueDelivery) (*int, time.Duration, error) {
+	secret := d.Secret
+	if secret == "" {
+		secret = s.Config.Commands.Webhooks.Secret
+	}
+	body := []byte(d.Payload)
+	timestamp := time.Now().Unix()
+
+	started := time.Now()
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, d.URL, bytes.NewReader(body))
+	if err != nil {
+		return nil, time.Since(started), err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "BashAPI-Webhook")
+	req.Header.Set(webhook.EventHeader, d.Event)
+	req.Header.Set(webhook.DeliveryHeader, strconv.FormatInt(d.ID, 10))
+	req.Header.Set(webhook.TimestampHeader, strconv.FormatInt(timestamp, 10))
+	if secret != "" {
+		req.Header.Set(webhook.SignatureHeader, webhook.Sign([]byte(secret), timestamp, body))
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, time.Since(started), err
+	}
+	defer resp.Body.Close()
+	excerpt, _ := io.ReadAll(io.LimitReader(resp.Body, maxErrorLength))
+	elapsed := time.Since(started)
+	statusCode := resp.StatusCode
+	if statusCode < 200 || statusCode > 299 {
+		return &statusCode, elapsed, fmt.Errorf("unexpected status %d: %s", statusCode, searchText(excerpt))
+	}
+	return &statusCode, elapsed, nil
+}
+
+// recordAttempt logs an attempt of a delivery and schedules its retry, or marks it delivered
+// or failed once the attempts are exhausted.
+func (s *CommandService) recordAttempt(d storage.DueDelivery, statusCode *int, sendErr error, elapsed time.Duration) error {
+	cfg := s.Config.Commands.Webhooks
+	attempt := d.Attempts + 1
+	status, message := models.DeliveryDelivered, ""
+	var nextAttempt time.Time
+	if sendErr != nil {
+		message = sendErr.Error()
+		status = models.DeliveryPending
+		if attempt >= cfg.MaxAttempts {
+			status = models.DeliveryFailed
+		}
+		nextAttempt = time.Now().Add(webhook.Backoff(attempt,
+			time.Duration(cfg.Backoff)*time.Second, time.Duration(cfg.MaxBackoff)*time.Second))
+		s.Logger.Warn("Webhook delivery failed", "deliveryID", d.ID, "url", d.URL, "attempt", attempt, "error", sendErr)
+	}
+
+	return s.Store.RecordAttempt(context.Background(), storage.DeliveryAttempt{
+		DeliveryID: d.ID, Attempt: attempt, StatusCode: statusCode, Error: message,
+		Duration: elapsed, Status: status, NextAttemptAt: nextAttempt,
+	})
+}
+
+// CreateWebhook subscribes a URL to command events.
+func (s *CommandService) CreateWebhook(hook models.Webhook) (models.Webhook, error) {
+	if hook.Events == nil {
+		hook.Events = []string{}
+	}
+	hook, err := s.Store.CreateWebhook(context.Background(), hook)
+	hook.Secret = ""
+	return hook, err
+}
+
+// FetchWebhooks lists the webhooks, secrets included.
+func (s *CommandService) FetchWebhooks() ([]models.Webhook, error) {
+	return s.Store.FetchWebhooks(context.Background())
+}
+
+// DeleteWebhook removes a webhook together with its deliveries.
+func (s *CommandService) DeleteWebhook(id int) error {
+	return s.Store.DeleteWebhook(context.Background(), id)
+}
+
+// FetchDeliveries lists the latest deliveries of a command or of a webhook with their attempts.
+func (s *CommandService) FetchDeliveries(query models.DeliveryQuery) ([]models.WebhookDelivery, error) {
+	return s.Store.FetchDeliveries(context.Background(), query)
+}
@@ -0,0 +1,23 @@
+package storage
+
+import (
+	"context"
+	"time"
+)
+
+// ArchiveStore tracks the outputs moved to the output archive, which replaces their chunks.
+type ArchiveStore interface {
+	// FindArchivable returns at most limit commands finished before finishedBefore, oldest
+	// first, whose output is stored and not archived yet.
+	FindArchivable(ctx context.Context, finishedBefore time.Time, limit int) ([]ArchivableOutput, error)
+	// SetOutputArchive records the reference of the archived output of a command and deletes
+	// its chunks. It reports false when the output was archived in the meantime.
+	SetOutputArchive(ctx context.Context, id int, ref string) (bool, error)
+}
+
+// ArchivableOutput is the output of a finished command that can be archived.
+type ArchivableOutput struct {
+	ID         int
+	FinishedAt time.Time
+	Layout     OutputLayout
+}
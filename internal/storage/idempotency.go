@@ -0,0 +1,26 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"time"
+)
+
+var (
+	ErrIdempotencyConflict   = errors.New("idempotency key was already used with a different request body")
+	ErrIdempotencyInProgress = errors.New("a request with this idempotency key is still being processed")
+)
+
+// IdempotencyStore remembers the requests made with an idempotency key and their responses.
+type IdempotencyStore interface {
+	// ReserveIdempotencyKey claims a key for the request with the given hash, once the keys
+	// reserved before expiredBefore are forgotten. It returns nil when the key was claimed and
+	// the stored response when the key was already used for an identical, finished request.
+	// A key used with another hash returns ErrIdempotencyConflict, a key whose request is still
+	// being processed ErrIdempotencyInProgress.
+	ReserveIdempotencyKey(ctx context.Context, key, hash string, expiredBefore time.Time) ([]byte, error)
+	// CompleteIdempotencyKey stores the response to the request that claimed the key.
+	CompleteIdempotencyKey(ctx context.Context, key string, commandID int, response []byte) error
+	// ReleaseIdempotencyKey forgets a key, so that its request can be retried.
+	ReleaseIdempotencyKey(ctx context.Context, key string) error
+}
@@ -0,0 +1,79 @@
+package storage
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"github.com/17HIERARCH70/BashAPI/internal/domain/models"
+	"time"
+)
+
+// DefaultListLimit is the page size of the command list when the query sets none.
+const DefaultListLimit = 100
+
+// SearchStore finds commands by the text of their script and of their indexed output.
+type SearchStore interface {
+	// SearchCommands returns one page of the commands whose script or indexed output matches
+	// query.Text, after applying the defaults of the query in place, with the layouts of their
+	// outputs and the cursor of the next page.
+	SearchCommands(ctx context.Context, query *models.SearchQuery) ([]models.SearchResult, []OutputLayout, string, error)
+	// IndexOutput replaces the searchable text of the output of a command.
+	IndexOutput(ctx context.Context, id int, content string) error
+}
+
+// Cursor is the position after the last command of a page. Commands are ordered by the
+// sort column and then by ID, so the pair identifies the position uniquely.
+type Cursor struct {
+	Sort string    `json:"s"`
+	Time time.Time `json:"t,omitempty"`
+	ID   int       `json:"id"`
+}
+
+// EncodeCursor returns the cursor of the page following the last command.
+func EncodeCursor(sort string, last models.Command) string {
+	cursor := Cursor{Sort: sort, ID: last.ID}
+	switch sort {
+	case models.SortByCreatedAt:
+		cursor.Time = last.CreatedAt
+	case models.SortByUpdatedAt:
+		cursor.Time = last.UpdatedAt
+	}
+	data, _ := json.Marshal(cursor)
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+func decodeCursor(value string) (Cursor, error) {
+	var cursor Cursor
+	data, err := base64.RawURLEncoding.DecodeString(value)
+	if err != nil {
+		return cursor, ErrInvalidCursor
+	}
+	if err := json.Unmarshal(data, &cursor); err != nil {
+		return cursor, ErrInvalidCursor
+	}
+	return cursor, nil
+}
+
+// PrepareQuery applies the defaults of a command list query in place and decodes its
+// cursor, nil on the first page. A cursor of another sort order is invalid.
+func PrepareQuery(query *models.CommandQuery) (*Cursor, error) {
+	switch query.Sort {
+	case models.SortByID, models.SortByCreatedAt, models.SortByUpdatedAt:
+	default:
+		query.Sort = models.SortByID
+	}
+	if query.Limit <= 0 {
+		query.Limit = DefaultListLimit
+	}
+	if query.Cursor == "" {
+		return nil, nil
+	}
+	cursor, err := decodeCursor(query.Cursor)
+	if err != nil {
+		return nil, err
+	}
+	if cursor.Sort != query.Sort {
+		return nil, ErrInvalidCursor
+	}
+	return &cursor, nil
+}
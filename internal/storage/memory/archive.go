@@ -0,0 +1,46 @@
+package memory
+
+import (
+	"context"
+	"github.com/17HIERARCH70/BashAPI/internal/domain/models"
+	"github.com/17HIERARCH70/BashAPI/internal/storage"
+	"sort"
+	"time"
+)
+
+func (s *Store) FindArchivable(ctx context.Context, finishedBefore time.Time, limit int) ([]storage.ArchivableOutput, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var outputs []storage.ArchivableOutput
+	for id, r := range s.commands {
+		cmd := r.command
+		switch {
+		case r.layout.Archive != nil,
+			r.layout.Size == 0,
+			cmd.FinishedAt == nil || !cmd.FinishedAt.Before(finishedBefore),
+			cmd.Status == models.StatusWaiting || cmd.Status == models.StatusRunning:
+			continue
+		}
+		outputs = append(outputs, storage.ArchivableOutput{ID: id, FinishedAt: *cmd.FinishedAt, Layout: r.layout})
+	}
+	sort.Slice(outputs, func(i, j int) bool {
+		return outputs[i].FinishedAt.Before(outputs[j].FinishedAt)
+	})
+	if len(outputs) > limit {
+		outputs = outputs[:limit]
+	}
+	return outputs, nil
+}
+
+func (s *Store) SetOutputArchive(ctx context.Context, id int, ref string) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	r, ok := s.commands[id]
+	if !ok || r.layout.Archive != nil {
+		return false, nil
+	}
+	archivedAt := now()
+	r.layout.Archive, r.layout.ArchivedAt = &ref, &archivedAt
+	r.chunks = nil
+	return true, nil
+}
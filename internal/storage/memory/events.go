@@ -0,0 +1,150 @@
+package memory
+
+import (
+	"context"
+	"github.com/17HIERARCH70/BashAPI/internal/domain/models"
+	"github.com/17HIERARCH70/BashAPI/internal/storage"
+	"maps"
+	"sort"
+	"time"
+)
+
+// recordEventLocked appends a status change to the history and wakes up the listeners.
+func (s *Store) recordEventLocked(commandID int, from *string, to, actor, reason string, at time.Time) {
+	s.lastEventID++
+	s.events = append(s.events, models.CommandEvent{
+		ID:        s.lastEventID,
+		CommandID: commandID,
+		From:      clonePointer(from),
+		To:        to,
+		Actor:     actor,
+		Reason:    reason,
+		CreatedAt: at,
+	})
+	for wake := range s.listeners {
+		select {
+		case wake <- struct{}{}:
+		default:
+		}
+	}
+}
+
+// eventsAfterLocked returns the events with a higher ID than after, which are at the end
+// of the history.
+func (s *Store) eventsAfterLocked(after int64) []models.CommandEvent {
+	i := sort.Search(len(s.events), func(i int) bool {
+		return s.events[i].ID > after
+	})
+	return s.events[i:]
+}
+
+// lifecycleEventLocked completes a stored event with the current details of its command.
+func (s *Store) lifecycleEventLocked(event models.CommandEvent) models.LifecycleEvent {
+	cmd := s.commands[event.CommandID].command
+	lifecycle := models.LifecycleEvent{
+		Event:          models.StatusEvents[event.To],
+		EventID:        event.ID,
+		CommandID:      event.CommandID,
+		Status:         event.To,
+		PreviousStatus: clonePointer(event.From),
+		Actor:          event.Actor,
+		Reason:         event.Reason,
+		Queue:          cmd.Queue,
+		Labels:         maps.Clone(cmd.Labels),
+		OccurredAt:     event.CreatedAt,
+	}
+	if models.IsFinished(event.To) {
+		lifecycle.ExitCode = clonePointer(cmd.ExitCode)
+	}
+	return lifecycle
+}
+
+func (s *Store) FetchCommandEvents(ctx context.Context, id int) ([]models.CommandEvent, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.commands[id]; !ok {
+		return nil, storage.ErrNotFound
+	}
+	events := []models.CommandEvent{}
+	for _, event := range s.events {
+		if event.CommandID == id {
+			event.From = clonePointer(event.From)
+			events = append(events, event)
+		}
+	}
+	return events, nil
+}
+
+func (s *Store) FetchLifecycleEvents(ctx context.Context, filter models.EventFilter, limit int) ([]models.LifecycleEvent, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	history := s.eventsAfterLocked(filter.After)
+	if !filter.Since.IsZero() {
+		i := sort.Search(len(s.events), func(i int) bool {
+			return !s.events[i].CreatedAt.Before(filter.Since)
+		})
+		if i < len(s.events)-len(history) {
+			history = s.events[i:]
+		}
+	}
+	events := []models.LifecycleEvent{}
+	for _, event := range history {
+		if len(events) == limit {
+			break
+		}
+		if lifecycle := s.lifecycleEventLocked(event); filter.Matches(lifecycle) {
+			events = append(events, lifecycle)
+		}
+	}
+	return events, nil
+}
+
+func (s *Store) FetchLifecycleEvent(ctx context.Context, id int64) (models.LifecycleEvent, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if events := s.eventsAfterLocked(id - 1); len(events) > 0 && events[0].ID == id {
+		return s.lifecycleEventLocked(events[0]), nil
+	}
+	return models.LifecycleEvent{}, storage.ErrNotFound
+}
+
+// ListenEvents announces the events recorded by this store, which only this process sees.
+func (s *Store) ListenEvents(ctx context.Context, ready func() error, announce func(id int64) error) error {
+	wake := make(chan struct{}, 1)
+	s.mu.Lock()
+	last := s.lastEventID
+	s.listeners[wake] = struct{}{}
+	s.mu.Unlock()
+	defer func() {
+		s.mu.Lock()
+		delete(s.listeners, wake)
+		s.mu.Unlock()
+	}()
+	if err := ready(); err != nil {
+		return err
+	}
+
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-wake:
+		}
+		s.mu.Lock()
+		var ids []int64
+		for _, event := range s.eventsAfterLocked(last) {
+			ids = append(ids, event.ID)
+		}
+		if len(ids) > 0 {
+			last = ids[len(ids)-1]
+		}
+		s.mu.Unlock()
+
+		// Announce without holding the store, listeners read the events back
+		for _, id := range ids {
+			if err := announce(id); err != nil {
+				return err
+			}
+		}
+	}
+}
@@ -0,0 +1,56 @@
+package memory
+
+import (
+	"context"
+	"github.com/17HIERARCH70/BashAPI/internal/storage"
+	"slices"
+	"time"
+)
+
+// idempotencyKey is a reserved idempotency key, response is nil until its request finished.
+type idempotencyKey struct {
+	hash      string
+	commandID int
+	response  []byte
+	createdAt time.Time
+}
+
+func (s *Store) ReserveIdempotencyKey(ctx context.Context, key, hash string, expiredBefore time.Time) ([]byte, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for k, stored := range s.idempotencyKeys {
+		if stored.createdAt.Before(expiredBefore) {
+			delete(s.idempotencyKeys, k)
+		}
+	}
+
+	stored, ok := s.idempotencyKeys[key]
+	if !ok {
+		s.idempotencyKeys[key] = &idempotencyKey{hash: hash, createdAt: now()}
+		return nil, nil
+	}
+	if stored.hash != hash {
+		return nil, storage.ErrIdempotencyConflict
+	}
+	if stored.response == nil {
+		return nil, storage.ErrIdempotencyInProgress
+	}
+	return slices.Clone(stored.response), nil
+}
+
+func (s *Store) CompleteIdempotencyKey(ctx context.Context, key string, commandID int, response []byte) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if stored, ok := s.idempotencyKeys[key]; ok {
+		stored.commandID = commandID
+		stored.response = slices.Clone(response)
+	}
+	return nil
+}
+
+func (s *Store) ReleaseIdempotencyKey(ctx context.Context, key string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.idempotencyKeys, key)
+	return nil
+}
@@ -0,0 +1,75 @@
+package memory
+
+import (
+	"context"
+	"github.com/17HIERARCH70/BashAPI/internal/storage"
+	"slices"
+)
+
+func (s *Store) FetchOutputWriter(ctx context.Context, id int) (storage.OutputWriterState, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	r, ok := s.commands[id]
+	if !ok {
+		return storage.OutputWriterState{}, storage.ErrNotFound
+	}
+	state := storage.OutputWriterState{Layout: r.layout, Limit: r.maxOutput, Policy: r.policy, Kill: r.kill}
+	for _, c := range r.chunks {
+		state.NextSeq = max(state.NextSeq, c.seq+1)
+	}
+	return state, nil
+}
+
+func (s *Store) FetchOutputLayout(ctx context.Context, id int) (storage.OutputLayout, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	r, ok := s.commands[id]
+	if !ok {
+		return storage.OutputLayout{}, storage.ErrNotFound
+	}
+	return r.layout, nil
+}
+
+func (s *Store) AppendOutput(ctx context.Context, id int, c storage.OutputChunk, layout storage.OutputLayout) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	r, ok := s.commands[id]
+	if !ok {
+		return nil
+	}
+	if len(c.Data) > 0 {
+		r.chunks = append(r.chunks, chunk{seq: c.Seq, offset: c.Offset, data: slices.Clone(c.Data)})
+	}
+	r.chunks = slices.DeleteFunc(r.chunks, func(c chunk) bool {
+		return c.offset >= layout.GapStart && c.offset+int64(len(c.data)) <= layout.GapEnd
+	})
+	layout.Archive, layout.ArchivedAt = r.layout.Archive, r.layout.ArchivedAt
+	r.layout = layout
+	r.command.UpdatedAt = now()
+	return nil
+}
+
+// ScanOutput collects the chunks first, so that fn runs without holding the store.
+func (s *Store) ScanOutput(ctx context.Context, id int, from, to int64, backwards bool, fn func(data []byte) bool) error {
+	s.mu.Lock()
+	var chunks [][]byte
+	if r, ok := s.commands[id]; ok {
+		// Chunks are appended in sequence order and never changed
+		for _, c := range r.chunks {
+			if c.offset < to && c.offset+int64(len(c.data)) > from {
+				chunks = append(chunks, storage.TrimChunk(c.data, c.offset, from, to))
+			}
+		}
+	}
+	s.mu.Unlock()
+
+	if backwards {
+		slices.Reverse(chunks)
+	}
+	for _, data := range chunks {
+		if !fn(data) {
+			return nil
+		}
+	}
+	return nil
+}
@@ -0,0 +1,257 @@
+package memory
+
+import (
+	"context"
+	"github.com/17HIERARCH70/BashAPI/internal/domain/models"
+	"github.com/17HIERARCH70/BashAPI/internal/storage"
+	"slices"
+	"sort"
+	"strings"
+	"time"
+)
+
+// entry is the place of a command in the queue.
+type entry struct {
+	queueID   int
+	commandID int
+	position  int
+	expiresAt *time.Time
+}
+
+// nextPositionLocked returns the position of a command joining the back of the queue.
+func (s *Store) nextPositionLocked() int {
+	s.lastPosition++
+	return s.lastPosition
+}
+
+func (s *Store) enqueueLocked(commandID, position int, expiresAt *time.Time) {
+	s.lastQueueID++
+	s.queue[commandID] = &entry{queueID: s.lastQueueID, commandID: commandID, position: position, expiresAt: expiresAt}
+}
+
+// queueOrderLocked returns the queue entries in dispatch order.
+func (s *Store) queueOrderLocked() []*entry {
+	order := make([]*entry, 0, len(s.queue))
+	for _, e := range s.queue {
+		order = append(order, e)
+	}
+	sort.Slice(order, func(i, j int) bool {
+		if order[i].position != order[j].position {
+			return order[i].position < order[j].position
+		}
+		return order[i].queueID < order[j].queueID
+	})
+	return order
+}
+
+func (s *Store) FetchQueue(ctx context.Context) ([]models.Queue, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var queue []models.Queue
+	for _, e := range s.queueOrderLocked() {
+		cmd := s.commands[e.commandID].command
+		queue = append(queue, models.Queue{
+			QueueId:   e.queueID,
+			CommandId: e.commandID,
+			Status:    models.StatusWaiting,
+			Script:    cmd.Script,
+			Weight:    cmd.Weight,
+			Queue:     cmd.Queue,
+			ExpiresAt: clonePointer(e.expiresAt),
+			Paused:    s.pausedLocked(cmd.Queue),
+			BlockedBy: s.blockingCommandLocked(e.commandID),
+		})
+	}
+	return queue, nil
+}
+
+func (s *Store) StartCommand(ctx context.Context, id int, change storage.StatusChange) (storage.Execution, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	transition, err := s.changeStatusLocked(id, change)
+	if err != nil {
+		return storage.Execution{Transition: transition}, err
+	}
+	delete(s.queue, id)
+	r := s.commands[id]
+	return storage.Execution{Transition: transition, Script: r.command.Script, TraceContext: r.traceContext}, nil
+}
+
+func (s *Store) DequeueCommand(ctx context.Context, id int, change storage.StatusChange) (storage.Transition, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	transition, err := s.changeStatusLocked(id, change)
+	if err != nil {
+		return transition, err
+	}
+	delete(s.queue, id)
+	return transition, nil
+}
+
+func (s *Store) RequeueCommand(ctx context.Context, id int, change storage.StatusChange) (storage.Transition, *int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	transition, err := s.changeStatusLocked(id, change)
+	if err != nil {
+		return transition, nil, err
+	}
+	// Go ahead of the first queued command
+	position := 0
+	if order := s.queueOrderLocked(); len(order) > 0 {
+		position = order[0].position - 1
+	}
+	s.enqueueLocked(id, position, nil)
+	return transition, clonePointer(s.commands[id].command.PID), nil
+}
+
+func (s *Store) MoveQueuedCommand(ctx context.Context, id, position int) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.queue[id]; !ok {
+		if _, exists := s.commands[id]; !exists {
+			return 0, storage.ErrNotFound
+		}
+		return 0, storage.ErrNotQueued
+	}
+
+	order := slices.DeleteFunc(s.queueOrderLocked(), func(e *entry) bool {
+		return e.commandID == id
+	})
+	index := min(max(position, 1), len(order)+1) - 1
+	order = slices.Insert(order, index, s.queue[id])
+	for i, e := range order {
+		e.position = i + 1
+	}
+	return index + 1, nil
+}
+
+func (s *Store) CancelQueuedCommands(ctx context.Context, filter models.QueueFilter, change storage.StatusChange) ([]storage.DequeuedCommand, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.dequeueCommandsLocked(func(e *entry) bool {
+		cmd := s.commands[e.commandID].command
+		switch {
+		case len(filter.IDs) > 0 && !slices.Contains(filter.IDs, cmd.ID),
+			filter.Queue != "" && cmd.Queue != filter.Queue,
+			filter.ScriptContains != "" && !strings.Contains(cmd.Script, filter.ScriptContains),
+			filter.CreatedBefore != nil && !cmd.CreatedAt.Before(*filter.CreatedBefore):
+			return false
+		}
+		return filter.Selector.Matches(cmd.Labels)
+	}, change), nil
+}
+
+func (s *Store) ExpireQueuedCommands(ctx context.Context, change storage.StatusChange) ([]storage.DequeuedCommand, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	current := now()
+	return s.dequeueCommandsLocked(func(e *entry) bool {
+		return e.expiresAt != nil && e.expiresAt.Before(current)
+	}, change), nil
+}
+
+// dequeueCommandsLocked removes the selected entries from the queue and moves the waiting
+// commands among them to change.To.
+func (s *Store) dequeueCommandsLocked(selected func(e *entry) bool, change storage.StatusChange) []storage.DequeuedCommand {
+	dequeued := []storage.DequeuedCommand{}
+	change.From = []string{models.StatusWaiting}
+	for _, e := range s.queueOrderLocked() {
+		if !selected(e) {
+			continue
+		}
+		delete(s.queue, e.commandID)
+		if transition, err := s.changeStatusLocked(e.commandID, change); err == nil {
+			dequeued = append(dequeued, storage.DequeuedCommand{ID: e.commandID, Queue: transition.Queue})
+		}
+	}
+	return dequeued
+}
+
+func (s *Store) FindBlockingCommand(ctx context.Context, id int) (*int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.blockingCommandLocked(id), nil
+}
+
+// blockingCommandLocked returns the lowest ID of the running commands holding a lock that
+// conflicts with the locks of the command. Two locks on the same key conflict unless both
+// of them are shared.
+func (s *Store) blockingCommandLocked(id int) *int {
+	wanted := s.commands[id]
+	if wanted == nil || len(wanted.locks) == 0 {
+		return nil
+	}
+	var blockedBy *int
+	for holderID, holder := range s.commands {
+		if holderID == id || holder.command.Status != models.StatusRunning || (blockedBy != nil && holderID > *blockedBy) {
+			continue
+		}
+		if conflicts(wanted.locks, holder.locks) {
+			blocking := holderID
+			blockedBy = &blocking
+		}
+	}
+	return blockedBy
+}
+
+func conflicts(wanted, held []models.CommandLock) bool {
+	for _, w := range wanted {
+		for _, h := range held {
+			if w.Key == h.Key && (w.IsExclusive() || h.IsExclusive()) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func (s *Store) PauseQueue(ctx context.Context, name, reason string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	pause, ok := s.pauses[name]
+	if !ok {
+		pause = models.QueuePause{Queue: name, PausedAt: now()}
+	}
+	pause.Reason = reason
+	s.pauses[name] = pause
+	return nil
+}
+
+func (s *Store) ResumeQueue(ctx context.Context, name string) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	_, ok := s.pauses[name]
+	delete(s.pauses, name)
+	return ok, nil
+}
+
+func (s *Store) FetchQueuePauses(ctx context.Context) ([]models.QueuePause, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	pauses := []models.QueuePause{}
+	for _, pause := range s.pauses {
+		pauses = append(pauses, pause)
+	}
+	sort.Slice(pauses, func(i, j int) bool {
+		return pauses[i].Queue < pauses[j].Queue
+	})
+	return pauses, nil
+}
+
+func (s *Store) FindQueuePause(ctx context.Context, name string) (*models.QueuePause, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, queue := range []string{models.AllQueues, name} {
+		if pause, ok := s.pauses[queue]; ok {
+			return &pause, nil
+		}
+	}
+	return nil, nil
+}
+
+// pausedLocked reports whether the named queue is paused, on its own or with all queues.
+func (s *Store) pausedLocked(name string) bool {
+	_, paused := s.pauses[name]
+	_, all := s.pauses[models.AllQueues]
+	return paused || all
+}
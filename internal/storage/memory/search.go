@@ -0,0 +1,106 @@
+package memory
+
+import (
+	"context"
+	"github.com/17HIERARCH70/BashAPI/internal/domain/models"
+	"github.com/17HIERARCH70/BashAPI/internal/storage"
+	"strings"
+	"unicode"
+)
+
+// snippetContext is the number of characters shown around a highlighted match, like
+// commands.search_snippet does for a substring match.
+const snippetContext = 60
+
+// SearchCommands matches the text as a substring, or each of its words as substrings, of the
+// script or the indexed output, ignoring case. The search syntax of the PostgreSQL store,
+// such as quoted phrases and excluded words, is not supported.
+func (s *Store) SearchCommands(ctx context.Context, query *models.SearchQuery) ([]models.SearchResult, []storage.OutputLayout, string, error) {
+	cursor, err := storage.PrepareQuery(&query.CommandQuery)
+	if err != nil {
+		return nil, nil, "", err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	page, next := s.pageLocked(&query.CommandQuery, cursor, func(r *record) bool {
+		_, inScript := findTerm(r.command.Script, query.Text)
+		_, inOutput := findTerm(r.searchContent, query.Text)
+		return inScript || inOutput
+	})
+	results := []models.SearchResult{}
+	var layouts []storage.OutputLayout
+	for _, r := range page {
+		result := models.SearchResult{Command: r.snapshot()}
+		if term, ok := findTerm(r.command.Script, query.Text); ok {
+			result.ScriptSnippet = snippet(r.command.Script, term)
+		}
+		if term, ok := findTerm(r.searchContent, query.Text); ok {
+			result.OutputSnippet = snippet(r.searchContent, term)
+		}
+		results = append(results, result)
+		layouts = append(layouts, r.layout)
+	}
+	return results, layouts, next, nil
+}
+
+func (s *Store) IndexOutput(ctx context.Context, id int, content string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if r, ok := s.commands[id]; ok {
+		r.searchContent = content
+	}
+	return nil
+}
+
+// findTerm reports whether content contains text, or else every word of text, and returns
+// the term to highlight.
+func findTerm(content, text string) (string, bool) {
+	if indexFold([]rune(content), []rune(text)) >= 0 {
+		return text, true
+	}
+	words := strings.Fields(text)
+	if len(words) < 2 {
+		return "", false
+	}
+	for _, word := range words {
+		if indexFold([]rune(content), []rune(word)) < 0 {
+			return "", false
+		}
+	}
+	return words[0], true
+}
+
+// snippet highlights the first occurrence of term in content with <mark> tags, with the
+// characters around it.
+func snippet(content, term string) string {
+	runes, length := []rune(content), len([]rune(term))
+	pos := indexFold(runes, []rune(term))
+	if pos < 0 {
+		return ""
+	}
+	end := pos + length
+	return string(runes[max(0, pos-snippetContext):pos]) + "<mark>" + string(runes[pos:end]) + "</mark>" +
+		string(runes[end:min(len(runes), end+snippetContext)])
+}
+
+// indexFold returns the position of the first occurrence of term in content ignoring case,
+// -1 when there is none.
+func indexFold(content, term []rune) int {
+	if len(term) == 0 {
+		return -1
+	}
+	for i := 0; i+len(term) <= len(content); i++ {
+		matched := true
+		for j, r := range term {
+			if unicode.ToLower(content[i+j]) != unicode.ToLower(r) {
+				matched = false
+				break
+			}
+		}
+		if matched {
+			return i
+		}
+	}
+	return -1
+}
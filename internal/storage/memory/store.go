@@ -0,0 +1,493 @@
+// Package memory keeps commands, their queue, their events and the deliveries of their
+// events in the process memory. It
+// behaves like the PostgreSQL store for a single process, which makes it suited to the
+// development mode and to tests, but everything is lost when the process exits.
+package memory
+
+import (
+	"context"
+	"fmt"
+	"github.com/17HIERARCH70/BashAPI/internal/domain/models"
+	"github.com/17HIERARCH70/BashAPI/internal/lib/labels"
+	"github.com/17HIERARCH70/BashAPI/internal/lib/metrics"
+	"github.com/17HIERARCH70/BashAPI/internal/storage"
+	"maps"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Store is a CommandStore held in memory. A single mutex guards all of it, so that every
+// method is atomic like a transaction of the PostgreSQL store.
+type Store struct {
+	mu       sync.Mutex
+	commands map[int]*record
+	// queue holds the queued commands in no particular order, see queueOrder.
+	queue     map[int]*entry
+	events    []models.CommandEvent
+	pauses    map[string]models.QueuePause
+	listeners map[chan struct{}]struct{}
+
+	idempotencyKeys map[string]*idempotencyKey
+	webhooks        []models.Webhook
+	deliveries      []*delivery
+	// webhookCursor is the last event fanned out to the webhooks.
+	webhookCursor int64
+	// fanOutMu keeps a fan-out from running concurrently with another one.
+	fanOutMu sync.Mutex
+
+	lastCommandID  int
+	lastQueueID    int
+	lastPosition   int
+	lastEventID    int64
+	lastWebhookID  int
+	lastDeliveryID int64
+}
+
+var _ storage.CommandStore = &Store{}
+
+// NewStore creates an empty store.
+func NewStore() *Store {
+	return &Store{
+		commands:  make(map[int]*record),
+		queue:     make(map[int]*entry),
+		pauses:    make(map[string]models.QueuePause),
+		listeners: make(map[chan struct{}]struct{}),
+
+		idempotencyKeys: make(map[string]*idempotencyKey),
+	}
+}
+
+// record is a stored command with what is not part of models.Command.
+type record struct {
+	command      models.Command
+	layout       storage.OutputLayout
+	traceContext string
+	callbackURL  string
+	maxOutput    int64
+	policy       string
+	kill         bool
+	locks        []models.CommandLock
+	chunks       []chunk
+	// searchContent is the indexed text of the output.
+	searchContent string
+}
+
+// chunk is a stored piece of output.
+type chunk struct {
+	seq    int
+	offset int64
+	data   []byte
+}
+
+// snapshot returns a copy of the command that the caller may change freely.
+func (r *record) snapshot() models.Command {
+	cmd := r.command
+	cmd.PID = clonePointer(cmd.PID)
+	cmd.StartedAt = clonePointer(cmd.StartedAt)
+	cmd.FinishedAt = clonePointer(cmd.FinishedAt)
+	cmd.ExitCode = clonePointer(cmd.ExitCode)
+	cmd.Tags = slices.Clone(cmd.Tags)
+	cmd.Labels = maps.Clone(cmd.Labels)
+	cmd.Annotations = maps.Clone(cmd.Annotations)
+	return cmd
+}
+
+func clonePointer[T any](p *T) *T {
+	if p == nil {
+		return nil
+	}
+	v := *p
+	return &v
+}
+
+// now returns the current time without its monotonic reading, like a time read back from
+// the database.
+func now() time.Time {
+	return time.Now().Round(0)
+}
+
+func (s *Store) Ping(ctx context.Context) error {
+	return nil
+}
+
+func (s *Store) CreateCommand(ctx context.Context, command storage.NewCommand) (int, error) {
+	opts := command.Options
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.lastCommandID++
+	id := s.lastCommandID
+	created := now()
+	s.commands[id] = &record{
+		command: models.Command{
+			ID:          id,
+			Script:      command.Script,
+			Status:      models.StatusWaiting,
+			Weight:      opts.Weight,
+			Queue:       opts.Queue,
+			CreatedAt:   created,
+			UpdatedAt:   created,
+			Submitter:   opts.Submitter,
+			Tags:        nonNil(slices.Clone(opts.Tags)),
+			Labels:      nonNilMap(maps.Clone(opts.Labels)),
+			Annotations: nonNilMap(maps.Clone(opts.Annotations)),
+		},
+		traceContext: command.TraceContext,
+		callbackURL:  opts.CallbackURL,
+		maxOutput:    opts.MaxOutput,
+		policy:       opts.OutputPolicy,
+		kill:         opts.KillOnOutputLimit,
+		locks:        slices.Clone(opts.Locks),
+	}
+	s.recordEventLocked(id, nil, models.StatusWaiting, storage.ActorAPI, "created", created)
+	s.enqueueLocked(id, s.nextPositionLocked(), clonePointer(command.ExpiresAt))
+	return id, nil
+}
+
+func nonNil[T any](values []T) []T {
+	if values == nil {
+		return []T{}
+	}
+	return values
+}
+
+func nonNilMap(values map[string]string) map[string]string {
+	if values == nil {
+		return map[string]string{}
+	}
+	return values
+}
+
+func (s *Store) FetchCommand(ctx context.Context, id int) (models.Command, storage.OutputLayout, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	r, ok := s.commands[id]
+	if !ok {
+		return models.Command{}, storage.OutputLayout{}, storage.ErrNotFound
+	}
+	return r.snapshot(), r.layout, nil
+}
+
+func (s *Store) FetchCommands(ctx context.Context, query *models.CommandQuery) ([]models.Command, []storage.OutputLayout, string, error) {
+	cursor, err := storage.PrepareQuery(query)
+	if err != nil {
+		return nil, nil, "", err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	page, next := s.pageLocked(query, cursor, func(r *record) bool { return true })
+	commands := []models.Command{}
+	var layouts []storage.OutputLayout
+	for _, r := range page {
+		commands = append(commands, r.snapshot())
+		layouts = append(layouts, r.layout)
+	}
+	return commands, layouts, next, nil
+}
+
+// pageLocked returns one page of the commands matching a prepared query and match, and the
+// cursor of the next page.
+func (s *Store) pageLocked(query *models.CommandQuery, cursor *storage.Cursor, match func(r *record) bool) ([]*record, string) {
+	var matched []*record
+	for _, r := range s.commands {
+		if matchesQuery(r.command, query) && match(r) {
+			matched = append(matched, r)
+		}
+	}
+	// before orders the commands as requested, ties broken by ID
+	before := func(a, b models.Command) bool {
+		ta, tb := sortTime(a, query.Sort), sortTime(b, query.Sort)
+		if !ta.Equal(tb) {
+			return ta.Before(tb) != query.Desc
+		}
+		return a.ID != b.ID && (a.ID < b.ID) != query.Desc
+	}
+	sort.Slice(matched, func(i, j int) bool {
+		return before(matched[i].command, matched[j].command)
+	})
+	if cursor != nil {
+		last := models.Command{ID: cursor.ID, CreatedAt: cursor.Time, UpdatedAt: cursor.Time}
+		matched = slices.DeleteFunc(matched, func(r *record) bool {
+			return !before(last, r.command)
+		})
+	}
+
+	if len(matched) > query.Limit {
+		return matched[:query.Limit], storage.EncodeCursor(query.Sort, matched[query.Limit-1].command)
+	}
+	return matched, ""
+}
+
+// sortTime returns the time the command list is sorted by, the zero time when it is sorted by ID.
+func sortTime(cmd models.Command, sort string) time.Time {
+	switch sort {
+	case models.SortByCreatedAt:
+		return cmd.CreatedAt
+	case models.SortByUpdatedAt:
+		return cmd.UpdatedAt
+	}
+	return time.Time{}
+}
+
+func matchesQuery(cmd models.Command, query *models.CommandQuery) bool {
+	switch {
+	case len(query.Statuses) > 0 && !slices.Contains(query.Statuses, cmd.Status),
+		query.CreatedAfter != nil && cmd.CreatedAt.Before(*query.CreatedAfter),
+		query.CreatedBefore != nil && !cmd.CreatedAt.Before(*query.CreatedBefore),
+		query.UpdatedAfter != nil && cmd.UpdatedAt.Before(*query.UpdatedAfter),
+		query.UpdatedBefore != nil && !cmd.UpdatedAt.Before(*query.UpdatedBefore),
+		query.Submitter != "" && cmd.Submitter != query.Submitter,
+		query.ScriptContains != "" && !strings.Contains(cmd.Script, query.ScriptContains),
+		query.ExitCode != nil && (cmd.ExitCode == nil || *cmd.ExitCode != *query.ExitCode):
+		return false
+	}
+	for _, tag := range query.Tags {
+		if !slices.Contains(cmd.Tags, tag) {
+			return false
+		}
+	}
+	return query.Labels.Matches(cmd.Labels)
+}
+
+func (s *Store) MatchCommands(ctx context.Context, statuses []string, selector labels.Selector) ([]int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var matched []int
+	for id, r := range s.commands {
+		if slices.Contains(statuses, r.command.Status) && selector.Matches(r.command.Labels) {
+			matched = append(matched, id)
+		}
+	}
+	slices.Sort(matched)
+	return matched, nil
+}
+
+func (s *Store) FetchRunningCommands(ctx context.Context) ([]models.Command, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var running []models.Command
+	for _, r := range s.commands {
+		if r.command.Status == models.StatusRunning {
+			running = append(running, r.snapshot())
+		}
+	}
+	sort.Slice(running, func(i, j int) bool {
+		return running[i].ID < running[j].ID
+	})
+	return running, nil
+}
+
+func (s *Store) ChangeStatus(ctx context.Context, id int, change storage.StatusChange) (storage.Transition, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.changeStatusLocked(id, change)
+}
+
+// changeStatusLocked applies a status change like the change statement of the PostgreSQL
+// store: the start time is set when the command starts running and the finish time when
+// it reaches any other status than waiting.
+func (s *Store) changeStatusLocked(id int, change storage.StatusChange) (storage.Transition, error) {
+	r, ok := s.commands[id]
+	if !ok {
+		return storage.Transition{}, storage.ErrNotFound
+	}
+	cmd := &r.command
+	from := cmd.Status
+	if !slices.Contains(change.From, from) {
+		return storage.Transition{From: from}, fmt.Errorf("%w: %s -> %s", storage.ErrInvalidTransition, from, change.To)
+	}
+
+	changed := now()
+	cmd.Status = change.To
+	if change.To == models.StatusRunning {
+		cmd.StartedAt = &changed
+	}
+	cmd.FinishedAt = nil
+	if change.To != models.StatusWaiting && change.To != models.StatusRunning {
+		finished := changed
+		cmd.FinishedAt = &finished
+	}
+	cmd.ExitCode = clonePointer(change.ExitCode)
+	cmd.UpdatedAt = changed
+	s.recordEventLocked(id, &from, change.To, change.Actor, change.Reason, changed)
+
+	return storage.Transition{
+		From:       from,
+		Queue:      cmd.Queue,
+		CreatedAt:  cmd.CreatedAt,
+		StartedAt:  clonePointer(cmd.StartedAt),
+		FinishedAt: clonePointer(cmd.FinishedAt),
+	}, nil
+}
+
+func (s *Store) SetPID(ctx context.Context, id, pid int) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if r, ok := s.commands[id]; ok {
+		r.command.PID = &pid
+		r.command.UpdatedAt = now()
+	}
+	return nil
+}
+
+func (s *Store) UpdateLabels(ctx context.Context, id int, update func(models.CommandLabels) (models.CommandLabels, error)) (models.CommandLabels, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	r, ok := s.commands[id]
+	if !ok {
+		return models.CommandLabels{}, storage.ErrNotFound
+	}
+	result, err := update(models.CommandLabels{
+		Labels:      maps.Clone(r.command.Labels),
+		Annotations: maps.Clone(r.command.Annotations),
+	})
+	if err != nil {
+		return models.CommandLabels{}, err
+	}
+	r.command.Labels = nonNilMap(maps.Clone(result.Labels))
+	r.command.Annotations = nonNilMap(maps.Clone(result.Annotations))
+	r.command.UpdatedAt = now()
+	return result, nil
+}
+
+// AverageDuration looks at the same number of recent commands as the PostgreSQL store.
+func (s *Store) AverageDuration(ctx context.Context, script string) (time.Duration, bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var completed []*record
+	for _, r := range s.commands {
+		cmd := r.command
+		if cmd.Status == models.StatusCompleted && cmd.StartedAt != nil && cmd.FinishedAt != nil {
+			completed = append(completed, r)
+		}
+	}
+	sort.Slice(completed, func(i, j int) bool {
+		return completed[i].command.ID > completed[j].command.ID
+	})
+
+	program := strings.Split(script, " ")[0]
+	tiers := []struct {
+		limit int
+		match func(cmd models.Command) bool
+	}{
+		{20, func(cmd models.Command) bool { return cmd.Script == script }},
+		{20, func(cmd models.Command) bool { return strings.Split(cmd.Script, " ")[0] == program }},
+		{100, func(cmd models.Command) bool { return true }},
+	}
+	for _, tier := range tiers {
+		var total time.Duration
+		count := 0
+		for _, r := range completed {
+			if count == tier.limit {
+				break
+			}
+			if tier.match(r.command) {
+				total += r.command.FinishedAt.Sub(*r.command.StartedAt)
+				count++
+			}
+		}
+		if count > 0 {
+			return total / time.Duration(count), true, nil
+		}
+	}
+	return 0, false, nil
+}
+
+func (s *Store) FindPurgeable(ctx context.Context, filter models.PurgeFilter) ([]int, int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var matched []*record
+	for _, r := range s.commands {
+		cmd := r.command
+		switch {
+		case cmd.Status == models.StatusRunning,
+			!slices.Contains(filter.Statuses, cmd.Status),
+			len(filter.IDs) > 0 && !slices.Contains(filter.IDs, cmd.ID),
+			filter.Queue != "" && cmd.Queue != filter.Queue,
+			filter.FinishedBefore != nil && (cmd.FinishedAt == nil || !cmd.FinishedAt.Before(*filter.FinishedBefore)),
+			!filter.Selector.Matches(cmd.Labels):
+			continue
+		}
+		matched = append(matched, r)
+	}
+
+	// Spare the newest filter.KeepLast commands of every queue
+	sort.Slice(matched, func(i, j int) bool {
+		return matched[i].command.ID > matched[j].command.ID
+	})
+	kept := make(map[string]int)
+	ids := []int{}
+	archived := 0
+	for _, r := range matched {
+		if kept[r.command.Queue] < filter.KeepLast {
+			kept[r.command.Queue]++
+			continue
+		}
+		ids = append(ids, r.command.ID)
+		if r.layout.Archive != nil {
+			archived++
+		}
+	}
+	slices.Sort(ids)
+	return ids, archived, nil
+}
+
+func (s *Store) DeleteCommands(ctx context.Context, ids []int) ([]int, []string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	deleted := []int{}
+	var archives []string
+	for _, id := range ids {
+		r, ok := s.commands[id]
+		if !ok || r.command.Status == models.StatusRunning {
+			continue
+		}
+		delete(s.commands, id)
+		delete(s.queue, id)
+		deleted = append(deleted, id)
+		if r.layout.Archive != nil {
+			archives = append(archives, *r.layout.Archive)
+		}
+	}
+	// The events, deliveries and idempotency keys go with their commands
+	s.events = slices.DeleteFunc(s.events, func(event models.CommandEvent) bool {
+		_, ok := s.commands[event.CommandID]
+		return !ok
+	})
+	s.deliveries = slices.DeleteFunc(s.deliveries, func(d *delivery) bool {
+		_, ok := s.commands[d.CommandID]
+		return !ok
+	})
+	for key, stored := range s.idempotencyKeys {
+		if _, ok := s.commands[stored.commandID]; stored.commandID != 0 && !ok {
+			delete(s.idempotencyKeys, key)
+		}
+	}
+	return deleted, archives, nil
+}
+
+func (s *Store) CommandStats(ctx context.Context) (metrics.CommandStats, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var stats metrics.CommandStats
+	for _, r := range s.commands {
+		switch r.command.Status {
+		case models.StatusRunning:
+			stats.Running++
+			stats.UsedSlots += r.command.Weight
+		case models.StatusWaiting:
+			stats.Queued++
+			if s.pausedLocked(r.command.Queue) {
+				stats.Paused++
+			}
+		}
+	}
+	return stats, nil
+}
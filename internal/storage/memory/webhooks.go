@@ -0,0 +1,186 @@
+package memory
+
+import (
+	"context"
+	"github.com/17HIERARCH70/BashAPI/internal/domain/models"
+	"github.com/17HIERARCH70/BashAPI/internal/storage"
+	"slices"
+	"sort"
+	"time"
+)
+
+// delivery is a stored delivery with what is not part of models.WebhookDelivery.
+type delivery struct {
+	models.WebhookDelivery
+	payload     string
+	nextAttempt time.Time
+}
+
+func (s *Store) CreateWebhook(ctx context.Context, hook models.Webhook) (models.Webhook, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.lastWebhookID++
+	hook.ID = s.lastWebhookID
+	hook.Events = slices.Clone(hook.Events)
+	hook.CreatedAt = now()
+	s.webhooks = append(s.webhooks, hook)
+	return hook, nil
+}
+
+func (s *Store) FetchWebhooks(ctx context.Context) ([]models.Webhook, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	webhooks := []models.Webhook{}
+	for _, hook := range s.webhooks {
+		hook.Events = slices.Clone(hook.Events)
+		webhooks = append(webhooks, hook)
+	}
+	return webhooks, nil
+}
+
+func (s *Store) DeleteWebhook(ctx context.Context, id int) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	index := slices.IndexFunc(s.webhooks, func(hook models.Webhook) bool { return hook.ID == id })
+	if index < 0 {
+		return storage.ErrWebhookNotFound
+	}
+	s.webhooks = slices.Delete(s.webhooks, index, index+1)
+	s.deliveries = slices.DeleteFunc(s.deliveries, func(d *delivery) bool {
+		return d.WebhookID != nil && *d.WebhookID == id
+	})
+	return nil
+}
+
+// FanOutEvents runs fanOut without holding the store, fanOutMu keeps fan-outs apart.
+func (s *Store) FanOutEvents(ctx context.Context, settledBefore time.Time, limit int, fanOut func(events []storage.PendingEvent) ([]storage.NewDelivery, error)) error {
+	s.fanOutMu.Lock()
+	defer s.fanOutMu.Unlock()
+
+	s.mu.Lock()
+	var events []storage.PendingEvent
+	for _, event := range s.eventsAfterLocked(s.webhookCursor) {
+		if len(events) == limit || !event.CreatedAt.Before(settledBefore) {
+			break
+		}
+		events = append(events, storage.PendingEvent{
+			Event:       s.lifecycleEventLocked(event),
+			CallbackURL: s.commands[event.CommandID].callbackURL,
+		})
+	}
+	s.mu.Unlock()
+	if len(events) == 0 {
+		return nil
+	}
+
+	deliveries, err := fanOut(events)
+	if err != nil {
+		return err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	created := now()
+	for _, d := range deliveries {
+		s.lastDeliveryID++
+		s.deliveries = append(s.deliveries, &delivery{
+			WebhookDelivery: models.WebhookDelivery{
+				ID:         s.lastDeliveryID,
+				WebhookID:  clonePointer(d.WebhookID),
+				CommandID:  d.Event.CommandID,
+				Event:      d.Event.Event,
+				URL:        d.URL,
+				Status:     models.DeliveryPending,
+				CreatedAt:  created,
+				AttemptLog: []models.WebhookAttempt{},
+			},
+			payload:     string(d.Payload),
+			nextAttempt: created,
+		})
+	}
+	s.webhookCursor = events[len(events)-1].Event.EventID
+	return nil
+}
+
+func (s *Store) ClaimDeliveries(ctx context.Context, limit int, lease time.Duration) ([]storage.DueDelivery, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	claimed := now()
+	var due []*delivery
+	for _, d := range s.deliveries {
+		if d.Status == models.DeliveryPending && !d.nextAttempt.After(claimed) {
+			due = append(due, d)
+		}
+	}
+	sort.SliceStable(due, func(i, j int) bool {
+		return due[i].nextAttempt.Before(due[j].nextAttempt)
+	})
+	if len(due) > limit {
+		due = due[:limit]
+	}
+
+	var claims []storage.DueDelivery
+	for _, d := range due {
+		d.nextAttempt = claimed.Add(lease)
+		claim := storage.DueDelivery{ID: d.ID, URL: d.URL, Event: d.Event, Payload: d.payload, Attempts: d.Attempts}
+		if d.WebhookID != nil {
+			if index := slices.IndexFunc(s.webhooks, func(hook models.Webhook) bool { return hook.ID == *d.WebhookID }); index >= 0 {
+				claim.Secret = s.webhooks[index].Secret
+			}
+		}
+		claims = append(claims, claim)
+	}
+	return claims, nil
+}
+
+func (s *Store) RecordAttempt(ctx context.Context, a storage.DeliveryAttempt) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	index := slices.IndexFunc(s.deliveries, func(d *delivery) bool { return d.ID == a.DeliveryID })
+	if index < 0 {
+		return nil
+	}
+	d := s.deliveries[index]
+	recorded := now()
+	d.AttemptLog = append(d.AttemptLog, models.WebhookAttempt{Attempt: a.Attempt, StatusCode: clonePointer(a.StatusCode),
+		Error: a.Error, DurationMs: int(a.Duration.Milliseconds()), CreatedAt: recorded})
+	d.Attempts = a.Attempt
+	d.Status = a.Status
+	d.LastStatusCode = clonePointer(a.StatusCode)
+	d.LastError = a.Error
+	if a.Status == models.DeliveryPending {
+		d.nextAttempt = a.NextAttemptAt
+	}
+	d.DeliveredAt = nil
+	if a.Status == models.DeliveryDelivered {
+		d.DeliveredAt = &recorded
+	}
+	return nil
+}
+
+func (s *Store) FetchDeliveries(ctx context.Context, query models.DeliveryQuery) ([]models.WebhookDelivery, error) {
+	if query.Limit <= 0 {
+		query.Limit = storage.DefaultListLimit
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	deliveries := []models.WebhookDelivery{}
+	// Deliveries are appended in ID order
+	for i := len(s.deliveries) - 1; i >= 0 && len(deliveries) < query.Limit; i-- {
+		d := s.deliveries[i]
+		if query.CommandID != nil && d.CommandID != *query.CommandID ||
+			query.WebhookID != nil && (d.WebhookID == nil || *d.WebhookID != *query.WebhookID) {
+			continue
+		}
+		listed := d.WebhookDelivery
+		listed.WebhookID = clonePointer(d.WebhookID)
+		listed.LastStatusCode = clonePointer(d.LastStatusCode)
+		listed.DeliveredAt = clonePointer(d.DeliveredAt)
+		listed.AttemptLog = slices.Clone(d.AttemptLog)
+		if d.Status == models.DeliveryPending {
+			nextAttempt := d.nextAttempt
+			listed.NextAttemptAt = &nextAttempt
+		}
+		deliveries = append(deliveries, listed)
+	}
+	return deliveries, nil
+}
@@ -0,0 +1,53 @@
+package postgresql
+
+import (
+	"context"
+	"github.com/17HIERARCH70/BashAPI/internal/storage"
+	"time"
+)
+
+func (s *Store) FindArchivable(ctx context.Context, finishedBefore time.Time, limit int) ([]storage.ArchivableOutput, error) {
+	rows, err := s.DB.Query(ctx,
+		`SELECT id, finished_at, `+OutputLayoutColumns+` FROM commands.commands
+		WHERE output_archive IS NULL AND finished_at < $1 AND output_size > 0 AND status NOT IN ('waiting', 'running')
+		ORDER BY finished_at LIMIT $2`,
+		finishedBefore, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var outputs []storage.ArchivableOutput
+	for rows.Next() {
+		var o storage.ArchivableOutput
+		if err := rows.Scan(append([]interface{}{&o.ID, &o.FinishedAt}, OutputLayoutTargets(&o.Layout)...)...); err != nil {
+			return nil, err
+		}
+		outputs = append(outputs, o)
+	}
+	return outputs, rows.Err()
+}
+
+func (s *Store) SetOutputArchive(ctx context.Context, id int, ref string) (bool, error) {
+	tx, err := s.DB.Begin(ctx)
+	if err != nil {
+		return false, err
+	}
+	defer func() {
+		_ = tx.Rollback(ctx)
+	}()
+
+	tag, err := tx.Exec(ctx,
+		"UPDATE commands.commands SET output_archive = $2, output_archived_at = NOW() WHERE id = $1 AND output_archive IS NULL",
+		id, ref)
+	if err != nil {
+		return false, err
+	}
+	if tag.RowsAffected() == 0 {
+		return false, nil
+	}
+	if _, err := tx.Exec(ctx, "DELETE FROM commands.command_output_chunks WHERE command_id = $1", id); err != nil {
+		return false, err
+	}
+	return true, tx.Commit(ctx)
+}
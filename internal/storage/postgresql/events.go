@@ -0,0 +1,145 @@
+package postgresql
+
+import (
+	"context"
+	"errors"
+	"github.com/17HIERARCH70/BashAPI/internal/domain/models"
+	"github.com/17HIERARCH70/BashAPI/internal/storage"
+	"github.com/jackc/pgx/v4"
+	"slices"
+	"strconv"
+)
+
+// eventChannel is the PostgreSQL channel command events are announced on.
+const eventChannel = "command_events"
+
+// LifecycleEventColumns are the columns scanned by ScanLifecycleEvent, from command_events e
+// joined with commands c.
+const LifecycleEventColumns = `e.id, e.command_id, e.from_status, e.to_status, e.actor, e.reason, e.created_at,
+	c.exit_code, c.queue_name, c.labels`
+
+// ScanLifecycleEvent scans a row of LifecycleEventColumns followed by the extra columns.
+func ScanLifecycleEvent(row RowScanner, extra ...interface{}) (models.LifecycleEvent, error) {
+	var event models.LifecycleEvent
+	var exitCode *int
+	targets := []interface{}{&event.EventID, &event.CommandID, &event.PreviousStatus, &event.Status, &event.Actor,
+		&event.Reason, &event.OccurredAt, &exitCode, &event.Queue, &event.Labels}
+	if err := row.Scan(append(targets, extra...)...); err != nil {
+		return event, err
+	}
+	event.Event = models.StatusEvents[event.Status]
+	if models.IsFinished(event.Status) {
+		event.ExitCode = exitCode
+	}
+	return event, nil
+}
+
+func (s *Store) FetchCommandEvents(ctx context.Context, id int) ([]models.CommandEvent, error) {
+	var exists bool
+	if err := s.DB.QueryRow(ctx, "SELECT EXISTS (SELECT 1 FROM commands.commands WHERE id = $1)", id).Scan(&exists); err != nil {
+		return nil, err
+	}
+	if !exists {
+		return nil, storage.ErrNotFound
+	}
+
+	rows, err := s.DB.Query(ctx,
+		`SELECT id, command_id, from_status, to_status, actor, reason, created_at
+		FROM commands.command_events WHERE command_id = $1 ORDER BY id`, id)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	events := []models.CommandEvent{}
+	for rows.Next() {
+		var event models.CommandEvent
+		if err := rows.Scan(&event.ID, &event.CommandID, &event.From, &event.To, &event.Actor, &event.Reason, &event.CreatedAt); err != nil {
+			return nil, err
+		}
+		events = append(events, event)
+	}
+	return events, rows.Err()
+}
+
+func (s *Store) FetchLifecycleEvents(ctx context.Context, filter models.EventFilter, limit int) ([]models.LifecycleEvent, error) {
+	var where Where
+	if filter.Since.IsZero() {
+		where.Add("e.id > ?", filter.After)
+	} else {
+		where.Add("(e.id > ? OR e.created_at >= ?)", filter.After, filter.Since)
+	}
+	if filter.Namespace != "" {
+		where.Add("c.queue_name = ?", filter.Namespace)
+	}
+	if len(filter.Events) > 0 {
+		var statuses []string
+		for status, event := range models.StatusEvents {
+			if slices.Contains(filter.Events, event) {
+				statuses = append(statuses, status)
+			}
+		}
+		where.Add("e.to_status = ANY(?)", statuses)
+	}
+	where.AddSelector("c.labels", filter.Selector)
+
+	rows, err := s.DB.Query(ctx,
+		"SELECT "+LifecycleEventColumns+` FROM commands.command_events e JOIN commands.commands c ON c.id = e.command_id
+		WHERE `+where.String()+" ORDER BY e.id LIMIT "+where.Param(limit),
+		where.Args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	events := []models.LifecycleEvent{}
+	for rows.Next() {
+		event, err := ScanLifecycleEvent(rows)
+		if err != nil {
+			return nil, err
+		}
+		events = append(events, event)
+	}
+	return events, rows.Err()
+}
+
+func (s *Store) FetchLifecycleEvent(ctx context.Context, id int64) (models.LifecycleEvent, error) {
+	event, err := ScanLifecycleEvent(s.DB.QueryRow(ctx,
+		"SELECT "+LifecycleEventColumns+" FROM commands.command_events e JOIN commands.commands c ON c.id = e.command_id WHERE e.id = $1",
+		id))
+	if errors.Is(err, pgx.ErrNoRows) {
+		return event, storage.ErrNotFound
+	}
+	return event, err
+}
+
+// ListenEvents listens to the announcements of the events trigger on a dedicated connection,
+// which receives the events recorded by every replica.
+func (s *Store) ListenEvents(ctx context.Context, ready func() error, announce func(id int64) error) error {
+	conn, err := s.DB.Acquire(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Release()
+	if _, err := conn.Exec(ctx, "LISTEN "+eventChannel); err != nil {
+		return err
+	}
+	if err := ready(); err != nil {
+		return err
+	}
+
+	for {
+		notification, err := conn.Conn().WaitForNotification(ctx)
+		if err != nil {
+			return err
+		}
+		// Payloads come from the trigger only, anything else is skipped
+		id, err := strconv.ParseInt(notification.Payload, 10, 64)
+		if err != nil {
+			continue
+		}
+		if err := announce(id); err != nil {
+			return err
+		}
+	}
+}
@@ -0,0 +1,58 @@
+package postgresql
+
+import (
+	"context"
+	"errors"
+	"github.com/17HIERARCH70/BashAPI/internal/storage"
+	"github.com/jackc/pgx/v4"
+	"time"
+)
+
+func (s *Store) ReserveIdempotencyKey(ctx context.Context, key, hash string, expiredBefore time.Time) ([]byte, error) {
+	// Keys outside the retention window are forgotten
+	if _, err := s.DB.Exec(ctx, "DELETE FROM commands.idempotency_keys WHERE created_at < $1", expiredBefore); err != nil {
+		return nil, err
+	}
+
+	tag, err := s.DB.Exec(ctx,
+		"INSERT INTO commands.idempotency_keys (idempotency_key, request_hash) VALUES ($1, $2) ON CONFLICT (idempotency_key) DO NOTHING",
+		key, hash)
+	if err != nil {
+		return nil, err
+	}
+	if tag.RowsAffected() == 1 {
+		return nil, nil
+	}
+
+	var storedHash string
+	var response []byte
+	err = s.DB.QueryRow(ctx,
+		"SELECT request_hash, response FROM commands.idempotency_keys WHERE idempotency_key = $1",
+		key).Scan(&storedHash, &response)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			// The concurrent owner of the key has just released it
+			return nil, storage.ErrIdempotencyInProgress
+		}
+		return nil, err
+	}
+	if storedHash != hash {
+		return nil, storage.ErrIdempotencyConflict
+	}
+	if response == nil {
+		return nil, storage.ErrIdempotencyInProgress
+	}
+	return response, nil
+}
+
+func (s *Store) CompleteIdempotencyKey(ctx context.Context, key string, commandID int, response []byte) error {
+	_, err := s.DB.Exec(ctx,
+		"UPDATE commands.idempotency_keys SET response = $1, command_id = $2 WHERE idempotency_key = $3",
+		response, commandID, key)
+	return err
+}
+
+func (s *Store) ReleaseIdempotencyKey(ctx context.Context, key string) error {
+	_, err := s.DB.Exec(ctx, "DELETE FROM commands.idempotency_keys WHERE idempotency_key = $1", key)
+	return err
+}
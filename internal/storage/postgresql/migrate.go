@@ -0,0 +1,282 @@
+package postgresql
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/17HIERARCH70/BashAPI/internal/storage"
+	"github.com/17HIERARCH70/BashAPI/migrations"
+	"github.com/jackc/pgx/v4"
+	"github.com/jackc/pgx/v4/pgxpool"
+	"io/fs"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+var (
+	ErrSchemaDirty   = errors.New("a migration failed and left the schema dirty, fix it by hand and reset the dirty flag in schema_migrations")
+	ErrSchemaBehind  = errors.New("database schema is behind, apply the pending migrations")
+	ErrUnknownSchema = errors.New("database schema version is not among the known migrations")
+)
+
+// NilVersion is the schema version before the first migration.
+const NilVersion = -1
+
+// migrationLockID keys the advisory lock that keeps concurrent servers from migrating at once.
+const migrationLockID = 7_318_465_102
+
+// Migration is a pair of SQL scripts moving the schema to Version and back.
+type Migration struct {
+	Version int64
+	Name    string
+	Up      string
+	Down    string
+}
+
+// SchemaVersion is the version of the latest embedded migration, the one the code relies on.
+var SchemaVersion = mustLatestVersion()
+
+func mustLatestVersion() int64 {
+	list, err := LoadMigrations(migrations.FS)
+	if err != nil {
+		panic("invalid embedded migrations: " + err.Error())
+	}
+	if len(list) == 0 {
+		return NilVersion
+	}
+	return list[len(list)-1].Version
+}
+
+// LoadMigrations reads the migrations named <version>_<name>.up.sql and <version>_<name>.down.sql
+// from fsys, ordered by version. Every migration must have both scripts.
+func LoadMigrations(fsys fs.FS) ([]Migration, error) {
+	files, err := fs.Glob(fsys, "*.sql")
+	if err != nil {
+		return nil, err
+	}
+	byVersion := make(map[int64]*Migration)
+	for _, file := range files {
+		base, direction, ok := cutDirection(file)
+		if !ok {
+			return nil, fmt.Errorf("migration %s: name does not end with .up.sql or .down.sql", file)
+		}
+		prefix, name, _ := strings.Cut(base, "_")
+		version, err := strconv.ParseInt(prefix, 10, 64)
+		if err != nil || version < 0 {
+			return nil, fmt.Errorf("migration %s: name does not start with a version", file)
+		}
+		content, err := fs.ReadFile(fsys, file)
+		if err != nil {
+			return nil, err
+		}
+
+		migration := byVersion[version]
+		if migration == nil {
+			migration = &Migration{Version: version, Name: name}
+			byVersion[version] = migration
+		} else if migration.Name != name {
+			return nil, fmt.Errorf("migration %d: scripts are named %q and %q", version, migration.Name, name)
+		}
+		if direction == "up" {
+			migration.Up = string(content)
+		} else {
+			migration.Down = string(content)
+		}
+	}
+
+	list := make([]Migration, 0, len(byVersion))
+	for _, migration := range byVersion {
+		if migration.Up == "" || migration.Down == "" {
+			return nil, fmt.Errorf("migration %d: both the up and the down script are required", migration.Version)
+		}
+		list = append(list, *migration)
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].Version < list[j].Version })
+	return list, nil
+}
+
+func cutDirection(file string) (string, string, bool) {
+	if base, ok := strings.CutSuffix(file, ".up.sql"); ok {
+		return base, "up", true
+	}
+	if base, ok := strings.CutSuffix(file, ".down.sql"); ok {
+		return base, "down", true
+	}
+	return "", "", false
+}
+
+// ReadSchemaVersion reads the version and the dirty flag recorded in the schema_migrations
+// table of golang-migrate. It returns NilVersion before the first migration.
+func ReadSchemaVersion(ctx context.Context, db *pgxpool.Pool) (int64, bool, error) {
+	conn, err := db.Acquire(ctx)
+	if err != nil {
+		return NilVersion, false, err
+	}
+	defer conn.Release()
+	return readVersion(ctx, conn.Conn())
+}
+
+func readVersion(ctx context.Context, conn *pgx.Conn) (int64, bool, error) {
+	var exists bool
+	if err := conn.QueryRow(ctx, "SELECT to_regclass('schema_migrations') IS NOT NULL").Scan(&exists); err != nil {
+		return NilVersion, false, err
+	}
+	if !exists {
+		return NilVersion, false, nil
+	}
+	var version int64
+	var dirty bool
+	err := conn.QueryRow(ctx, "SELECT version, dirty FROM schema_migrations LIMIT 1").Scan(&version, &dirty)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return NilVersion, false, nil
+	}
+	return version, dirty, err
+}
+
+// VerifySchema checks the version of the schema, see CheckSchemaVersion.
+func VerifySchema(ctx context.Context, db *pgxpool.Pool) error {
+	version, dirty, err := ReadSchemaVersion(ctx, db)
+	if err != nil {
+		return err
+	}
+	return CheckSchemaVersion(version, dirty)
+}
+
+var _ storage.SchemaChecker = &Store{}
+
+// CheckSchema reads the version recorded by the migrations, see CheckSchemaVersion.
+func (s *Store) CheckSchema(ctx context.Context) (string, error) {
+	version, dirty, err := ReadSchemaVersion(ctx, s.DB)
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("version %d, expected %d", version, SchemaVersion), CheckSchemaVersion(version, dirty)
+}
+
+// CheckSchemaVersion checks that the schema is clean and at least at SchemaVersion. A newer
+// schema is accepted, as replicas of the previous release keep serving during a rolling upgrade.
+func CheckSchemaVersion(version int64, dirty bool) error {
+	if dirty {
+		return fmt.Errorf("%w: version %d", ErrSchemaDirty, version)
+	}
+	if version < SchemaVersion {
+		return fmt.Errorf("%w: version %d, expected %d", ErrSchemaBehind, version, SchemaVersion)
+	}
+	return nil
+}
+
+// MigrateUp applies the embedded migrations newer than the schema and returns their versions.
+func MigrateUp(ctx context.Context, db *pgxpool.Pool) ([]int64, error) {
+	list, err := LoadMigrations(migrations.FS)
+	if err != nil {
+		return nil, err
+	}
+	var applied []int64
+	err = withMigrationLock(ctx, db, func(conn *pgx.Conn, version int64) error {
+		for _, migration := range list {
+			if migration.Version <= version {
+				continue
+			}
+			if err := runMigration(ctx, conn, migration.Version, migration.Up); err != nil {
+				return fmt.Errorf("migration %d_%s up: %w", migration.Version, migration.Name, err)
+			}
+			applied = append(applied, migration.Version)
+		}
+		return nil
+	})
+	return applied, err
+}
+
+// MigrateDown rolls back the given number of the latest applied migrations and returns
+// the versions rolled back.
+func MigrateDown(ctx context.Context, db *pgxpool.Pool, steps int) ([]int64, error) {
+	list, err := LoadMigrations(migrations.FS)
+	if err != nil {
+		return nil, err
+	}
+	var reverted []int64
+	err = withMigrationLock(ctx, db, func(conn *pgx.Conn, version int64) error {
+		for ; steps > 0 && version != NilVersion; steps-- {
+			index := sort.Search(len(list), func(i int) bool { return list[i].Version >= version })
+			if index == len(list) || list[index].Version != version {
+				return fmt.Errorf("%w: version %d", ErrUnknownSchema, version)
+			}
+			previous := int64(NilVersion)
+			if index > 0 {
+				previous = list[index-1].Version
+			}
+			if err := runMigration(ctx, conn, previous, list[index].Down); err != nil {
+				return fmt.Errorf("migration %d_%s down: %w", version, list[index].Name, err)
+			}
+			reverted = append(reverted, version)
+			version = previous
+		}
+		return nil
+	})
+	return reverted, err
+}
+
+// withMigrationLock runs migrate on a connection holding the migration lock, with the
+// current schema version. A dirty schema is not migrated.
+func withMigrationLock(ctx context.Context, db *pgxpool.Pool, migrate func(conn *pgx.Conn, version int64) error) error {
+	pooled, err := db.Acquire(ctx)
+	if err != nil {
+		return err
+	}
+	defer pooled.Release()
+	conn := pooled.Conn()
+
+	if _, err := conn.Exec(ctx, "SELECT pg_advisory_lock($1)", migrationLockID); err != nil {
+		return err
+	}
+	defer func() {
+		_, _ = conn.Exec(context.Background(), "SELECT pg_advisory_unlock($1)", migrationLockID)
+	}()
+	if _, err := conn.Exec(ctx,
+		"CREATE TABLE IF NOT EXISTS schema_migrations (version BIGINT NOT NULL PRIMARY KEY, dirty BOOLEAN NOT NULL)"); err != nil {
+		return err
+	}
+
+	version, dirty, err := readVersion(ctx, conn)
+	if err != nil {
+		return err
+	}
+	if dirty {
+		return fmt.Errorf("%w: version %d", ErrSchemaDirty, version)
+	}
+	return migrate(conn, version)
+}
+
+// runMigration runs a script the way golang-migrate does: the target version is recorded as
+// dirty first and only cleared once the script succeeded, so that a failure is noticed.
+func runMigration(ctx context.Context, conn *pgx.Conn, target int64, script string) error {
+	if err := setVersion(ctx, conn, target, true); err != nil {
+		return err
+	}
+	// Without arguments the script runs over the simple protocol, which allows several statements
+	if _, err := conn.Exec(ctx, script); err != nil {
+		return err
+	}
+	return setVersion(ctx, conn, target, false)
+}
+
+// setVersion replaces the only row of schema_migrations. A clean NilVersion leaves it empty.
+func setVersion(ctx context.Context, conn *pgx.Conn, version int64, dirty bool) error {
+	tx, err := conn.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		_ = tx.Rollback(ctx)
+	}()
+	if _, err := tx.Exec(ctx, "TRUNCATE schema_migrations"); err != nil {
+		return err
+	}
+	if version != NilVersion || dirty {
+		if _, err := tx.Exec(ctx, "INSERT INTO schema_migrations (version, dirty) VALUES ($1, $2)", version, dirty); err != nil {
+			return err
+		}
+	}
+	return tx.Commit(ctx)
+}
@@ -0,0 +1,98 @@
+package postgresql
+
+import (
+	"context"
+	"errors"
+	"github.com/17HIERARCH70/BashAPI/internal/storage"
+	"github.com/jackc/pgx/v4"
+	"math"
+)
+
+// outputChunkPage is the number of chunks read per query when scanning an output.
+const outputChunkPage = 64
+
+func (s *Store) FetchOutputWriter(ctx context.Context, id int) (storage.OutputWriterState, error) {
+	var state storage.OutputWriterState
+	l := &state.Layout
+	err := s.DB.QueryRow(ctx,
+		`SELECT COALESCE((SELECT MAX(seq) + 1 FROM commands.command_output_chunks WHERE command_id = $1), 0),
+			output_size, max_output, output_policy, kill_on_output_limit,
+			output_truncated, output_gap_start, output_gap_end, output_dropped
+		FROM commands.commands WHERE id = $1`,
+		id).Scan(&state.NextSeq, &l.Size, &state.Limit, &state.Policy, &state.Kill, &l.Truncated, &l.GapStart, &l.GapEnd, &l.Dropped)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return state, storage.ErrNotFound
+	}
+	return state, err
+}
+
+func (s *Store) FetchOutputLayout(ctx context.Context, id int) (storage.OutputLayout, error) {
+	var layout storage.OutputLayout
+	err := s.DB.QueryRow(ctx, "SELECT "+OutputLayoutColumns+" FROM commands.commands WHERE id = $1", id).
+		Scan(OutputLayoutTargets(&layout)...)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return layout, storage.ErrNotFound
+	}
+	return layout, err
+}
+
+func (s *Store) AppendOutput(ctx context.Context, id int, chunk storage.OutputChunk, layout storage.OutputLayout) error {
+	_, err := s.DB.Exec(ctx,
+		`WITH chunk AS (
+			INSERT INTO commands.command_output_chunks (command_id, seq, byte_offset, data)
+			SELECT $1::integer, $2::integer, $3::bigint, $4::bytea WHERE octet_length($4::bytea) > 0
+		), trimmed AS (
+			DELETE FROM commands.command_output_chunks
+			WHERE command_id = $1 AND byte_offset >= $7 AND byte_offset + octet_length(data) <= $8
+		)
+		UPDATE commands.commands
+		SET output_size = $5, output_truncated = $6, output_gap_start = $7, output_gap_end = $8, output_dropped = $9
+		WHERE id = $1`,
+		id, chunk.Seq, chunk.Offset, chunk.Data, layout.Size, layout.Truncated, layout.GapStart, layout.GapEnd, layout.Dropped)
+	return err
+}
+
+// ScanOutput reads the chunks a page at a time, so that a scan stopping early does not load
+// the whole output.
+func (s *Store) ScanOutput(ctx context.Context, id int, from, to int64, backwards bool, fn func(data []byte) bool) error {
+	query := `SELECT seq, byte_offset, data FROM commands.command_output_chunks
+		WHERE command_id = $1 AND byte_offset < $3 AND byte_offset + octet_length(data) > $2 AND seq > $4
+		ORDER BY seq LIMIT $5`
+	cursor := -1
+	if backwards {
+		query = `SELECT seq, byte_offset, data FROM commands.command_output_chunks
+		WHERE command_id = $1 AND byte_offset < $3 AND byte_offset + octet_length(data) > $2 AND seq < $4
+		ORDER BY seq DESC LIMIT $5`
+		cursor = math.MaxInt32
+	}
+
+	for {
+		rows, err := s.DB.Query(ctx, query, id, from, to, cursor, outputChunkPage)
+		if err != nil {
+			return err
+		}
+		var chunks [][]byte
+		for rows.Next() {
+			var offset int64
+			var data []byte
+			if err := rows.Scan(&cursor, &offset, &data); err != nil {
+				rows.Close()
+				return err
+			}
+			chunks = append(chunks, storage.TrimChunk(data, offset, from, to))
+		}
+		rows.Close()
+		if err := rows.Err(); err != nil {
+			return err
+		}
+
+		for _, data := range chunks {
+			if !fn(data) {
+				return nil
+			}
+		}
+		if len(chunks) < outputChunkPage {
+			return nil
+		}
+	}
+}
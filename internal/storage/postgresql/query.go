@@ -0,0 +1,56 @@
+package postgresql
+
+import (
+	"encoding/json"
+	"github.com/17HIERARCH70/BashAPI/internal/lib/labels"
+	"strconv"
+	"strings"
+)
+
+// Where collects SQL conditions and numbers their placeholders as they are added.
+type Where struct {
+	conditions []string
+	Args       []interface{}
+}
+
+// Add appends a condition in which every "?" stands for the next argument.
+func (w *Where) Add(condition string, args ...interface{}) {
+	for _, arg := range args {
+		w.Args = append(w.Args, arg)
+		condition = strings.Replace(condition, "?", "$"+strconv.Itoa(len(w.Args)), 1)
+	}
+	w.conditions = append(w.conditions, condition)
+}
+
+// String renders the conditions joined with AND, or TRUE when there are none.
+func (w *Where) String() string {
+	if len(w.conditions) == 0 {
+		return "TRUE"
+	}
+	return strings.Join(w.conditions, " AND ")
+}
+
+// Param adds an argument used outside of the conditions and returns its placeholder.
+func (w *Where) Param(arg interface{}) string {
+	w.Args = append(w.Args, arg)
+	return "$" + strconv.Itoa(len(w.Args))
+}
+
+// AddSelector adds the requirements of a label selector on the JSONB column.
+func (w *Where) AddSelector(column string, selector labels.Selector) {
+	for _, req := range selector {
+		switch req.Operator {
+		case labels.Equals, labels.NotEquals:
+			pair, _ := json.Marshal(map[string]string{req.Key: req.Value})
+			condition := column + " @> ?::jsonb"
+			if req.Operator == labels.NotEquals {
+				condition = "NOT " + condition
+			}
+			w.Add(condition, string(pair))
+		case labels.Exists:
+			w.Add(column+" ->> ? IS NOT NULL", req.Key)
+		case labels.NotExists:
+			w.Add(column+" ->> ? IS NULL", req.Key)
+		}
+	}
+}
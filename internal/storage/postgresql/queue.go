@@ -0,0 +1,297 @@
+package postgresql
+
+import (
+	"context"
+	"errors"
+	"github.com/17HIERARCH70/BashAPI/internal/domain/models"
+	"github.com/17HIERARCH70/BashAPI/internal/storage"
+	"github.com/jackc/pgx/v4"
+)
+
+// blockingCommandQuery selects the first running command holding a lock that conflicts
+// with the locks declared by q.command_id. Two locks on the same key conflict unless
+// both of them are shared.
+const blockingCommandQuery = `
+	SELECT held.command_id
+	FROM commands.command_locks wanted
+	JOIN commands.command_locks held ON held.lock_key = wanted.lock_key AND held.command_id <> wanted.command_id
+	JOIN commands.commands holder ON holder.id = held.command_id
+	WHERE wanted.command_id = q.command_id
+	  AND holder.status = 'running'
+	  AND (wanted.exclusive OR held.exclusive)
+	ORDER BY held.command_id
+	LIMIT 1`
+
+func (s *Store) FetchQueue(ctx context.Context) ([]models.Queue, error) {
+	var queue []models.Queue
+	rows, err := s.DB.Query(ctx,
+		"SELECT q.queue_id, q.command_id, q.status, c.script, c.weight, c.queue_name, q.expires_at, "+
+			"EXISTS (SELECT 1 FROM commands.queue_pauses p WHERE p.queue_name IN (c.queue_name, $1)), "+
+			"("+blockingCommandQuery+") "+
+			"FROM commands.queue q JOIN commands.commands c ON c.id = q.command_id ORDER BY q.position, q.queue_id",
+		models.AllQueues)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var q models.Queue
+		if err := rows.Scan(&q.QueueId, &q.CommandId, &q.Status, &q.Script, &q.Weight, &q.Queue, &q.ExpiresAt, &q.Paused, &q.BlockedBy); err != nil {
+			return nil, err
+		}
+		queue = append(queue, q)
+	}
+	return queue, rows.Err()
+}
+
+func (s *Store) StartCommand(ctx context.Context, id int, change storage.StatusChange) (storage.Execution, error) {
+	var execution storage.Execution
+	tx, err := s.DB.Begin(ctx)
+	if err != nil {
+		return execution, err
+	}
+	defer func() {
+		_ = tx.Rollback(ctx)
+	}()
+
+	if _, err := tx.Exec(ctx, "DELETE FROM commands.queue WHERE command_id = $1", id); err != nil {
+		return execution, err
+	}
+	if execution.Transition, err = changeStatus(ctx, tx, id, change); err != nil {
+		return execution, err
+	}
+	if err := tx.QueryRow(ctx, "SELECT script, trace_context FROM commands.commands WHERE id = $1", id).
+		Scan(&execution.Script, &execution.TraceContext); err != nil {
+		return execution, err
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return execution, err
+	}
+	return execution, nil
+}
+
+func (s *Store) DequeueCommand(ctx context.Context, id int, change storage.StatusChange) (storage.Transition, error) {
+	tx, err := s.DB.Begin(ctx)
+	if err != nil {
+		return storage.Transition{}, err
+	}
+	defer func() {
+		_ = tx.Rollback(ctx)
+	}()
+
+	if _, err := tx.Exec(ctx, "DELETE FROM commands.queue WHERE command_id = $1", id); err != nil {
+		return storage.Transition{}, err
+	}
+	transition, err := changeStatus(ctx, tx, id, change)
+	if err != nil {
+		return transition, err
+	}
+	return transition, tx.Commit(ctx)
+}
+
+func (s *Store) RequeueCommand(ctx context.Context, id int, change storage.StatusChange) (storage.Transition, *int, error) {
+	tx, err := s.DB.Begin(ctx)
+	if err != nil {
+		return storage.Transition{}, nil, err
+	}
+	defer func() {
+		_ = tx.Rollback(ctx)
+	}()
+
+	transition, err := changeStatus(ctx, tx, id, change)
+	if err != nil {
+		return transition, nil, err
+	}
+	if _, err := tx.Exec(ctx,
+		"INSERT INTO commands.queue (command_id, status, position) "+
+			"SELECT $1, 'waiting', COALESCE(MIN(position), 1) - 1 FROM commands.queue", id); err != nil {
+		return transition, nil, err
+	}
+	var pid *int
+	if err := tx.QueryRow(ctx, "SELECT pid FROM commands.commands WHERE id = $1", id).Scan(&pid); err != nil {
+		return transition, nil, err
+	}
+	if err := tx.Commit(ctx); err != nil {
+		return transition, nil, err
+	}
+	return transition, pid, nil
+}
+
+// MoveQueuedCommand locks the whole queue and renumbers it, so that concurrent moves do
+// not interleave.
+func (s *Store) MoveQueuedCommand(ctx context.Context, id, position int) (int, error) {
+	tx, err := s.DB.Begin(ctx)
+	if err != nil {
+		return 0, err
+	}
+	defer func() {
+		_ = tx.Rollback(ctx)
+	}()
+
+	rows, err := tx.Query(ctx, "SELECT command_id FROM commands.queue ORDER BY position, queue_id FOR UPDATE")
+	if err != nil {
+		return 0, err
+	}
+	var order []int
+	found := false
+	for rows.Next() {
+		var commandID int
+		if err := rows.Scan(&commandID); err != nil {
+			rows.Close()
+			return 0, err
+		}
+		if commandID == id {
+			found = true
+			continue
+		}
+		order = append(order, commandID)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return 0, err
+	}
+	if !found {
+		return 0, s.notQueuedError(ctx, id)
+	}
+
+	index := min(max(position, 1), len(order)+1) - 1
+	order = append(order[:index], append([]int{id}, order[index:]...)...)
+
+	for i, commandID := range order {
+		if _, err := tx.Exec(ctx, "UPDATE commands.queue SET position = $1 WHERE command_id = $2", i+1, commandID); err != nil {
+			return 0, err
+		}
+	}
+	if err := tx.Commit(ctx); err != nil {
+		return 0, err
+	}
+	return index + 1, nil
+}
+
+// notQueuedError tells a missing command apart from one that is not waiting anymore.
+func (s *Store) notQueuedError(ctx context.Context, id int) error {
+	var exists bool
+	err := s.DB.QueryRow(ctx, "SELECT TRUE FROM commands.commands WHERE id = $1", id).Scan(&exists)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return storage.ErrNotFound
+	}
+	if err != nil {
+		return err
+	}
+	return storage.ErrNotQueued
+}
+
+func (s *Store) CancelQueuedCommands(ctx context.Context, filter models.QueueFilter, change storage.StatusChange) ([]storage.DequeuedCommand, error) {
+	var where Where
+	if len(filter.IDs) > 0 {
+		where.Add("c.id = ANY(?)", filter.IDs)
+	}
+	if filter.Queue != "" {
+		where.Add("c.queue_name = ?", filter.Queue)
+	}
+	if filter.ScriptContains != "" {
+		where.Add("strpos(c.script, ?) > 0", filter.ScriptContains)
+	}
+	if filter.CreatedBefore != nil {
+		where.Add("c.created_at < ?", *filter.CreatedBefore)
+	}
+	where.AddSelector("c.labels", filter.Selector)
+
+	return s.dequeueCommands(ctx,
+		"DELETE FROM commands.queue q USING commands.commands c WHERE c.id = q.command_id AND "+where.String()+" RETURNING q.command_id",
+		where, change)
+}
+
+func (s *Store) ExpireQueuedCommands(ctx context.Context, change storage.StatusChange) ([]storage.DequeuedCommand, error) {
+	return s.dequeueCommands(ctx, "DELETE FROM commands.queue WHERE expires_at < NOW() RETURNING command_id", Where{}, change)
+}
+
+// dequeueCommands deletes the queue entries selected by the delete statement and moves the
+// waiting commands among them to change.To in one statement.
+func (s *Store) dequeueCommands(ctx context.Context, dequeue string, where Where, change storage.StatusChange) ([]storage.DequeuedCommand, error) {
+	to, actor, reason := where.Param(change.To), where.Param(change.Actor), where.Param(change.Reason)
+	rows, err := s.DB.Query(ctx,
+		"WITH dequeued AS ("+dequeue+"), "+
+			"changed AS (UPDATE commands.commands SET status = "+to+"::text, finished_at = NOW() "+
+			"WHERE id IN (SELECT command_id FROM dequeued) AND status = 'waiting' RETURNING id, queue_name), "+
+			"events AS (INSERT INTO commands.command_events (command_id, from_status, to_status, actor, reason) "+
+			"SELECT id, 'waiting', "+to+"::text, "+actor+"::text, "+reason+"::text FROM changed) "+
+			"SELECT id, queue_name FROM changed",
+		where.Args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	dequeued := []storage.DequeuedCommand{}
+	for rows.Next() {
+		var command storage.DequeuedCommand
+		if err := rows.Scan(&command.ID, &command.Queue); err != nil {
+			return nil, err
+		}
+		dequeued = append(dequeued, command)
+	}
+	return dequeued, rows.Err()
+}
+
+func (s *Store) FindBlockingCommand(ctx context.Context, id int) (*int, error) {
+	var blockedBy *int
+	err := s.DB.QueryRow(ctx,
+		"SELECT ("+blockingCommandQuery+") FROM (SELECT $1::INTEGER AS command_id) q",
+		id).Scan(&blockedBy)
+	if err != nil {
+		return nil, err
+	}
+	return blockedBy, nil
+}
+
+func (s *Store) PauseQueue(ctx context.Context, name, reason string) error {
+	_, err := s.DB.Exec(ctx,
+		"INSERT INTO commands.queue_pauses (queue_name, reason) VALUES ($1, $2) "+
+			"ON CONFLICT (queue_name) DO UPDATE SET reason = EXCLUDED.reason",
+		name, reason)
+	return err
+}
+
+func (s *Store) ResumeQueue(ctx context.Context, name string) (bool, error) {
+	tag, err := s.DB.Exec(ctx, "DELETE FROM commands.queue_pauses WHERE queue_name = $1", name)
+	if err != nil {
+		return false, err
+	}
+	return tag.RowsAffected() > 0, nil
+}
+
+func (s *Store) FetchQueuePauses(ctx context.Context) ([]models.QueuePause, error) {
+	pauses := []models.QueuePause{}
+	rows, err := s.DB.Query(ctx, "SELECT queue_name, reason, paused_at FROM commands.queue_pauses ORDER BY queue_name")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var p models.QueuePause
+		if err := rows.Scan(&p.Queue, &p.Reason, &p.PausedAt); err != nil {
+			return nil, err
+		}
+		pauses = append(pauses, p)
+	}
+	return pauses, rows.Err()
+}
+
+func (s *Store) FindQueuePause(ctx context.Context, name string) (*models.QueuePause, error) {
+	var p models.QueuePause
+	err := s.DB.QueryRow(ctx,
+		"SELECT queue_name, reason, paused_at FROM commands.queue_pauses WHERE queue_name IN ($1, $2) "+
+			"ORDER BY queue_name = $2 DESC LIMIT 1",
+		name, models.AllQueues).Scan(&p.Queue, &p.Reason, &p.PausedAt)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &p, nil
+}
@@ -0,0 +1,78 @@
+package postgresql
+
+import (
+	"context"
+	"github.com/17HIERARCH70/BashAPI/internal/domain/models"
+	"github.com/17HIERARCH70/BashAPI/internal/storage"
+	"strings"
+)
+
+// likeEscaper escapes the wildcards of a LIKE pattern.
+var likeEscaper = strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`)
+
+// SearchCommands matches the text by words with the full-text search or as a substring,
+// and highlights the matches with commands.search_snippet.
+func (s *Store) SearchCommands(ctx context.Context, query *models.SearchQuery) ([]models.SearchResult, []storage.OutputLayout, string, error) {
+	cursor, err := storage.PrepareQuery(&query.CommandQuery)
+	if err != nil {
+		return nil, nil, "", err
+	}
+	where, order := CommandListClause(&query.CommandQuery, cursor)
+	term := where.Param(query.Text)
+	pattern := where.Param("%" + likeEscaper.Replace(query.Text) + "%")
+	tsquery := "websearch_to_tsquery('simple', " + term + ")"
+	// Each branch is answered from the full-text and trigram indexes of its table
+	where.Add(`c.id IN (
+		SELECT id FROM commands.commands WHERE to_tsvector('simple', script) @@ ` + tsquery + ` OR script ILIKE ` + pattern + `
+		UNION
+		SELECT command_id FROM commands.command_search WHERE document @@ ` + tsquery + ` OR content ILIKE ` + pattern + `)`)
+	limit := where.Param(query.Limit + 1)
+
+	rows, err := s.DB.Query(ctx,
+		"SELECT "+CommandColumns+", "+
+			"commands.search_snippet(c.script, "+tsquery+", "+term+"), "+
+			"commands.search_snippet(s.content, "+tsquery+", "+term+") "+
+			"FROM commands.commands c LEFT JOIN commands.command_search s ON s.command_id = c.id "+
+			"WHERE "+where.String()+" ORDER BY "+order+" LIMIT "+limit,
+		where.Args...)
+	if err != nil {
+		return nil, nil, "", err
+	}
+	defer rows.Close()
+
+	results := []models.SearchResult{}
+	var layouts []storage.OutputLayout
+	for rows.Next() {
+		var result models.SearchResult
+		var outputSnippet *string
+		cmd, layout, err := ScanCommand(rows, &result.ScriptSnippet, &outputSnippet)
+		if err != nil {
+			return nil, nil, "", err
+		}
+		result.Command = cmd
+		if outputSnippet != nil {
+			result.OutputSnippet = *outputSnippet
+		}
+		results = append(results, result)
+		layouts = append(layouts, layout)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, nil, "", err
+	}
+
+	// The extra row only tells that there is a next page
+	var next string
+	if len(results) > query.Limit {
+		results, layouts = results[:query.Limit], layouts[:query.Limit]
+		next = storage.EncodeCursor(query.Sort, results[query.Limit-1].Command)
+	}
+	return results, layouts, next, nil
+}
+
+func (s *Store) IndexOutput(ctx context.Context, id int, content string) error {
+	_, err := s.DB.Exec(ctx,
+		`INSERT INTO commands.command_search (command_id, content) VALUES ($1, $2)
+		ON CONFLICT (command_id) DO UPDATE SET content = EXCLUDED.content`,
+		id, content)
+	return err
+}
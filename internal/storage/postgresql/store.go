@@ -0,0 +1,520 @@
+package postgresql
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/17HIERARCH70/BashAPI/internal/domain/models"
+	"github.com/17HIERARCH70/BashAPI/internal/lib/labels"
+	"github.com/17HIERARCH70/BashAPI/internal/lib/metrics"
+	"github.com/17HIERARCH70/BashAPI/internal/lib/tracing"
+	"github.com/17HIERARCH70/BashAPI/internal/storage"
+	"github.com/jackc/pgx/v4"
+	"github.com/jackc/pgx/v4/pgxpool"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+	"time"
+)
+
+// Store keeps commands, their queue and their events in the commands schema.
+type Store struct {
+	DB *pgxpool.Pool
+}
+
+var _ storage.CommandStore = &Store{}
+
+// NewStore creates a store on the connection pool.
+func NewStore(db *pgxpool.Pool) *Store {
+	return &Store{DB: db}
+}
+
+// querier is implemented by both *pgxpool.Pool and pgx.Tx.
+type querier interface {
+	QueryRow(ctx context.Context, sql string, args ...interface{}) pgx.Row
+}
+
+// RowScanner is implemented by both pgx.Row and pgx.Rows.
+type RowScanner interface {
+	Scan(dest ...interface{}) error
+}
+
+// OutputLayoutColumns are the columns of commands.commands scanned by OutputLayoutTargets.
+const OutputLayoutColumns = "output_size, output_truncated, output_gap_start, output_gap_end, output_dropped, output_archive, output_archived_at"
+
+// OutputLayoutTargets returns the scan targets of OutputLayoutColumns.
+func OutputLayoutTargets(l *storage.OutputLayout) []interface{} {
+	return []interface{}{&l.Size, &l.Truncated, &l.GapStart, &l.GapEnd, &l.Dropped, &l.Archive, &l.ArchivedAt}
+}
+
+// CommandColumns are the columns of commands.commands scanned by ScanCommand.
+const CommandColumns = "id, script, status, pid, weight, queue_name, " + OutputLayoutColumns +
+	", created_at, updated_at, started_at, finished_at, submitter, tags, exit_code, labels, annotations"
+
+// ScanCommand scans a row of CommandColumns followed by the extra columns.
+func ScanCommand(row RowScanner, extra ...interface{}) (models.Command, storage.OutputLayout, error) {
+	var cmd models.Command
+	var layout storage.OutputLayout
+	targets := append([]interface{}{&cmd.ID, &cmd.Script, &cmd.Status, &cmd.PID, &cmd.Weight, &cmd.Queue}, OutputLayoutTargets(&layout)...)
+	targets = append(targets, &cmd.CreatedAt, &cmd.UpdatedAt, &cmd.StartedAt, &cmd.FinishedAt, &cmd.Submitter, &cmd.Tags, &cmd.ExitCode, &cmd.Labels, &cmd.Annotations)
+	err := row.Scan(append(targets, extra...)...)
+	return cmd, layout, err
+}
+
+// Ping checks that the database can be reached.
+func (s *Store) Ping(ctx context.Context) error {
+	return s.DB.Ping(ctx)
+}
+
+// CreateCommand inserts the command, its creation event, its locks and its queue entry in
+// one transaction, tracing every insert as a child of the span in ctx.
+func (s *Store) CreateCommand(ctx context.Context, command storage.NewCommand) (int, error) {
+	opts := command.Options
+	tx, err := s.DB.Begin(ctx)
+	if err != nil {
+		return 0, err
+	}
+	defer func() {
+		_ = tx.Rollback(ctx)
+	}()
+
+	// Create the command record and get the ID
+	var commandID int
+	insert := startInsertSpan(ctx, "commands.commands")
+	err = tx.QueryRow(ctx,
+		`INSERT INTO commands.commands (script, status, weight, queue_name, max_output, output_policy, kill_on_output_limit, submitter, tags, labels, annotations, callback_url, trace_context)
+		VALUES ($1, 'waiting', $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12) RETURNING id`,
+		command.Script, opts.Weight, opts.Queue, opts.MaxOutput, opts.OutputPolicy, opts.KillOnOutputLimit, opts.Submitter, nonNilTags(opts.Tags),
+		nonNilLabels(opts.Labels), nonNilLabels(opts.Annotations), opts.CallbackURL, command.TraceContext).Scan(&commandID)
+	endSpan(insert, err)
+	if err != nil {
+		return 0, fmt.Errorf("failed to create command record: %w", err)
+	}
+	insert = startInsertSpan(ctx, "commands.command_events")
+	_, err = tx.Exec(ctx,
+		"INSERT INTO commands.command_events (command_id, to_status, actor, reason) VALUES ($1, $2, $3, 'created')",
+		commandID, models.StatusWaiting, storage.ActorAPI)
+	endSpan(insert, err)
+	if err != nil {
+		return 0, fmt.Errorf("failed to record command creation: %w", err)
+	}
+
+	// Declare the locks of the command
+	for _, lock := range opts.Locks {
+		insert = startInsertSpan(ctx, "commands.command_locks")
+		_, err = tx.Exec(ctx,
+			"INSERT INTO commands.command_locks (command_id, lock_key, exclusive) VALUES ($1, $2, $3)",
+			commandID, lock.Key, lock.IsExclusive())
+		endSpan(insert, err)
+		if err != nil {
+			return 0, fmt.Errorf("failed to declare command lock %q: %w", lock.Key, err)
+		}
+	}
+
+	insert = startInsertSpan(ctx, "commands.queue")
+	_, err = tx.Exec(ctx, "INSERT INTO commands.queue (command_id, status, expires_at) VALUES ($1, 'waiting', $2)", commandID, command.ExpiresAt)
+	endSpan(insert, err)
+	if err != nil {
+		return 0, fmt.Errorf("failed to enqueue command: %w", err)
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return 0, err
+	}
+	return commandID, nil
+}
+
+// startInsertSpan starts the span of an INSERT into table.
+func startInsertSpan(ctx context.Context, table string) trace.Span {
+	_, span := tracing.Tracer().Start(ctx, "INSERT "+table, trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(
+			attribute.String("db.system", "postgresql"),
+			attribute.String("db.operation", "INSERT"),
+			attribute.String("db.sql.table", table),
+		))
+	return span
+}
+
+// endSpan ends a span, marking it failed when err is set.
+func endSpan(span trace.Span, err error) {
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End()
+}
+
+// nonNilTags stores missing tags as an empty array rather than NULL.
+func nonNilTags(tags []string) []string {
+	if tags == nil {
+		return []string{}
+	}
+	return tags
+}
+
+// nonNilLabels stores missing labels as an empty object rather than a JSON null.
+func nonNilLabels(labels map[string]string) map[string]string {
+	if labels == nil {
+		return map[string]string{}
+	}
+	return labels
+}
+
+func (s *Store) FetchCommand(ctx context.Context, id int) (models.Command, storage.OutputLayout, error) {
+	command, layout, err := ScanCommand(s.DB.QueryRow(ctx,
+		"SELECT "+CommandColumns+" FROM commands.commands WHERE id = $1", id))
+	if errors.Is(err, pgx.ErrNoRows) {
+		return models.Command{}, layout, storage.ErrNotFound
+	}
+	return command, layout, err
+}
+
+// sortColumns maps the sort options of the command list to their columns.
+var sortColumns = map[string]string{
+	models.SortByID:        "c.id",
+	models.SortByCreatedAt: "c.created_at",
+	models.SortByUpdatedAt: "c.updated_at",
+}
+
+func (s *Store) FetchCommands(ctx context.Context, query *models.CommandQuery) ([]models.Command, []storage.OutputLayout, string, error) {
+	cursor, err := storage.PrepareQuery(query)
+	if err != nil {
+		return nil, nil, "", err
+	}
+	where, order := CommandListClause(query, cursor)
+	limit := where.Param(query.Limit + 1)
+
+	rows, err := s.DB.Query(ctx,
+		"SELECT "+CommandColumns+" FROM commands.commands c WHERE "+where.String()+" ORDER BY "+order+" LIMIT "+limit,
+		where.Args...)
+	if err != nil {
+		return nil, nil, "", err
+	}
+	defer rows.Close()
+
+	commands := []models.Command{}
+	var layouts []storage.OutputLayout
+	for rows.Next() {
+		cmd, layout, err := ScanCommand(rows)
+		if err != nil {
+			return nil, nil, "", err
+		}
+		commands = append(commands, cmd)
+		layouts = append(layouts, layout)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, nil, "", err
+	}
+
+	// The extra row only tells that there is a next page
+	var next string
+	if len(commands) > query.Limit {
+		commands, layouts = commands[:query.Limit], layouts[:query.Limit]
+		next = storage.EncodeCursor(query.Sort, commands[query.Limit-1])
+	}
+	return commands, layouts, next, nil
+}
+
+// CommandListClause builds the conditions and the order of a prepared command list query.
+func CommandListClause(query *models.CommandQuery, cursor *storage.Cursor) (Where, string) {
+	var where Where
+	if len(query.Statuses) > 0 {
+		where.Add("c.status = ANY(?)", query.Statuses)
+	}
+	if query.CreatedAfter != nil {
+		where.Add("c.created_at >= ?", *query.CreatedAfter)
+	}
+	if query.CreatedBefore != nil {
+		where.Add("c.created_at < ?", *query.CreatedBefore)
+	}
+	if query.UpdatedAfter != nil {
+		where.Add("c.updated_at >= ?", *query.UpdatedAfter)
+	}
+	if query.UpdatedBefore != nil {
+		where.Add("c.updated_at < ?", *query.UpdatedBefore)
+	}
+	if query.Submitter != "" {
+		where.Add("c.submitter = ?", query.Submitter)
+	}
+	if len(query.Tags) > 0 {
+		where.Add("c.tags @> ?", query.Tags)
+	}
+	if query.ScriptContains != "" {
+		where.Add("strpos(c.script, ?) > 0", query.ScriptContains)
+	}
+	if query.ExitCode != nil {
+		where.Add("c.exit_code = ?", *query.ExitCode)
+	}
+	where.AddSelector("c.labels", query.Labels)
+
+	column := sortColumns[query.Sort]
+	direction, comparison := "ASC", ">"
+	if query.Desc {
+		direction, comparison = "DESC", "<"
+	}
+	if cursor != nil {
+		if query.Sort == models.SortByID {
+			where.Add("c.id "+comparison+" ?", cursor.ID)
+		} else {
+			where.Add("("+column+", c.id) "+comparison+" (?, ?)", cursor.Time, cursor.ID)
+		}
+	}
+
+	if query.Sort == models.SortByID {
+		return where, "c.id " + direction
+	}
+	return where, column + " " + direction + ", c.id " + direction
+}
+
+func (s *Store) MatchCommands(ctx context.Context, statuses []string, selector labels.Selector) ([]int, error) {
+	var where Where
+	where.Add("c.status = ANY(?)", statuses)
+	where.AddSelector("c.labels", selector)
+
+	rows, err := s.DB.Query(ctx, "SELECT c.id FROM commands.commands c WHERE "+where.String()+" ORDER BY c.id", where.Args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var matched []int
+	for rows.Next() {
+		var id int
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		matched = append(matched, id)
+	}
+	return matched, rows.Err()
+}
+
+func (s *Store) FetchRunningCommands(ctx context.Context) ([]models.Command, error) {
+	rows, err := s.DB.Query(ctx, "SELECT "+CommandColumns+" FROM commands.commands WHERE status = 'running' ORDER BY id")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var running []models.Command
+	for rows.Next() {
+		cmd, _, err := ScanCommand(rows)
+		if err != nil {
+			return nil, err
+		}
+		running = append(running, cmd)
+	}
+	return running, rows.Err()
+}
+
+// changeStatusQuery changes the status only when the current one allows it and records the
+// transition in the same statement, so that concurrent changes cannot interleave.
+const changeStatusQuery = `
+WITH previous AS (
+	SELECT id, status FROM commands.commands WHERE id = $1 FOR UPDATE
+), changed AS (
+	UPDATE commands.commands c SET status = $2::text,
+		started_at = CASE WHEN $2::text = 'running' THEN NOW() ELSE c.started_at END,
+		finished_at = CASE WHEN $2::text IN ('waiting', 'running') THEN NULL ELSE NOW() END,
+		exit_code = $6::integer
+	FROM previous WHERE c.id = previous.id AND previous.status = ANY($3::text[])
+	RETURNING c.id, previous.status AS from_status, c.queue_name, c.created_at, c.started_at, c.finished_at
+), recorded AS (
+	INSERT INTO commands.command_events (command_id, from_status, to_status, actor, reason)
+	SELECT id, from_status, $2::text, $4::text, $5::text FROM changed
+)
+SELECT from_status, queue_name, created_at, started_at, finished_at FROM changed`
+
+func (s *Store) ChangeStatus(ctx context.Context, id int, change storage.StatusChange) (storage.Transition, error) {
+	return changeStatus(ctx, s.DB, id, change)
+}
+
+func changeStatus(ctx context.Context, db querier, id int, change storage.StatusChange) (storage.Transition, error) {
+	var t storage.Transition
+	err := db.QueryRow(ctx, changeStatusQuery,
+		id, change.To, change.From, change.Actor, change.Reason, change.ExitCode).
+		Scan(&t.From, &t.Queue, &t.CreatedAt, &t.StartedAt, &t.FinishedAt)
+	if !errors.Is(err, pgx.ErrNoRows) {
+		return t, err
+	}
+
+	var current string
+	if err := db.QueryRow(ctx, "SELECT status FROM commands.commands WHERE id = $1", id).Scan(&current); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return t, storage.ErrNotFound
+		}
+		return t, err
+	}
+	return storage.Transition{From: current}, fmt.Errorf("%w: %s -> %s", storage.ErrInvalidTransition, current, change.To)
+}
+
+func (s *Store) SetPID(ctx context.Context, id, pid int) error {
+	_, err := s.DB.Exec(ctx, "UPDATE commands.commands SET pid = $1 WHERE id = $2", pid, id)
+	return err
+}
+
+func (s *Store) UpdateLabels(ctx context.Context, id int, update func(models.CommandLabels) (models.CommandLabels, error)) (models.CommandLabels, error) {
+	tx, err := s.DB.Begin(ctx)
+	if err != nil {
+		return models.CommandLabels{}, err
+	}
+	defer func() {
+		_ = tx.Rollback(ctx)
+	}()
+
+	var current models.CommandLabels
+	err = tx.QueryRow(ctx, "SELECT labels, annotations FROM commands.commands WHERE id = $1 FOR UPDATE", id).
+		Scan(&current.Labels, &current.Annotations)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return models.CommandLabels{}, storage.ErrNotFound
+		}
+		return models.CommandLabels{}, err
+	}
+	result, err := update(current)
+	if err != nil {
+		return models.CommandLabels{}, err
+	}
+
+	if _, err := tx.Exec(ctx, "UPDATE commands.commands SET labels = $2, annotations = $3 WHERE id = $1",
+		id, nonNilLabels(result.Labels), nonNilLabels(result.Annotations)); err != nil {
+		return models.CommandLabels{}, err
+	}
+	if err := tx.Commit(ctx); err != nil {
+		return models.CommandLabels{}, err
+	}
+	return result, nil
+}
+
+// historicalDurationQuery averages the run time of recent completed commands, preferring
+// the same script, then scripts starting with the same program, then any script.
+const historicalDurationQuery = `
+	SELECT COALESCE(
+		(SELECT AVG(EXTRACT(EPOCH FROM finished_at - started_at)) FROM (
+			SELECT started_at, finished_at FROM commands.commands
+			WHERE status = 'completed' AND started_at IS NOT NULL AND finished_at IS NOT NULL AND script = $1
+			ORDER BY id DESC LIMIT 20) same_script),
+		(SELECT AVG(EXTRACT(EPOCH FROM finished_at - started_at)) FROM (
+			SELECT started_at, finished_at FROM commands.commands
+			WHERE status = 'completed' AND started_at IS NOT NULL AND finished_at IS NOT NULL
+			  AND split_part(script, ' ', 1) = split_part($1, ' ', 1)
+			ORDER BY id DESC LIMIT 20) same_program),
+		(SELECT AVG(EXTRACT(EPOCH FROM finished_at - started_at)) FROM (
+			SELECT started_at, finished_at FROM commands.commands
+			WHERE status = 'completed' AND started_at IS NOT NULL AND finished_at IS NOT NULL
+			ORDER BY id DESC LIMIT 100) any_script))`
+
+func (s *Store) AverageDuration(ctx context.Context, script string) (time.Duration, bool, error) {
+	var seconds *float64
+	if err := s.DB.QueryRow(ctx, historicalDurationQuery, script).Scan(&seconds); err != nil || seconds == nil {
+		return 0, false, err
+	}
+	return time.Duration(*seconds * float64(time.Second)), true, nil
+}
+
+func (s *Store) CommandStats(ctx context.Context) (metrics.CommandStats, error) {
+	var stats metrics.CommandStats
+	err := s.DB.QueryRow(ctx,
+		`SELECT COUNT(*) FILTER (WHERE c.status = 'running'),
+			COUNT(*) FILTER (WHERE c.status = 'waiting'),
+			COUNT(*) FILTER (WHERE c.status = 'waiting' AND EXISTS (
+				SELECT 1 FROM commands.queue_pauses p WHERE p.queue_name IN (c.queue_name, $1))),
+			COALESCE(SUM(c.weight) FILTER (WHERE c.status = 'running'), 0)
+		FROM commands.commands c WHERE c.status IN ('running', 'waiting')`,
+		models.AllQueues).Scan(&stats.Running, &stats.Queued, &stats.Paused, &stats.UsedSlots)
+	return stats, err
+}
+
+// FindPurgeable numbers the matching commands of every queue from the newest, so that the
+// newest filter.KeepLast of them are spared.
+func (s *Store) FindPurgeable(ctx context.Context, filter models.PurgeFilter) ([]int, int, error) {
+	var where Where
+	where.Add("c.status <> 'running'")
+	where.Add("c.status = ANY(?)", filter.Statuses)
+	if len(filter.IDs) > 0 {
+		where.Add("c.id = ANY(?)", filter.IDs)
+	}
+	if filter.Queue != "" {
+		where.Add("c.queue_name = ?", filter.Queue)
+	}
+	if filter.FinishedBefore != nil {
+		where.Add("c.finished_at < ?", *filter.FinishedBefore)
+	}
+	where.AddSelector("c.labels", filter.Selector)
+	keepLast := where.Param(filter.KeepLast)
+
+	rows, err := s.DB.Query(ctx,
+		`SELECT id, has_archive FROM (
+			SELECT c.id, c.output_archive IS NOT NULL AS has_archive,
+				row_number() OVER (PARTITION BY c.queue_name ORDER BY c.id DESC) AS newest
+			FROM commands.commands c WHERE `+where.String()+`
+		) matched WHERE newest > `+keepLast+` ORDER BY id`,
+		where.Args...)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer rows.Close()
+
+	ids := []int{}
+	archived := 0
+	for rows.Next() {
+		var id int
+		var hasArchive bool
+		if err := rows.Scan(&id, &hasArchive); err != nil {
+			return nil, 0, err
+		}
+		ids = append(ids, id)
+		if hasArchive {
+			archived++
+		}
+	}
+	return ids, archived, rows.Err()
+}
+
+func (s *Store) DeleteCommands(ctx context.Context, ids []int) ([]int, []string, error) {
+	tx, err := s.DB.Begin(ctx)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer func() {
+		_ = tx.Rollback(ctx)
+	}()
+
+	// Lock the rows first so that the dispatcher cannot start a command being deleted
+	if _, err := tx.Exec(ctx,
+		"SELECT id FROM commands.commands WHERE id = ANY($1) AND status <> 'running' FOR UPDATE",
+		ids); err != nil {
+		return nil, nil, err
+	}
+	if _, err := tx.Exec(ctx,
+		`DELETE FROM commands.queue WHERE command_id IN (
+			SELECT id FROM commands.commands WHERE id = ANY($1) AND status <> 'running')`,
+		ids); err != nil {
+		return nil, nil, err
+	}
+	rows, err := tx.Query(ctx,
+		"DELETE FROM commands.commands WHERE id = ANY($1) AND status <> 'running' RETURNING id, output_archive",
+		ids)
+	if err != nil {
+		return nil, nil, err
+	}
+	deleted := []int{}
+	var archives []string
+	for rows.Next() {
+		var id int
+		var archive *string
+		if err := rows.Scan(&id, &archive); err != nil {
+			rows.Close()
+			return nil, nil, err
+		}
+		deleted = append(deleted, id)
+		if archive != nil {
+			archives = append(archives, *archive)
+		}
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, nil, err
+	}
+	if err := tx.Commit(ctx); err != nil {
+		return nil, nil, err
+	}
+	return deleted, archives, nil
+}
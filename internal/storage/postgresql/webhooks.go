@@ -0,0 +1,211 @@
+package postgresql
+
+import (
+	"context"
+	"github.com/17HIERARCH70/BashAPI/internal/domain/models"
+	"github.com/17HIERARCH70/BashAPI/internal/storage"
+	"time"
+)
+
+func (s *Store) CreateWebhook(ctx context.Context, hook models.Webhook) (models.Webhook, error) {
+	err := s.DB.QueryRow(ctx,
+		"INSERT INTO commands.webhooks (url, secret, events) VALUES ($1, $2, $3) RETURNING id, created_at",
+		hook.URL, hook.Secret, hook.Events).Scan(&hook.ID, &hook.CreatedAt)
+	return hook, err
+}
+
+func (s *Store) FetchWebhooks(ctx context.Context) ([]models.Webhook, error) {
+	rows, err := s.DB.Query(ctx, "SELECT id, url, secret, events, created_at FROM commands.webhooks ORDER BY id")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	webhooks := []models.Webhook{}
+	for rows.Next() {
+		var hook models.Webhook
+		if err := rows.Scan(&hook.ID, &hook.URL, &hook.Secret, &hook.Events, &hook.CreatedAt); err != nil {
+			return nil, err
+		}
+		webhooks = append(webhooks, hook)
+	}
+	return webhooks, rows.Err()
+}
+
+func (s *Store) DeleteWebhook(ctx context.Context, id int) error {
+	tag, err := s.DB.Exec(ctx, "DELETE FROM commands.webhooks WHERE id = $1", id)
+	if err != nil {
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return storage.ErrWebhookNotFound
+	}
+	return nil
+}
+
+// FanOutEvents holds the row of commands.webhook_cursor locked, so that replicas do not
+// fan out an event twice.
+func (s *Store) FanOutEvents(ctx context.Context, settledBefore time.Time, limit int, fanOut func(events []storage.PendingEvent) ([]storage.NewDelivery, error)) error {
+	tx, err := s.DB.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		_ = tx.Rollback(ctx)
+	}()
+
+	var cursor int64
+	if err := tx.QueryRow(ctx, "SELECT last_event_id FROM commands.webhook_cursor FOR UPDATE").Scan(&cursor); err != nil {
+		return err
+	}
+
+	rows, err := tx.Query(ctx,
+		"SELECT "+LifecycleEventColumns+`, c.callback_url
+		FROM commands.command_events e JOIN commands.commands c ON c.id = e.command_id
+		WHERE e.id > $1 AND e.created_at < $3 ORDER BY e.id LIMIT $2`,
+		cursor, limit, settledBefore)
+	if err != nil {
+		return err
+	}
+	var events []storage.PendingEvent
+	for rows.Next() {
+		var p storage.PendingEvent
+		if p.Event, err = ScanLifecycleEvent(rows, &p.CallbackURL); err != nil {
+			rows.Close()
+			return err
+		}
+		events = append(events, p)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return err
+	}
+	if len(events) == 0 {
+		return nil
+	}
+
+	deliveries, err := fanOut(events)
+	if err != nil {
+		return err
+	}
+	for _, d := range deliveries {
+		if _, err := tx.Exec(ctx,
+			`INSERT INTO commands.webhook_deliveries (webhook_id, command_id, event_id, event, url, payload)
+			VALUES ($1, $2, $3, $4, $5, $6)`,
+			d.WebhookID, d.Event.CommandID, d.Event.EventID, d.Event.Event, d.URL, string(d.Payload)); err != nil {
+			return err
+		}
+	}
+
+	last := events[len(events)-1].Event.EventID
+	if _, err := tx.Exec(ctx, "UPDATE commands.webhook_cursor SET last_event_id = $1", last); err != nil {
+		return err
+	}
+	return tx.Commit(ctx)
+}
+
+func (s *Store) ClaimDeliveries(ctx context.Context, limit int, lease time.Duration) ([]storage.DueDelivery, error) {
+	rows, err := s.DB.Query(ctx,
+		`UPDATE commands.webhook_deliveries d SET next_attempt_at = NOW() + make_interval(secs => $2)
+		FROM (
+			SELECT dd.id FROM commands.webhook_deliveries dd
+			WHERE dd.status = 'pending' AND dd.next_attempt_at <= NOW()
+			ORDER BY dd.next_attempt_at LIMIT $1 FOR UPDATE OF dd SKIP LOCKED
+		) due
+		WHERE d.id = due.id
+		RETURNING d.id, d.url, d.event, d.payload::text, d.attempts,
+			COALESCE((SELECT w.secret FROM commands.webhooks w WHERE w.id = d.webhook_id), '')`,
+		limit, lease.Seconds())
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var due []storage.DueDelivery
+	for rows.Next() {
+		var d storage.DueDelivery
+		if err := rows.Scan(&d.ID, &d.URL, &d.Event, &d.Payload, &d.Attempts, &d.Secret); err != nil {
+			return nil, err
+		}
+		due = append(due, d)
+	}
+	return due, rows.Err()
+}
+
+func (s *Store) RecordAttempt(ctx context.Context, a storage.DeliveryAttempt) error {
+	_, err := s.DB.Exec(ctx,
+		`WITH attempt AS (
+			INSERT INTO commands.webhook_attempts (delivery_id, attempt, status_code, error, duration_ms)
+			VALUES ($1, $2, $3, $4, $5)
+		)
+		UPDATE commands.webhook_deliveries SET attempts = $2, status = $6, last_status_code = $3, last_error = $4,
+			next_attempt_at = CASE WHEN $6 = 'pending' THEN $7 ELSE next_attempt_at END,
+			delivered_at = CASE WHEN $6 = 'delivered' THEN NOW() END
+		WHERE id = $1`,
+		a.DeliveryID, a.Attempt, a.StatusCode, a.Error, a.Duration.Milliseconds(), a.Status, a.NextAttemptAt)
+	return err
+}
+
+func (s *Store) FetchDeliveries(ctx context.Context, query models.DeliveryQuery) ([]models.WebhookDelivery, error) {
+	var where Where
+	if query.CommandID != nil {
+		where.Add("command_id = ?", *query.CommandID)
+	}
+	if query.WebhookID != nil {
+		where.Add("webhook_id = ?", *query.WebhookID)
+	}
+	if query.Limit <= 0 {
+		query.Limit = storage.DefaultListLimit
+	}
+	limit := where.Param(query.Limit)
+
+	rows, err := s.DB.Query(ctx,
+		`SELECT id, webhook_id, command_id, event, url, status, attempts,
+			CASE WHEN status = 'pending' THEN next_attempt_at END, last_status_code, last_error, created_at, delivered_at
+		FROM commands.webhook_deliveries WHERE `+where.String()+` ORDER BY id DESC LIMIT `+limit,
+		where.Args...)
+	if err != nil {
+		return nil, err
+	}
+	deliveries := []models.WebhookDelivery{}
+	index := map[int64]int{}
+	var ids []int64
+	for rows.Next() {
+		var d models.WebhookDelivery
+		if err := rows.Scan(&d.ID, &d.WebhookID, &d.CommandID, &d.Event, &d.URL, &d.Status, &d.Attempts,
+			&d.NextAttemptAt, &d.LastStatusCode, &d.LastError, &d.CreatedAt, &d.DeliveredAt); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		d.AttemptLog = []models.WebhookAttempt{}
+		index[d.ID] = len(deliveries)
+		ids = append(ids, d.ID)
+		deliveries = append(deliveries, d)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	if len(ids) == 0 {
+		return deliveries, nil
+	}
+
+	rows, err = s.DB.Query(ctx,
+		`SELECT delivery_id, attempt, status_code, error, duration_ms, created_at
+		FROM commands.webhook_attempts WHERE delivery_id = ANY($1) ORDER BY delivery_id, attempt`,
+		ids)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var deliveryID int64
+		var a models.WebhookAttempt
+		if err := rows.Scan(&deliveryID, &a.Attempt, &a.StatusCode, &a.Error, &a.DurationMs, &a.CreatedAt); err != nil {
+			return nil, err
+		}
+		d := &deliveries[index[deliveryID]]
+		d.AttemptLog = append(d.AttemptLog, a)
+	}
+	return deliveries, rows.Err()
+}
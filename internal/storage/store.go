@@ -0,0 +1,217 @@
+// Package storage defines how commands, their queue, their event history and the
+// deliveries of their events are persisted.
+// The postgresql package stores them in PostgreSQL, the memory package in the process
+// memory for development and tests.
+package storage
+
+import (
+	"context"
+	"errors"
+	"github.com/17HIERARCH70/BashAPI/internal/domain/models"
+	"github.com/17HIERARCH70/BashAPI/internal/lib/labels"
+	"github.com/17HIERARCH70/BashAPI/internal/lib/metrics"
+	"time"
+)
+
+var (
+	ErrNotFound          = errors.New("command not found")
+	ErrInvalidTransition = errors.New("invalid status transition")
+	ErrNotQueued         = errors.New("command is not waiting in the queue")
+	ErrInvalidCursor     = errors.New("invalid cursor")
+)
+
+// Actors recorded in the event history.
+const (
+	ActorAPI        = "api"
+	ActorDispatcher = "dispatcher"
+	ActorExecutor   = "executor"
+	ActorShutdown   = "shutdown"
+)
+
+// CommandStore persists commands with their output, the queue they wait in with the locks
+// and pauses holding them back, and the history of their status changes. Every status
+// change is recorded in the event history in the same step as the change itself.
+type CommandStore interface {
+	IdempotencyStore
+	SearchStore
+	WebhookStore
+	ArchiveStore
+
+	// Ping checks that the store can be reached.
+	Ping(ctx context.Context) error
+
+	// CreateCommand stores a waiting command with its creation event and its locks and
+	// appends it to the queue.
+	CreateCommand(ctx context.Context, command NewCommand) (int, error)
+	FetchCommand(ctx context.Context, id int) (models.Command, OutputLayout, error)
+	// FetchCommands returns one page of the commands matching the query, after applying
+	// the defaults of the query in place, and the cursor of the next page.
+	FetchCommands(ctx context.Context, query *models.CommandQuery) ([]models.Command, []OutputLayout, string, error)
+	// MatchCommands returns the IDs of the commands in one of the statuses matching the selector.
+	MatchCommands(ctx context.Context, statuses []string, selector labels.Selector) ([]int, error)
+	// FetchRunningCommands returns the running commands without their output.
+	FetchRunningCommands(ctx context.Context) ([]models.Command, error)
+	// ChangeStatus moves a command to change.To when its current status is one of change.From.
+	// A rejected change returns ErrInvalidTransition and the current status as Transition.From.
+	ChangeStatus(ctx context.Context, id int, change StatusChange) (Transition, error)
+	SetPID(ctx context.Context, id, pid int) error
+	// UpdateLabels replaces the labels and annotations of a command with the result of update,
+	// which is applied to the current ones without any concurrent update in between.
+	UpdateLabels(ctx context.Context, id int, update func(models.CommandLabels) (models.CommandLabels, error)) (models.CommandLabels, error)
+	// AverageDuration averages the run time of recent completed commands, preferring the same
+	// script, then scripts starting with the same program, then any script. It reports false
+	// without any completed command.
+	AverageDuration(ctx context.Context, script string) (time.Duration, bool, error)
+	// FindPurgeable returns the IDs of the commands that are not running and match the purge
+	// filter, whose statuses are already set, in ID order, with the number of them having an
+	// archived output.
+	FindPurgeable(ctx context.Context, filter models.PurgeFilter) ([]int, int, error)
+	// DeleteCommands deletes the commands that are not running with their queue entries,
+	// output and events. It returns the deleted IDs and the references of their archived outputs.
+	DeleteCommands(ctx context.Context, ids []int) ([]int, []string, error)
+	// CommandStats counts the running, queued and paused commands and the used slots.
+	CommandStats(ctx context.Context) (metrics.CommandStats, error)
+
+	// FetchOutputWriter returns where the output of a command continues and its limits.
+	FetchOutputWriter(ctx context.Context, id int) (OutputWriterState, error)
+	FetchOutputLayout(ctx context.Context, id int) (OutputLayout, error)
+	// AppendOutput stores a chunk, unless it is empty, deletes the chunks lying entirely in
+	// the gap of the layout and saves the layout, except for its archive fields.
+	AppendOutput(ctx context.Context, id int, chunk OutputChunk, layout OutputLayout) error
+	// ScanOutput passes the stored output bytes in [from, to) to fn chunk by chunk, in order
+	// or backwards from the end, until fn returns false.
+	ScanOutput(ctx context.Context, id int, from, to int64, backwards bool, fn func(data []byte) bool) error
+
+	// FetchQueue returns the queued commands in dispatch order, with whether their queue is
+	// paused and the running command holding a lock they wait for.
+	FetchQueue(ctx context.Context) ([]models.Queue, error)
+	// StartCommand removes a command from the queue, if it is queued, and changes its status.
+	StartCommand(ctx context.Context, id int, change StatusChange) (Execution, error)
+	// DequeueCommand removes a command from the queue and changes its status.
+	DequeueCommand(ctx context.Context, id int, change StatusChange) (Transition, error)
+	// RequeueCommand changes the status of a command and puts it at the front of the queue.
+	// It returns the PID of the process the command ran in.
+	RequeueCommand(ctx context.Context, id int, change StatusChange) (Transition, *int, error)
+	// MoveQueuedCommand puts a queued command at the given 1-based position and returns the
+	// position it ended up at. It returns ErrNotQueued for a command that is not queued.
+	MoveQueuedCommand(ctx context.Context, id, position int) (int, error)
+	// CancelQueuedCommands dequeues the commands matching the filter with the change.
+	CancelQueuedCommands(ctx context.Context, filter models.QueueFilter, change StatusChange) ([]DequeuedCommand, error)
+	// ExpireQueuedCommands dequeues the commands that waited past their expiry with the change.
+	ExpireQueuedCommands(ctx context.Context, change StatusChange) ([]DequeuedCommand, error)
+	// FindBlockingCommand returns the ID of a running command holding a lock that conflicts
+	// with the locks of the given one, or nil.
+	FindBlockingCommand(ctx context.Context, id int) (*int, error)
+
+	// PauseQueue pauses a named queue, or every queue for models.AllQueues.
+	PauseQueue(ctx context.Context, name, reason string) error
+	// ResumeQueue lifts a pause and reports whether the queue was paused.
+	ResumeQueue(ctx context.Context, name string) (bool, error)
+	FetchQueuePauses(ctx context.Context) ([]models.QueuePause, error)
+	// FindQueuePause returns the pause holding back the named queue, preferring the global one.
+	FindQueuePause(ctx context.Context, name string) (*models.QueuePause, error)
+
+	// FetchCommandEvents returns the status changes of a command, oldest first.
+	FetchCommandEvents(ctx context.Context, id int) ([]models.CommandEvent, error)
+	// FetchLifecycleEvents returns at most limit events after filter.After, or that occurred
+	// at filter.Since or later, matching the filter, oldest first.
+	FetchLifecycleEvents(ctx context.Context, filter models.EventFilter, limit int) ([]models.LifecycleEvent, error)
+	// FetchLifecycleEvent returns one event, ErrNotFound once its command was deleted.
+	FetchLifecycleEvent(ctx context.Context, id int64) (models.LifecycleEvent, error)
+	// ListenEvents calls announce with the ID of every event recorded from now on, by any
+	// process sharing the store, until ctx is cancelled or the store cannot be listened to.
+	// ready is called once listening has begun. An error returned by ready or announce ends
+	// the listening with that error.
+	ListenEvents(ctx context.Context, ready func() error, announce func(id int64) error) error
+}
+
+// SchemaChecker is implemented by the stores whose schema is versioned by migrations.
+type SchemaChecker interface {
+	// CheckSchema describes the version of the schema and fails when it is not the version
+	// the code relies on.
+	CheckSchema(ctx context.Context) (string, error)
+}
+
+// NewCommand is a command to be created waiting in the queue.
+type NewCommand struct {
+	Script  string
+	Options models.CommandOptions
+	// TraceContext is the trace context the execution continues.
+	TraceContext string
+	// ExpiresAt is when the command gives up waiting in the queue, nil for never.
+	ExpiresAt *time.Time
+}
+
+// StatusChange is a transition requested by an actor.
+type StatusChange struct {
+	To string
+	// From lists the statuses the command may change from.
+	From   []string
+	Actor  string
+	Reason string
+	// ExitCode is stored for finished commands, nil when the process did not exit by itself.
+	ExitCode *int
+}
+
+// Transition is an applied status change with the times of the command it changed.
+type Transition struct {
+	From       string
+	Queue      string
+	CreatedAt  time.Time
+	StartedAt  *time.Time
+	FinishedAt *time.Time
+}
+
+// Execution is a command moved to running with what its execution needs.
+type Execution struct {
+	Transition
+	Script       string
+	TraceContext string
+}
+
+// DequeuedCommand is a command removed from the queue by a bulk change.
+type DequeuedCommand struct {
+	ID    int
+	Queue string
+}
+
+// OutputLayout describes how the kept output maps onto the stored stream: the bytes before
+// GapStart, the truncation marker and the bytes from GapEnd up to Size.
+type OutputLayout struct {
+	Size      int64
+	Truncated bool
+	GapStart  int64
+	GapEnd    int64
+	Dropped   int64
+	// Archive references the archived output, which replaces the stored chunks.
+	Archive    *string
+	ArchivedAt *time.Time
+}
+
+// OutputWriterState is where the output of a command continues, with its limits.
+type OutputWriterState struct {
+	// NextSeq is the sequence number of the next chunk.
+	NextSeq int
+	Layout  OutputLayout
+	Limit   int64
+	Policy  string
+	Kill    bool
+}
+
+// OutputChunk is a piece of output starting at Offset of the stream.
+type OutputChunk struct {
+	Seq    int
+	Offset int64
+	Data   []byte
+}
+
+// TrimChunk trims the data of a chunk starting at offset to the range [from, to).
+func TrimChunk(data []byte, offset, from, to int64) []byte {
+	if offset+int64(len(data)) > to {
+		data = data[:to-offset]
+	}
+	if offset < from {
+		data = data[from-offset:]
+	}
+	return data
+}
@@ -0,0 +1,73 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"github.com/17HIERARCH70/BashAPI/internal/domain/models"
+	"time"
+)
+
+var ErrWebhookNotFound = errors.New("webhook not found")
+
+// WebhookStore keeps the webhooks, the deliveries of command events to them and to the
+// callback URLs of the commands, and the attempts made to send the deliveries.
+type WebhookStore interface {
+	// CreateWebhook stores a webhook and returns it with its ID and creation time.
+	CreateWebhook(ctx context.Context, hook models.Webhook) (models.Webhook, error)
+	// FetchWebhooks lists the webhooks, secrets included.
+	FetchWebhooks(ctx context.Context) ([]models.Webhook, error)
+	// DeleteWebhook removes a webhook together with its deliveries.
+	DeleteWebhook(ctx context.Context, id int) error
+	// FanOutEvents passes at most limit events after the fan-out cursor, which occurred before
+	// settledBefore, to fanOut. The deliveries it returns are stored and the cursor is moved
+	// past the events in one step, which no other process fans out at the same time.
+	FanOutEvents(ctx context.Context, settledBefore time.Time, limit int, fanOut func(events []PendingEvent) ([]NewDelivery, error)) error
+	// ClaimDeliveries leases at most limit due pending deliveries for lease, so that other
+	// processes skip them meanwhile. A delivery is due again once its lease expired.
+	ClaimDeliveries(ctx context.Context, limit int, lease time.Duration) ([]DueDelivery, error)
+	// RecordAttempt logs an attempt of a delivery and updates the delivery with its outcome.
+	RecordAttempt(ctx context.Context, attempt DeliveryAttempt) error
+	// FetchDeliveries lists the latest deliveries of a command or of a webhook with their
+	// attempts, newest first.
+	FetchDeliveries(ctx context.Context, query models.DeliveryQuery) ([]models.WebhookDelivery, error)
+}
+
+// PendingEvent is a command event to fan out, with the callback URL of its command.
+type PendingEvent struct {
+	Event       models.LifecycleEvent
+	CallbackURL string
+}
+
+// NewDelivery is an event to send to a subscriber.
+type NewDelivery struct {
+	// WebhookID is nil for the callback URL of the command.
+	WebhookID *int
+	Event     models.LifecycleEvent
+	URL       string
+	Payload   []byte
+}
+
+// DueDelivery is a delivery claimed for an attempt, with the secret of its webhook.
+type DueDelivery struct {
+	ID       int64
+	URL      string
+	Event    string
+	Payload  string
+	Attempts int
+	Secret   string
+}
+
+// DeliveryAttempt is the outcome of sending a delivery.
+type DeliveryAttempt struct {
+	DeliveryID int64
+	// Attempt is the number of the attempt, counting from 1.
+	Attempt int
+	// StatusCode is nil when no response came.
+	StatusCode *int
+	Error      string
+	Duration   time.Duration
+	// Status is the status of the delivery after the attempt.
+	Status string
+	// NextAttemptAt is when a pending delivery is tried again.
+	NextAttemptAt time.Time
+}
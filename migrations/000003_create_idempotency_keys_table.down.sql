@@ -0,0 +1,2 @@
+-- This script drops the idempotency keys table during a rollback.
+DROP TABLE IF EXISTS commands.idempotency_keys;
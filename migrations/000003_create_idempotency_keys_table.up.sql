@@ -0,0 +1,11 @@
+CREATE TABLE IF NOT EXISTS commands.idempotency_keys (
+                                                         idempotency_key VARCHAR(255) NOT NULL,
+                                                         request_hash CHAR(64) NOT NULL,
+                                                         command_id INTEGER,
+                                                         response JSONB,
+                                                         created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+                                                         CONSTRAINT idempotency_keys_key_unique UNIQUE (idempotency_key),
+                                                         FOREIGN KEY (command_id) REFERENCES commands.commands(id) ON DELETE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS idempotency_keys_created_at_idx ON commands.idempotency_keys (created_at);
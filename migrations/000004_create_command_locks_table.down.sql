@@ -0,0 +1,2 @@
+-- This script drops the command locks table during a rollback.
+DROP TABLE IF EXISTS commands.command_locks;
@@ -0,0 +1,9 @@
+CREATE TABLE IF NOT EXISTS commands.command_locks (
+                                                      command_id INTEGER NOT NULL,
+                                                      lock_key VARCHAR(255) NOT NULL,
+                                                      exclusive BOOLEAN NOT NULL DEFAULT TRUE,
+                                                      PRIMARY KEY (command_id, lock_key),
+                                                      FOREIGN KEY (command_id) REFERENCES commands.commands(id) ON DELETE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS command_locks_lock_key_idx ON commands.command_locks (lock_key);
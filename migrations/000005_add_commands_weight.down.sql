@@ -0,0 +1,2 @@
+-- This script removes the command weight during a rollback.
+ALTER TABLE commands.commands DROP COLUMN IF EXISTS weight;
@@ -0,0 +1 @@
+ALTER TABLE commands.commands ADD COLUMN IF NOT EXISTS weight INTEGER NOT NULL DEFAULT 1 CHECK (weight > 0);
@@ -0,0 +1,3 @@
+-- This script drops the queue pauses during a rollback.
+DROP TABLE IF EXISTS commands.queue_pauses;
+ALTER TABLE commands.commands DROP COLUMN IF EXISTS queue_name;
@@ -0,0 +1,8 @@
+ALTER TABLE commands.commands ADD COLUMN IF NOT EXISTS queue_name VARCHAR(64) NOT NULL DEFAULT 'default';
+
+-- A row pauses dispatching from the named queue, '*' pauses all of them.
+CREATE TABLE IF NOT EXISTS commands.queue_pauses (
+                                                     queue_name VARCHAR(64) PRIMARY KEY,
+                                                     reason TEXT NOT NULL DEFAULT '',
+                                                     paused_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+);
@@ -0,0 +1,6 @@
+-- This script removes queue ordering and expiration during a rollback.
+DROP INDEX IF EXISTS commands.queue_expires_at_idx;
+DROP INDEX IF EXISTS commands.queue_position_idx;
+ALTER TABLE commands.queue DROP COLUMN IF EXISTS expires_at;
+ALTER TABLE commands.queue DROP COLUMN IF EXISTS position;
+DROP SEQUENCE IF EXISTS commands.queue_position_seq;
@@ -0,0 +1,11 @@
+CREATE SEQUENCE IF NOT EXISTS commands.queue_position_seq;
+
+ALTER TABLE commands.queue ADD COLUMN IF NOT EXISTS position BIGINT;
+UPDATE commands.queue SET position = nextval('commands.queue_position_seq') WHERE position IS NULL;
+ALTER TABLE commands.queue ALTER COLUMN position SET DEFAULT nextval('commands.queue_position_seq');
+ALTER TABLE commands.queue ALTER COLUMN position SET NOT NULL;
+
+ALTER TABLE commands.queue ADD COLUMN IF NOT EXISTS expires_at TIMESTAMP WITH TIME ZONE;
+
+CREATE INDEX IF NOT EXISTS queue_position_idx ON commands.queue (position, queue_id);
+CREATE INDEX IF NOT EXISTS queue_expires_at_idx ON commands.queue (expires_at) WHERE expires_at IS NOT NULL;
@@ -0,0 +1,4 @@
+-- This script removes the command run times during a rollback.
+DROP INDEX IF EXISTS commands.commands_completed_script_idx;
+ALTER TABLE commands.commands DROP COLUMN IF EXISTS finished_at;
+ALTER TABLE commands.commands DROP COLUMN IF EXISTS started_at;
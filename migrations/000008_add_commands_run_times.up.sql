@@ -0,0 +1,8 @@
+ALTER TABLE commands.commands ADD COLUMN IF NOT EXISTS started_at TIMESTAMP WITH TIME ZONE;
+ALTER TABLE commands.commands ADD COLUMN IF NOT EXISTS finished_at TIMESTAMP WITH TIME ZONE;
+
+-- Approximate the run times of existing commands to seed start time estimates.
+UPDATE commands.commands SET started_at = created_at, finished_at = updated_at
+WHERE status IN ('completed', 'error', 'timeout', 'stopped') AND started_at IS NULL;
+
+CREATE INDEX IF NOT EXISTS commands_completed_script_idx ON commands.commands (script, id DESC) WHERE status = 'completed';
@@ -0,0 +1,23 @@
+-- This script folds the output chunks back into commands.output during a rollback.
+-- Scripts may print bytes that are not valid UTF-8, such outputs are kept escape-encoded
+-- (non-ASCII bytes as \ooo) instead of aborting the rollback.
+CREATE FUNCTION pg_temp.output_text(data BYTEA) RETURNS TEXT AS $$
+BEGIN
+    RETURN convert_from(data, 'UTF8');
+EXCEPTION WHEN character_not_in_repertoire OR untranslatable_character THEN
+    RETURN encode(data, 'escape');
+END;
+$$ LANGUAGE plpgsql;
+
+UPDATE commands.commands c SET output = pg_temp.output_text(chunks.data)
+FROM (
+    SELECT command_id, string_agg(data, ''::bytea ORDER BY seq) AS data
+    FROM commands.command_output_chunks
+    GROUP BY command_id
+) chunks
+WHERE c.id = chunks.command_id;
+
+DROP FUNCTION pg_temp.output_text(BYTEA);
+DROP FUNCTION IF EXISTS commands.command_output(INTEGER, BIGINT);
+DROP TABLE IF EXISTS commands.command_output_chunks;
+ALTER TABLE commands.commands DROP COLUMN IF EXISTS output_size;
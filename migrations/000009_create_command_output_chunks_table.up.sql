@@ -0,0 +1,27 @@
+ALTER TABLE commands.commands ADD COLUMN IF NOT EXISTS output_size BIGINT NOT NULL DEFAULT 0;
+
+-- Output is appended as sequenced chunks instead of rewriting commands.output.
+CREATE TABLE IF NOT EXISTS commands.command_output_chunks (
+                                                              command_id INTEGER NOT NULL REFERENCES commands.commands(id) ON DELETE CASCADE,
+                                                              seq INTEGER NOT NULL,
+                                                              byte_offset BIGINT NOT NULL,
+                                                              data BYTEA NOT NULL,
+                                                              created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+                                                              PRIMARY KEY (command_id, seq)
+);
+
+-- Move existing outputs into a single chunk each.
+INSERT INTO commands.command_output_chunks (command_id, seq, byte_offset, data)
+SELECT id, 0, 0, convert_to(output, 'UTF8') FROM commands.commands
+WHERE output IS NOT NULL AND output <> '';
+
+UPDATE commands.commands SET output_size = octet_length(convert_to(output, 'UTF8')), output = ''
+WHERE output IS NOT NULL AND output <> '';
+
+-- Reassembles at most max_bytes of a command output from its chunks.
+CREATE OR REPLACE FUNCTION commands.command_output(cmd_id INTEGER, max_bytes BIGINT)
+    RETURNS BYTEA AS $$
+SELECT substring(COALESCE(string_agg(data, ''::bytea ORDER BY seq), ''::bytea) FROM 1 FOR max_bytes::integer)
+FROM commands.command_output_chunks
+WHERE command_id = cmd_id AND byte_offset < max_bytes;
+$$ LANGUAGE sql STABLE;
@@ -0,0 +1,15 @@
+-- This script removes the output limits during a rollback.
+ALTER TABLE commands.commands DROP COLUMN IF EXISTS output_dropped;
+ALTER TABLE commands.commands DROP COLUMN IF EXISTS output_gap_end;
+ALTER TABLE commands.commands DROP COLUMN IF EXISTS output_gap_start;
+ALTER TABLE commands.commands DROP COLUMN IF EXISTS output_truncated;
+ALTER TABLE commands.commands DROP COLUMN IF EXISTS kill_on_output_limit;
+ALTER TABLE commands.commands DROP COLUMN IF EXISTS output_policy;
+ALTER TABLE commands.commands DROP COLUMN IF EXISTS max_output;
+
+CREATE OR REPLACE FUNCTION commands.command_output(cmd_id INTEGER, max_bytes BIGINT)
+    RETURNS BYTEA AS $$
+SELECT substring(COALESCE(string_agg(data, ''::bytea ORDER BY seq), ''::bytea) FROM 1 FOR max_bytes::integer)
+FROM commands.command_output_chunks
+WHERE command_id = cmd_id AND byte_offset < max_bytes;
+$$ LANGUAGE sql STABLE;
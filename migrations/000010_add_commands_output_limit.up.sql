@@ -0,0 +1,12 @@
+ALTER TABLE commands.commands ADD COLUMN IF NOT EXISTS max_output BIGINT NOT NULL DEFAULT 0;
+ALTER TABLE commands.commands ADD COLUMN IF NOT EXISTS output_policy VARCHAR(16) NOT NULL DEFAULT 'head';
+ALTER TABLE commands.commands ADD COLUMN IF NOT EXISTS kill_on_output_limit BOOLEAN NOT NULL DEFAULT FALSE;
+
+-- A truncated output keeps the stored bytes before output_gap_start and from output_gap_end on.
+ALTER TABLE commands.commands ADD COLUMN IF NOT EXISTS output_truncated BOOLEAN NOT NULL DEFAULT FALSE;
+ALTER TABLE commands.commands ADD COLUMN IF NOT EXISTS output_gap_start BIGINT NOT NULL DEFAULT 0;
+ALTER TABLE commands.commands ADD COLUMN IF NOT EXISTS output_gap_end BIGINT NOT NULL DEFAULT 0;
+ALTER TABLE commands.commands ADD COLUMN IF NOT EXISTS output_dropped BIGINT NOT NULL DEFAULT 0;
+
+-- Output is reassembled by the service, which knows about truncation.
+DROP FUNCTION IF EXISTS commands.command_output(INTEGER, BIGINT);
@@ -0,0 +1,4 @@
+-- This script removes the output archive references during a rollback. Archived outputs stay in the archive directory.
+DROP INDEX IF EXISTS commands.commands_unarchived_idx;
+ALTER TABLE commands.commands DROP COLUMN IF EXISTS output_archived_at;
+ALTER TABLE commands.commands DROP COLUMN IF EXISTS output_archive;
@@ -0,0 +1,5 @@
+-- Reference of the archived output, whose chunks are deleted once it is archived.
+ALTER TABLE commands.commands ADD COLUMN IF NOT EXISTS output_archive TEXT;
+ALTER TABLE commands.commands ADD COLUMN IF NOT EXISTS output_archived_at TIMESTAMP WITH TIME ZONE;
+
+CREATE INDEX IF NOT EXISTS commands_unarchived_idx ON commands.commands (finished_at) WHERE output_archive IS NULL;
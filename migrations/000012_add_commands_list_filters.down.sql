@@ -0,0 +1,9 @@
+-- This script removes the command list filters and their indexes during a rollback.
+DROP INDEX IF EXISTS commands.commands_tags_idx;
+DROP INDEX IF EXISTS commands.commands_submitter_id_idx;
+DROP INDEX IF EXISTS commands.commands_status_id_idx;
+DROP INDEX IF EXISTS commands.commands_updated_at_id_idx;
+DROP INDEX IF EXISTS commands.commands_created_at_id_idx;
+ALTER TABLE commands.commands DROP COLUMN IF EXISTS exit_code;
+ALTER TABLE commands.commands DROP COLUMN IF EXISTS tags;
+ALTER TABLE commands.commands DROP COLUMN IF EXISTS submitter;
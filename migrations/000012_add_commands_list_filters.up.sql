@@ -0,0 +1,10 @@
+ALTER TABLE commands.commands ADD COLUMN IF NOT EXISTS submitter VARCHAR(255) NOT NULL DEFAULT '';
+ALTER TABLE commands.commands ADD COLUMN IF NOT EXISTS tags TEXT[] NOT NULL DEFAULT '{}';
+ALTER TABLE commands.commands ADD COLUMN IF NOT EXISTS exit_code INTEGER;
+
+-- Keyset pagination of the command list by each sort column.
+CREATE INDEX IF NOT EXISTS commands_created_at_id_idx ON commands.commands (created_at, id);
+CREATE INDEX IF NOT EXISTS commands_updated_at_id_idx ON commands.commands (updated_at, id);
+CREATE INDEX IF NOT EXISTS commands_status_id_idx ON commands.commands (status, id);
+CREATE INDEX IF NOT EXISTS commands_submitter_id_idx ON commands.commands (submitter, id);
+CREATE INDEX IF NOT EXISTS commands_tags_idx ON commands.commands USING GIN (tags);
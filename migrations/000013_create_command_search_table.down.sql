@@ -0,0 +1,6 @@
+-- This script removes the command search table, its indexes and the snippet function during a rollback.
+DROP FUNCTION IF EXISTS commands.search_snippet(TEXT, TSQUERY, TEXT);
+DROP INDEX IF EXISTS commands.commands_script_trgm_idx;
+DROP INDEX IF EXISTS commands.commands_script_document_idx;
+DROP TABLE IF EXISTS commands.command_search;
+-- pg_trgm stays installed, it belongs to the whole database and other schemas may use it.
@@ -0,0 +1,56 @@
+-- Installing pg_trgm needs the CREATE privilege on the database (PostgreSQL 13 and later,
+-- superuser before), an extension installed beforehand by an administrator is reused.
+CREATE EXTENSION IF NOT EXISTS pg_trgm;
+
+-- Searchable text of finished command outputs. It is kept apart from the chunks so that
+-- archived outputs stay searchable.
+CREATE TABLE IF NOT EXISTS commands.command_search (
+                                                       command_id INTEGER PRIMARY KEY REFERENCES commands.commands(id) ON DELETE CASCADE,
+                                                       content TEXT NOT NULL,
+                                                       document TSVECTOR GENERATED ALWAYS AS (to_tsvector('simple', content)) STORED
+);
+
+-- Full-text search over words, trigrams for substrings and punctuation.
+CREATE INDEX IF NOT EXISTS command_search_document_idx ON commands.command_search USING GIN (document);
+CREATE INDEX IF NOT EXISTS command_search_content_trgm_idx ON commands.command_search USING GIN (content gin_trgm_ops);
+CREATE INDEX IF NOT EXISTS commands_script_document_idx ON commands.commands USING GIN (to_tsvector('simple', script));
+CREATE INDEX IF NOT EXISTS commands_script_trgm_idx ON commands.commands USING GIN (script gin_trgm_ops);
+
+-- Index the outputs of finished commands, skipping outputs that are not valid text.
+DO $$
+DECLARE
+    r RECORD;
+BEGIN
+    FOR r IN
+        SELECT o.command_id, substring(string_agg(o.data, ''::bytea ORDER BY o.seq) FROM 1 FOR 262144) AS data
+        FROM commands.command_output_chunks o
+        JOIN commands.commands c ON c.id = o.command_id
+        WHERE c.status NOT IN ('waiting', 'running')
+        GROUP BY o.command_id
+    LOOP
+        BEGIN
+            INSERT INTO commands.command_search (command_id, content)
+            VALUES (r.command_id, convert_from(r.data, 'UTF8'))
+            ON CONFLICT (command_id) DO NOTHING;
+        EXCEPTION WHEN character_not_in_repertoire OR untranslatable_character THEN
+            NULL;
+        END;
+    END LOOP;
+END $$;
+
+-- Returns the fragments of content matching query with the matches in <mark> tags, or
+-- the first occurrence of term when only the substring search matched.
+CREATE OR REPLACE FUNCTION commands.search_snippet(content TEXT, query TSQUERY, term TEXT)
+    RETURNS TEXT AS $$
+SELECT CASE
+           WHEN content IS NULL OR content = '' THEN ''
+           WHEN to_tsvector('simple', content) @@ query THEN
+               ts_headline('simple', content, query, 'StartSel=<mark>, StopSel=</mark>, MaxFragments=3, MaxWords=20, MinWords=5')
+           WHEN pos > 0 THEN
+               substring(content FROM greatest(1, pos - 60) FOR least(pos - 1, 60))
+                   || '<mark>' || substring(content FROM pos FOR length(term)) || '</mark>'
+                   || substring(content FROM pos + length(term) FOR 60)
+           ELSE ''
+       END
+FROM (SELECT strpos(lower(content), lower(term)) AS pos) p;
+$$ LANGUAGE sql IMMUTABLE;
@@ -0,0 +1,4 @@
+-- This script removes the labels and annotations of commands during a rollback.
+DROP INDEX IF EXISTS commands.commands_labels_idx;
+ALTER TABLE commands.commands DROP COLUMN IF EXISTS annotations;
+ALTER TABLE commands.commands DROP COLUMN IF EXISTS labels;
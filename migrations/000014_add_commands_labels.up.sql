@@ -0,0 +1,5 @@
+ALTER TABLE commands.commands ADD COLUMN IF NOT EXISTS labels JSONB NOT NULL DEFAULT '{}';
+ALTER TABLE commands.commands ADD COLUMN IF NOT EXISTS annotations JSONB NOT NULL DEFAULT '{}';
+
+-- Label selectors are answered by containment, annotations are not searchable.
+CREATE INDEX IF NOT EXISTS commands_labels_idx ON commands.commands USING GIN (labels jsonb_path_ops);
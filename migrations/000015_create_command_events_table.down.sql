@@ -0,0 +1,2 @@
+-- This script removes the command event history during a rollback.
+DROP TABLE IF EXISTS commands.command_events;
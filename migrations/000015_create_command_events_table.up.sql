@@ -0,0 +1,17 @@
+-- Every status transition of a command, written together with the status change.
+CREATE TABLE IF NOT EXISTS commands.command_events (
+                                                       id BIGSERIAL PRIMARY KEY,
+                                                       command_id INTEGER NOT NULL REFERENCES commands.commands(id) ON DELETE CASCADE,
+                                                       from_status VARCHAR(50),
+                                                       to_status VARCHAR(50) NOT NULL,
+                                                       actor VARCHAR(64) NOT NULL,
+                                                       reason TEXT NOT NULL DEFAULT '',
+                                                       created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS command_events_command_id_idx ON commands.command_events (command_id, id);
+
+-- Existing commands start their history with the status they have now.
+INSERT INTO commands.command_events (command_id, from_status, to_status, actor, reason, created_at)
+SELECT id, NULL, status, 'migration', 'status before the event history', COALESCE(updated_at, CURRENT_TIMESTAMP)
+FROM commands.commands;
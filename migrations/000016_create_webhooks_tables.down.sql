@@ -0,0 +1,6 @@
+-- This script removes webhook subscriptions, deliveries and callback URLs during a rollback.
+DROP TABLE IF EXISTS commands.webhook_cursor;
+DROP TABLE IF EXISTS commands.webhook_attempts;
+DROP TABLE IF EXISTS commands.webhook_deliveries;
+DROP TABLE IF EXISTS commands.webhooks;
+ALTER TABLE commands.commands DROP COLUMN IF EXISTS callback_url;
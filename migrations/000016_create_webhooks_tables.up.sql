@@ -0,0 +1,55 @@
+ALTER TABLE commands.commands ADD COLUMN IF NOT EXISTS callback_url TEXT NOT NULL DEFAULT '';
+
+-- Global subscriptions, an empty events array subscribes to every event.
+CREATE TABLE IF NOT EXISTS commands.webhooks (
+                                                 id SERIAL PRIMARY KEY,
+                                                 url TEXT NOT NULL,
+                                                 secret TEXT NOT NULL DEFAULT '',
+                                                 events TEXT[] NOT NULL DEFAULT '{}',
+                                                 created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+);
+
+-- One delivery per event and subscriber, webhook_id is NULL for the callback_url of a command.
+CREATE TABLE IF NOT EXISTS commands.webhook_deliveries (
+                                                           id BIGSERIAL PRIMARY KEY,
+                                                           webhook_id INTEGER REFERENCES commands.webhooks(id) ON DELETE CASCADE,
+                                                           command_id INTEGER NOT NULL REFERENCES commands.commands(id) ON DELETE CASCADE,
+                                                           event_id BIGINT NOT NULL REFERENCES commands.command_events(id) ON DELETE CASCADE,
+                                                           event VARCHAR(32) NOT NULL,
+                                                           url TEXT NOT NULL,
+                                                           payload JSONB NOT NULL,
+                                                           status VARCHAR(16) NOT NULL DEFAULT 'pending',
+                                                           attempts INTEGER NOT NULL DEFAULT 0,
+                                                           next_attempt_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP,
+                                                           last_status_code INTEGER,
+                                                           last_error TEXT NOT NULL DEFAULT '',
+                                                           created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+                                                           delivered_at TIMESTAMP WITH TIME ZONE
+);
+
+CREATE INDEX IF NOT EXISTS webhook_deliveries_due_idx ON commands.webhook_deliveries (next_attempt_at) WHERE status = 'pending';
+CREATE INDEX IF NOT EXISTS webhook_deliveries_command_id_idx ON commands.webhook_deliveries (command_id, id);
+CREATE INDEX IF NOT EXISTS webhook_deliveries_webhook_id_idx ON commands.webhook_deliveries (webhook_id, id);
+
+CREATE TABLE IF NOT EXISTS commands.webhook_attempts (
+                                                         id BIGSERIAL PRIMARY KEY,
+                                                         delivery_id BIGINT NOT NULL REFERENCES commands.webhook_deliveries(id) ON DELETE CASCADE,
+                                                         attempt INTEGER NOT NULL,
+                                                         status_code INTEGER,
+                                                         error TEXT NOT NULL DEFAULT '',
+                                                         duration_ms INTEGER NOT NULL,
+                                                         created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS webhook_attempts_delivery_id_idx ON commands.webhook_attempts (delivery_id, attempt);
+
+-- The last command event turned into deliveries. The single row is locked while events are
+-- fanned out, so that replicas do not deliver an event twice. Past events are not delivered.
+CREATE TABLE IF NOT EXISTS commands.webhook_cursor (
+                                                       id BOOLEAN PRIMARY KEY DEFAULT TRUE CHECK (id),
+                                                       last_event_id BIGINT NOT NULL
+);
+
+INSERT INTO commands.webhook_cursor (last_event_id)
+SELECT COALESCE(MAX(id), 0) FROM commands.command_events
+ON CONFLICT (id) DO NOTHING;
@@ -0,0 +1,3 @@
+-- This script stops announcing command events during a rollback.
+DROP TRIGGER IF EXISTS command_events_notify ON commands.command_events;
+DROP FUNCTION IF EXISTS commands.notify_command_event;
@@ -0,0 +1,15 @@
+-- Announce every command event on the command_events channel, so that the event stream of
+-- every replica sees the transitions made by the others. The payload is the event ID only,
+-- as NOTIFY payloads are limited to 8000 bytes.
+CREATE OR REPLACE FUNCTION commands.notify_command_event()
+    RETURNS TRIGGER AS $$
+BEGIN
+    PERFORM pg_notify('command_events', NEW.id::text);
+    RETURN NEW;
+END;
+$$ LANGUAGE plpgsql;
+
+DROP TRIGGER IF EXISTS command_events_notify ON commands.command_events;
+CREATE TRIGGER command_events_notify
+    AFTER INSERT ON commands.command_events
+    FOR EACH ROW EXECUTE FUNCTION commands.notify_command_event();
@@ -0,0 +1,2 @@
+-- This script removes the trace context of commands during a rollback.
+ALTER TABLE commands.commands DROP COLUMN IF EXISTS trace_context;
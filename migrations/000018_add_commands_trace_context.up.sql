@@ -0,0 +1,3 @@
+-- W3C traceparent of the span that queued the command, so that the dispatcher and the
+-- execution continue the trace of the creating request on any replica.
+ALTER TABLE commands.commands ADD COLUMN IF NOT EXISTS trace_context TEXT NOT NULL DEFAULT '';
@@ -0,0 +1,8 @@
+// Package migrations embeds the SQL migrations of the database schema into the binary.
+// The files keep the golang-migrate naming, <version>_<name>.up.sql and .down.sql.
+package migrations
+
+import "embed"
+
+//go:embed *.sql
+var FS embed.FS
@@ -0,0 +1,146 @@
+package tests_test
+
+import (
+	"github.com/17HIERARCH70/BashAPI/internal/domain/models"
+	"github.com/17HIERARCH70/BashAPI/internal/handlers"
+	services "github.com/17HIERARCH70/BashAPI/internal/services/command"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestPauseAllQueues(t *testing.T) {
+	mockService := new(MockCommandService)
+	mockService.On("PauseQueue", models.AllQueues, "db maintenance").Return(nil)
+
+	handler := handlers.NewCommandHandlers(mockService, nil)
+	router := gin.Default()
+	router.POST("/admin/pause", handler.PauseAllQueues)
+
+	req, _ := http.NewRequest("POST", "/admin/pause", strings.NewReader(`{"reason":"db maintenance"}`))
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.JSONEq(t, `{"message":"Queue paused","queue":"*"}`, w.Body.String())
+	mockService.AssertExpectations(t)
+}
+
+func TestPauseQueueWithoutBody(t *testing.T) {
+	mockService := new(MockCommandService)
+	mockService.On("PauseQueue", "db", "").Return(nil)
+
+	handler := handlers.NewCommandHandlers(mockService, nil)
+	router := gin.Default()
+	router.POST("/admin/queues/:name/pause", handler.PauseQueue)
+
+	req, _ := http.NewRequest("POST", "/admin/queues/db/pause", nil)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.JSONEq(t, `{"message":"Queue paused","queue":"db"}`, w.Body.String())
+	mockService.AssertExpectations(t)
+}
+
+func TestPauseQueueInvalidName(t *testing.T) {
+	handler := handlers.NewCommandHandlers(new(MockCommandService), nil)
+	router := gin.Default()
+	router.POST("/admin/queues/:name/pause", handler.PauseQueue)
+
+	req, _ := http.NewRequest("POST", "/admin/queues/*/pause", nil)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+}
+
+func TestResumeQueueNotPaused(t *testing.T) {
+	mockService := new(MockCommandService)
+	mockService.On("ResumeQueue", "db").Return(services.ErrQueueNotPaused)
+
+	handler := handlers.NewCommandHandlers(mockService, nil)
+	router := gin.Default()
+	router.POST("/admin/queues/:name/resume", handler.ResumeQueue)
+
+	req, _ := http.NewRequest("POST", "/admin/queues/db/resume", nil)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusConflict, w.Code)
+	assert.JSONEq(t, `{"error":"Queue is not paused"}`, w.Body.String())
+	mockService.AssertExpectations(t)
+}
+
+func TestResumeAllQueues(t *testing.T) {
+	mockService := new(MockCommandService)
+	mockService.On("ResumeQueue", models.AllQueues).Return(nil)
+
+	handler := handlers.NewCommandHandlers(mockService, nil)
+	router := gin.Default()
+	router.POST("/admin/resume", handler.ResumeAllQueues)
+
+	req, _ := http.NewRequest("POST", "/admin/resume", nil)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.JSONEq(t, `{"message":"Queue resumed","queue":"*"}`, w.Body.String())
+	mockService.AssertExpectations(t)
+}
+
+func TestGetQueuePauses(t *testing.T) {
+	mockService := new(MockCommandService)
+	pausedAt := time.Date(2024, 5, 1, 10, 0, 0, 0, time.UTC)
+	mockService.On("FetchQueuePauses").Return([]models.QueuePause{{Queue: "db", Reason: "vacuum", PausedAt: pausedAt}}, nil)
+
+	handler := handlers.NewCommandHandlers(mockService, nil)
+	router := gin.Default()
+	router.GET("/admin/pauses", handler.GetQueuePauses)
+
+	req, _ := http.NewRequest("GET", "/admin/pauses", nil)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.JSONEq(t, `[{"queue":"db","reason":"vacuum","paused_at":"2024-05-01T10:00:00Z"}]`, w.Body.String())
+	mockService.AssertExpectations(t)
+}
+
+func TestPurgeCommandsDryRun(t *testing.T) {
+	mockService := new(MockCommandService)
+	filter := models.PurgeFilter{Queue: "deploy", Statuses: []string{"completed"}, KeepLast: 10, DryRun: true}
+	mockService.On("PurgeCommands", filter).Return(models.PurgeResult{DryRun: true, Count: 2, IDs: []int{3, 4}, ArchivedOutputs: 1}, nil)
+
+	handler := handlers.NewCommandHandlers(mockService, nil)
+	router := gin.Default()
+	router.POST("/admin/purge", handler.PurgeCommands)
+
+	req, _ := http.NewRequest("POST", "/admin/purge", strings.NewReader(`{"queue":"deploy","statuses":["completed"],"keep_last":10,"dry_run":true}`))
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.JSONEq(t, `{"dry_run":true,"count":2,"ids":[3,4],"archived_outputs":1}`, w.Body.String())
+	mockService.AssertExpectations(t)
+}
+
+func TestPurgeCommandsRequiresFilter(t *testing.T) {
+	mockService := new(MockCommandService)
+	handler := handlers.NewCommandHandlers(mockService, nil)
+	router := gin.Default()
+	router.POST("/admin/purge", handler.PurgeCommands)
+
+	for _, body := range []string{`{}`, `{"dry_run":true}`, `{"all":true,"keep_last":-1}`} {
+		req, _ := http.NewRequest("POST", "/admin/purge", strings.NewReader(body))
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusBadRequest, w.Code, body)
+	}
+	mockService.AssertNotCalled(t, "PurgeCommands", models.PurgeFilter{})
+}
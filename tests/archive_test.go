@@ -0,0 +1,68 @@
+package tests_test
+
+import (
+	"github.com/17HIERARCH70/BashAPI/internal/lib/archive"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestFileStoreRoundTrip(t *testing.T) {
+	for _, compression := range []string{archive.Zstd, archive.Gzip} {
+		dir := t.TempDir()
+		store, err := archive.NewFileStore(dir, compression)
+		require.NoError(t, err)
+
+		content := strings.Repeat("line of output\n", 1000)
+		ref, err := store.Put("2026-10/command-7.out", strings.NewReader(content))
+		require.NoError(t, err, compression)
+
+		info, err := os.Stat(filepath.Join(dir, ref))
+		require.NoError(t, err)
+		assert.Less(t, info.Size(), int64(len(content)), compression)
+
+		reader, err := store.Open(ref)
+		require.NoError(t, err)
+		restored, err := io.ReadAll(reader)
+		require.NoError(t, err)
+		require.NoError(t, reader.Close())
+		assert.Equal(t, content, string(restored), compression)
+
+		require.NoError(t, store.Delete(ref))
+		_, err = store.Open(ref)
+		assert.ErrorIs(t, err, os.ErrNotExist)
+		assert.NoError(t, store.Delete(ref))
+	}
+}
+
+func TestFileStoreReadsOtherCompression(t *testing.T) {
+	dir := t.TempDir()
+	gzipStore, err := archive.NewFileStore(dir, archive.Gzip)
+	require.NoError(t, err)
+	ref, err := gzipStore.Put("command-1.out", strings.NewReader("hello"))
+	require.NoError(t, err)
+
+	// Switching the configured compression keeps older archives readable
+	zstdStore, err := archive.NewFileStore(dir, archive.Zstd)
+	require.NoError(t, err)
+	reader, err := zstdStore.Open(ref)
+	require.NoError(t, err)
+	defer reader.Close()
+	restored, err := io.ReadAll(reader)
+	require.NoError(t, err)
+	assert.Equal(t, "hello", string(restored))
+}
+
+func TestFileStoreRejectsInvalidInput(t *testing.T) {
+	_, err := archive.NewFileStore(t.TempDir(), "lz4")
+	assert.ErrorIs(t, err, archive.ErrUnknownCompression)
+
+	store, err := archive.NewFileStore(t.TempDir(), archive.Zstd)
+	require.NoError(t, err)
+	_, err = store.Open("../outside.zst")
+	assert.Error(t, err)
+}
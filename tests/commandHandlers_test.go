@@ -2,6 +2,7 @@ package tests_test
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"errors"
 	"fmt"
@@ -14,6 +15,7 @@ import (
 	"net/http/httptest"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 )
@@ -23,17 +25,42 @@ type MockCommandService struct {
 	services.ICommandService
 }
 
-func (m *MockCommandService) ProcessCommand(script string) (gin.H, error) {
-	args := m.Called(script)
+func (m *MockCommandService) ProcessCommand(script string, opts models.CommandOptions) (gin.H, error) {
+	args := m.Called(script, opts)
 	if args.Get(0) != nil {
 		return args.Get(0).(gin.H), args.Error(1)
 	}
 	return gin.H{}, args.Error(1)
 }
 
-func (m *MockCommandService) FetchCommands() ([]models.Command, error) {
-	args := m.Called()
-	return args.Get(0).([]models.Command), args.Error(1)
+func (m *MockCommandService) FetchCommands(query models.CommandQuery) (models.CommandPage, error) {
+	args := m.Called(query)
+	return args.Get(0).(models.CommandPage), args.Error(1)
+}
+
+func (m *MockCommandService) SearchCommands(query models.SearchQuery) (models.SearchPage, error) {
+	args := m.Called(query)
+	return args.Get(0).(models.SearchPage), args.Error(1)
+}
+
+func (m *MockCommandService) StopCommands(filter models.SelectorFilter) ([]int, error) {
+	args := m.Called(filter)
+	return args.Get(0).([]int), args.Error(1)
+}
+
+func (m *MockCommandService) UpdateLabels(id int, update models.LabelUpdate) (models.CommandLabels, error) {
+	args := m.Called(id, update)
+	return args.Get(0).(models.CommandLabels), args.Error(1)
+}
+
+func (m *MockCommandService) FetchCommandEvents(id int) ([]models.CommandEvent, error) {
+	args := m.Called(id)
+	return args.Get(0).([]models.CommandEvent), args.Error(1)
+}
+
+func (m *MockCommandService) WaitForCommand(ctx context.Context, id int, timeout time.Duration) (models.Command, bool, error) {
+	args := m.Called(ctx, id, timeout)
+	return args.Get(0).(models.Command), args.Bool(1), args.Error(2)
 }
 
 func (m *MockCommandService) FetchCommandByID(id int) (models.Command, error) {
@@ -64,9 +91,99 @@ func (m *MockCommandService) StopAllRunningCommands() error {
 	return args.Error(0)
 }
 
+func (m *MockCommandService) FetchCapacity() (models.Capacity, error) {
+	args := m.Called()
+	return args.Get(0).(models.Capacity), args.Error(1)
+}
+
+func (m *MockCommandService) FetchAdmission() models.Admission {
+	args := m.Called()
+	return args.Get(0).(models.Admission)
+}
+
+func (m *MockCommandService) PauseQueue(name, reason string) error {
+	args := m.Called(name, reason)
+	return args.Error(0)
+}
+
+func (m *MockCommandService) ResumeQueue(name string) error {
+	args := m.Called(name)
+	return args.Error(0)
+}
+
+func (m *MockCommandService) FetchQueuePauses() ([]models.QueuePause, error) {
+	args := m.Called()
+	return args.Get(0).([]models.QueuePause), args.Error(1)
+}
+
+func (m *MockCommandService) CancelCommand(id int) error {
+	args := m.Called(id)
+	return args.Error(0)
+}
+
+func (m *MockCommandService) MoveQueuedCommand(id, position int) error {
+	args := m.Called(id, position)
+	return args.Error(0)
+}
+
+func (m *MockCommandService) CancelQueuedCommands(filter models.QueueFilter) ([]int, error) {
+	args := m.Called(filter)
+	return args.Get(0).([]int), args.Error(1)
+}
+
+func (m *MockCommandService) DeleteCommand(id int) error {
+	args := m.Called(id)
+	return args.Error(0)
+}
+
+func (m *MockCommandService) PurgeCommands(filter models.PurgeFilter) (models.PurgeResult, error) {
+	args := m.Called(filter)
+	return args.Get(0).(models.PurgeResult), args.Error(1)
+}
+
+func (m *MockCommandService) FetchCommandOutput(id int, query models.OutputQuery) (models.CommandOutput, error) {
+	args := m.Called(id, query)
+	return args.Get(0).(models.CommandOutput), args.Error(1)
+}
+
+func (m *MockCommandService) CreateWebhook(hook models.Webhook) (models.Webhook, error) {
+	args := m.Called(hook)
+	return args.Get(0).(models.Webhook), args.Error(1)
+}
+
+func (m *MockCommandService) FetchWebhooks() ([]models.Webhook, error) {
+	args := m.Called()
+	return args.Get(0).([]models.Webhook), args.Error(1)
+}
+
+func (m *MockCommandService) DeleteWebhook(id int) error {
+	args := m.Called(id)
+	return args.Error(0)
+}
+
+func (m *MockCommandService) FetchDeliveries(query models.DeliveryQuery) ([]models.WebhookDelivery, error) {
+	args := m.Called(query)
+	return args.Get(0).([]models.WebhookDelivery), args.Error(1)
+}
+
+func (m *MockCommandService) SubscribeEvents(filter models.EventFilter) (<-chan models.LifecycleEvent, func()) {
+	args := m.Called(filter)
+	return args.Get(0).(chan models.LifecycleEvent), func() {}
+}
+
+func (m *MockCommandService) ReplayEvents(filter models.EventFilter) ([]models.LifecycleEvent, error) {
+	args := m.Called(filter)
+	return args.Get(0).([]models.LifecycleEvent), args.Error(1)
+}
+
+func (m *MockCommandService) CheckReadiness(ctx context.Context) []models.HealthCheck {
+	args := m.Called(ctx)
+	return args.Get(0).([]models.HealthCheck)
+}
+
 func TestCreateCommand(t *testing.T) {
 	mockService := new(MockCommandService)
-	mockService.On("ProcessCommand", "echo 'Hello, World!'").Return(gin.H{"message": "Command is being executed"}, nil)
+	mockService.On("ProcessCommand", "echo 'Hello, World!'", models.CommandOptions{}).Return(gin.H{"message": "Command is being executed"}, nil)
 
 	handler := handlers.NewCommandHandlers(mockService, nil) // Logger is nil for simplicity
 
@@ -88,7 +205,7 @@ func TestCreateCommand(t *testing.T) {
 func TestCreateCommandFailure(t *testing.T) {
 	mockService := new(MockCommandService)
 	// Ensure a non-nil gin.H{} is returned even when the operation is meant to fail
-	mockService.On("ProcessCommand", "fail command").Return(gin.H{}, errors.New("command processing failed"))
+	mockService.On("ProcessCommand", "fail command", models.CommandOptions{}).Return(gin.H{}, errors.New("command processing failed"))
 
 	handler := handlers.NewCommandHandlers(mockService, nil)
 	router := gin.Default()
@@ -128,7 +245,7 @@ func TestCreateSudoCommandInvalidInput(t *testing.T) {
 func TestGetCommandsList(t *testing.T) {
 	mockService := new(MockCommandService)
 	commands := []models.Command{{ID: 1, Script: "echo 'Hello'"}}
-	mockService.On("FetchCommands").Return(commands, nil)
+	mockService.On("FetchCommands", models.CommandQuery{Sort: models.SortByID}).Return(models.CommandPage{Items: commands}, nil)
 
 	handler := handlers.NewCommandHandlers(mockService, nil)
 	router := gin.Default()
@@ -138,7 +255,7 @@ func TestGetCommandsList(t *testing.T) {
 	w := httptest.NewRecorder()
 	router.ServeHTTP(w, req)
 
-	expectedBody, _ := json.Marshal(commands)
+	expectedBody := listedJSON(t, commands)
 	assert.Equal(t, http.StatusOK, w.Code)
 	assert.JSONEq(t, string(expectedBody), w.Body.String())
 	mockService.AssertExpectations(t)
@@ -150,7 +267,7 @@ func TestGetCommandsList_Success(t *testing.T) {
 		{ID: 1, Script: "echo Hello World", Status: "success"},
 		{ID: 2, Script: "ls -l", Status: "pending"},
 	}
-	mockService.On("FetchCommands").Return(expectedCommands, nil)
+	mockService.On("FetchCommands", models.CommandQuery{Sort: models.SortByID}).Return(models.CommandPage{Items: expectedCommands}, nil)
 
 	handler := handlers.NewCommandHandlers(mockService, nil)
 	router := gin.Default()
@@ -160,7 +277,7 @@ func TestGetCommandsList_Success(t *testing.T) {
 	w := httptest.NewRecorder()
 	router.ServeHTTP(w, req)
 
-	expectedJSON, _ := json.Marshal(expectedCommands)
+	expectedJSON := listedJSON(t, expectedCommands)
 	assert.Equal(t, http.StatusOK, w.Code)
 	assert.JSONEq(t, string(expectedJSON), w.Body.String())
 	mockService.AssertExpectations(t)
@@ -221,8 +338,9 @@ func TestForceStartCommand(t *testing.T) {
 
 func TestGetQueueList(t *testing.T) {
 	mockService := new(MockCommandService)
-	queue := []models.Queue{{QueueId: 1, CommandId: 2, Status: "waiting"}}
+	queue := []models.Queue{{QueueId: 1, CommandId: 2, Status: "waiting", Weight: 1}}
 	mockService.On("FetchQueueList").Return(queue, nil)
+	mockService.On("FetchCapacity").Return(models.Capacity{Total: 4, Used: 3, Available: 1}, nil)
 
 	handler := handlers.NewCommandHandlers(mockService, nil)
 	router := gin.Default()
@@ -235,6 +353,44 @@ func TestGetQueueList(t *testing.T) {
 	expectedBody, _ := json.Marshal(queue)
 	assert.Equal(t, http.StatusOK, w.Code)
 	assert.JSONEq(t, string(expectedBody), w.Body.String())
+	assert.Equal(t, "4", w.Header().Get("X-Capacity-Total"))
+	assert.Equal(t, "3", w.Header().Get("X-Capacity-Used"))
+	assert.Equal(t, "1", w.Header().Get("X-Capacity-Available"))
+	mockService.AssertExpectations(t)
+}
+
+func TestGetQueueListCapacityError(t *testing.T) {
+	mockService := new(MockCommandService)
+	mockService.On("FetchQueueList").Return([]models.Queue{}, nil)
+	mockService.On("FetchCapacity").Return(models.Capacity{}, errors.New("database error"))
+
+	handler := handlers.NewCommandHandlers(mockService, nil)
+	router := gin.Default()
+	router.GET("/commands/queue", handler.GetQueueList)
+
+	req, _ := http.NewRequest("GET", "/commands/queue", nil)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusInternalServerError, w.Code)
+	assert.Empty(t, w.Header().Get("X-Capacity-Used"))
+	mockService.AssertExpectations(t)
+}
+
+func TestGetCapacity(t *testing.T) {
+	mockService := new(MockCommandService)
+	mockService.On("FetchCapacity").Return(models.Capacity{Total: 4, Used: 3, Available: 1}, nil)
+
+	handler := handlers.NewCommandHandlers(mockService, nil)
+	router := gin.Default()
+	router.GET("/commands/capacity", handler.GetCapacity)
+
+	req, _ := http.NewRequest("GET", "/commands/capacity", nil)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.JSONEq(t, `{"total":4,"used":3,"available":1}`, w.Body.String())
 	mockService.AssertExpectations(t)
 }
 
@@ -289,7 +445,7 @@ func TestForceStartCommandFailure(t *testing.T) {
 
 func TestProcessCommandDBFailure(t *testing.T) {
 	mockService := new(MockCommandService)
-	mockService.On("ProcessCommand", "db fail").Return(nil, errors.New("database connection failed"))
+	mockService.On("ProcessCommand", "db fail", models.CommandOptions{}).Return(nil, errors.New("database connection failed"))
 
 	handler := handlers.NewCommandHandlers(mockService, nil)
 	router := gin.Default()
@@ -309,7 +465,7 @@ func TestProcessCommandDBFailure(t *testing.T) {
 func TestCreateCommandLongScript(t *testing.T) {
 	longScript := strings.Repeat("echo 'hello';", 1000) // A very long script
 	mockService := new(MockCommandService)
-	mockService.On("ProcessCommand", longScript).Return(gin.H{"message": "Long command processed"}, nil)
+	mockService.On("ProcessCommand", longScript, models.CommandOptions{}).Return(gin.H{"message": "Long command processed"}, nil)
 
 	handler := handlers.NewCommandHandlers(mockService, nil)
 	router := gin.Default()
@@ -347,7 +503,7 @@ func TestFetchCommandByIDDoesNotExist(t *testing.T) {
 func TestCreateSudoCommandWithSudo(t *testing.T) {
 	sudoScript := "sudo ls"
 	mockService := new(MockCommandService)
-	mockService.On("ProcessCommand", sudoScript).Return(gin.H{"message": "Sudo command executed"}, nil)
+	mockService.On("ProcessCommand", sudoScript, models.CommandOptions{}).Return(gin.H{"message": "Sudo command executed"}, nil)
 
 	handler := handlers.NewCommandHandlers(mockService, nil)
 	router := gin.Default()
@@ -367,7 +523,7 @@ func TestCreateSudoCommandWithSudo(t *testing.T) {
 // Assume this test simulates a server error scenario such as a crash or misconfiguration
 func TestInternalServerError(t *testing.T) {
 	mockService := new(MockCommandService)
-	mockService.On("ProcessCommand", "crash command").Return(nil, errors.New("internal server error"))
+	mockService.On("ProcessCommand", "crash command", models.CommandOptions{}).Return(nil, errors.New("internal server error"))
 
 	handler := handlers.NewCommandHandlers(mockService, nil)
 	router := gin.Default()
@@ -448,7 +604,7 @@ func TestCreateSudoCommandInternalError(t *testing.T) {
 	mockService := new(MockCommandService)
 	script := "sudo reboot" // This should match the script you expect to trigger an internal error
 
-	mockService.On("ProcessCommand", script).Return(nil, errors.New("internal server error"))
+	mockService.On("ProcessCommand", script, models.CommandOptions{}).Return(nil, errors.New("internal server error"))
 
 	handler := handlers.NewCommandHandlers(mockService, nil)
 	router := gin.Default()
@@ -466,7 +622,7 @@ func TestCreateSudoCommandInternalError(t *testing.T) {
 }
 func TestGetCommandsListServiceError(t *testing.T) {
 	mockService := new(MockCommandService)
-	mockService.On("FetchCommands").Return(([]models.Command)(nil), errors.New("database error")) // Correctly handle nil slices
+	mockService.On("FetchCommands", models.CommandQuery{Sort: models.SortByID}).Return(models.CommandPage{}, errors.New("database error"))
 
 	handler := handlers.NewCommandHandlers(mockService, nil)
 	router := gin.Default()
@@ -620,3 +776,320 @@ func TestForceStartCommandInternalError(t *testing.T) {
 	assert.Equal(t, http.StatusInternalServerError, w.Code)
 	assert.JSONEq(t, `{"error":"internal error"}`, w.Body.String())
 }
+
+func TestCreateCommandWithIdempotencyKey(t *testing.T) {
+	mockService := new(MockCommandService)
+	opts := models.CommandOptions{IdempotencyKey: "deploy-42"}
+	mockService.On("ProcessCommand", "echo deploy", opts).Return(gin.H{"message": "Command is being executed", "id": 7}, nil)
+
+	handler := handlers.NewCommandHandlers(mockService, nil)
+	router := gin.Default()
+	router.POST("/commands", handler.CreateCommand)
+
+	body, _ := json.Marshal(gin.H{"script": "echo deploy"})
+	req, _ := http.NewRequest("POST", "/commands", bytes.NewBuffer(body))
+	req.Header.Set("Idempotency-Key", "deploy-42")
+	w := httptest.NewRecorder()
+
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusAccepted, w.Code)
+	assert.JSONEq(t, `{"message":"Command is being executed","id":7}`, w.Body.String())
+	mockService.AssertExpectations(t)
+}
+
+func TestCreateCommandIdempotencyConflict(t *testing.T) {
+	mockService := new(MockCommandService)
+	opts := models.CommandOptions{IdempotencyKey: "deploy-42"}
+	mockService.On("ProcessCommand", "echo other", opts).Return(nil, services.ErrIdempotencyConflict)
+
+	handler := handlers.NewCommandHandlers(mockService, nil)
+	router := gin.Default()
+	router.POST("/commands/sudo", handler.CreateSudoCommand)
+
+	body, _ := json.Marshal(gin.H{"script": "echo other"})
+	req, _ := http.NewRequest("POST", "/commands/sudo", bytes.NewBuffer(body))
+	req.Header.Set("Idempotency-Key", "deploy-42")
+	w := httptest.NewRecorder()
+
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusConflict, w.Code)
+	assert.JSONEq(t, fmt.Sprintf(`{"error":%q}`, services.ErrIdempotencyConflict.Error()), w.Body.String())
+	mockService.AssertExpectations(t)
+}
+
+func TestCreateCommandIdempotencyKeyTooLong(t *testing.T) {
+	handler := handlers.NewCommandHandlers(new(MockCommandService), nil)
+	router := gin.Default()
+	router.POST("/commands", handler.CreateCommand)
+
+	body, _ := json.Marshal(gin.H{"script": "echo deploy"})
+	req, _ := http.NewRequest("POST", "/commands", bytes.NewBuffer(body))
+	req.Header.Set("Idempotency-Key", strings.Repeat("k", 256))
+	w := httptest.NewRecorder()
+
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.JSONEq(t, `{"error":"Idempotency-Key is too long"}`, w.Body.String())
+}
+
+func TestCreateCommandWithLocks(t *testing.T) {
+	mockService := new(MockCommandService)
+	opts := models.CommandOptions{Locks: []models.CommandLock{{Key: "db-main"}, {Key: "backups", Mode: "shared"}}}
+	mockService.On("ProcessCommand", "pg_dump main", opts).Return(gin.H{"message": "Command is being queued", "id": 3}, nil)
+
+	handler := handlers.NewCommandHandlers(mockService, nil)
+	router := gin.Default()
+	router.POST("/commands", handler.CreateCommand)
+
+	body := `{"script":"pg_dump main","locks":[{"key":"db-main"},{"key":"backups","mode":"shared"}]}`
+	req, _ := http.NewRequest("POST", "/commands", strings.NewReader(body))
+	w := httptest.NewRecorder()
+
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusAccepted, w.Code)
+	assert.JSONEq(t, `{"message":"Command is being queued","id":3}`, w.Body.String())
+	mockService.AssertExpectations(t)
+}
+
+func TestCreateCommandInvalidLocks(t *testing.T) {
+	cases := map[string]string{
+		`{"script":"ls","locks":[{"key":""}]}`:                                "Lock key must be between 1 and 255 characters",
+		`{"script":"ls","locks":[{"key":"db","mode":"read"}]}`:                "Lock mode must be 'exclusive' or 'shared'",
+		`{"script":"ls","locks":[{"key":"db"},{"key":"db","mode":"shared"}]}`: "Lock 'db' is declared more than once",
+	}
+
+	handler := handlers.NewCommandHandlers(new(MockCommandService), nil)
+	router := gin.Default()
+	router.POST("/commands", handler.CreateCommand)
+
+	for body, message := range cases {
+		req, _ := http.NewRequest("POST", "/commands", strings.NewReader(body))
+		w := httptest.NewRecorder()
+
+		router.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+		assert.JSONEq(t, fmt.Sprintf(`{"error":%q}`, message), w.Body.String())
+	}
+}
+
+func TestGetQueueListShowsBlockingCommand(t *testing.T) {
+	mockService := new(MockCommandService)
+	holder := 4
+	queue := []models.Queue{{QueueId: 1, CommandId: 5, Status: "waiting", Weight: 1, BlockedBy: &holder}}
+	mockService.On("FetchQueueList").Return(queue, nil)
+	mockService.On("FetchCapacity").Return(models.Capacity{Total: 2, Used: 1, Available: 1}, nil)
+
+	handler := handlers.NewCommandHandlers(mockService, nil)
+	router := gin.Default()
+	router.GET("/commands/queue", handler.GetQueueList)
+
+	req, _ := http.NewRequest("GET", "/commands/queue", nil)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	expectedBody, _ := json.Marshal(queue)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.JSONEq(t, string(expectedBody), w.Body.String())
+	assert.Contains(t, w.Body.String(), `"BlockedBy":4`)
+	mockService.AssertExpectations(t)
+}
+
+func TestCreateCommandWithWeight(t *testing.T) {
+	mockService := new(MockCommandService)
+	mockService.On("ProcessCommand", "pg_dump main", models.CommandOptions{Weight: 4}).Return(gin.H{"message": "Command is being queued", "id": 9}, nil)
+
+	handler := handlers.NewCommandHandlers(mockService, nil)
+	router := gin.Default()
+	router.POST("/commands", handler.CreateCommand)
+
+	req, _ := http.NewRequest("POST", "/commands", strings.NewReader(`{"script":"pg_dump main","weight":4}`))
+	w := httptest.NewRecorder()
+
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusAccepted, w.Code)
+	assert.JSONEq(t, `{"message":"Command is being queued","id":9}`, w.Body.String())
+	mockService.AssertExpectations(t)
+}
+
+func TestCreateCommandWeightExceedsCapacity(t *testing.T) {
+	mockService := new(MockCommandService)
+	mockService.On("ProcessCommand", "pg_dump main", models.CommandOptions{Weight: 500}).Return(nil, services.ErrWeightExceedsCapacity)
+
+	handler := handlers.NewCommandHandlers(mockService, nil)
+	router := gin.Default()
+	router.POST("/commands", handler.CreateCommand)
+
+	req, _ := http.NewRequest("POST", "/commands", strings.NewReader(`{"script":"pg_dump main","weight":500}`))
+	w := httptest.NewRecorder()
+
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.JSONEq(t, `{"error":"command weight exceeds the total capacity"}`, w.Body.String())
+	mockService.AssertExpectations(t)
+}
+
+func TestCreateCommandNegativeWeight(t *testing.T) {
+	handler := handlers.NewCommandHandlers(new(MockCommandService), nil)
+	router := gin.Default()
+	router.POST("/commands", handler.CreateCommand)
+
+	req, _ := http.NewRequest("POST", "/commands", strings.NewReader(`{"script":"ls","weight":-1}`))
+	w := httptest.NewRecorder()
+
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.JSONEq(t, `{"error":"Weight must be a positive number"}`, w.Body.String())
+}
+
+func TestGetAdmission(t *testing.T) {
+	mockService := new(MockCommandService)
+	admission := models.Admission{
+		Enabled: true,
+		Admit:   false,
+		Reasons: []string{"load per CPU 3.10 exceeds 2.00"},
+		Metrics: &models.HostMetrics{CPUs: 2, Load1: 6.2, LoadPerCPU: 3.1},
+	}
+	mockService.On("FetchAdmission").Return(admission)
+
+	handler := handlers.NewCommandHandlers(mockService, nil)
+	router := gin.Default()
+	router.GET("/commands/admission", handler.GetAdmission)
+
+	req, _ := http.NewRequest("GET", "/commands/admission", nil)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	expectedBody, _ := json.Marshal(admission)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.JSONEq(t, string(expectedBody), w.Body.String())
+	mockService.AssertExpectations(t)
+}
+
+func TestCreateCommandInvalidQueueName(t *testing.T) {
+	handler := handlers.NewCommandHandlers(new(MockCommandService), nil)
+	router := gin.Default()
+	router.POST("/commands", handler.CreateCommand)
+
+	req, _ := http.NewRequest("POST", "/commands", strings.NewReader(`{"script":"ls","queue":"db maintenance"}`))
+	w := httptest.NewRecorder()
+
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.JSONEq(t, `{"error":"Queue name must be 1-64 letters, digits, '_', '.' or '-'"}`, w.Body.String())
+}
+
+func TestCreateCommandQueuedBehindPause(t *testing.T) {
+	mockService := new(MockCommandService)
+	response := gin.H{"message": "Command is queued behind a paused queue", "id": 12, "queue": "db", "paused": true, "reason": "vacuum"}
+	mockService.On("ProcessCommand", "psql -c 'select 1'", models.CommandOptions{Queue: "db"}).Return(response, nil)
+
+	handler := handlers.NewCommandHandlers(mockService, nil)
+	router := gin.Default()
+	router.POST("/commands", handler.CreateCommand)
+
+	req, _ := http.NewRequest("POST", "/commands", strings.NewReader(`{"script":"psql -c 'select 1'","queue":"db"}`))
+	w := httptest.NewRecorder()
+
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusAccepted, w.Code)
+	assert.JSONEq(t, `{"message":"Command is queued behind a paused queue","id":12,"queue":"db","paused":true,"reason":"vacuum"}`, w.Body.String())
+	mockService.AssertExpectations(t)
+}
+
+func TestCreateCommandWithOutputLimit(t *testing.T) {
+	mockService := new(MockCommandService)
+	opts := models.CommandOptions{MaxOutput: 1024, OutputPolicy: models.OutputKeepHeadTail, KillOnOutputLimit: true}
+	mockService.On("ProcessCommand", "yes", opts).Return(gin.H{"message": "Command is being executed", "id": 3}, nil)
+
+	handler := handlers.NewCommandHandlers(mockService, nil)
+	router := gin.Default()
+	router.POST("/commands", handler.CreateCommand)
+
+	req, _ := http.NewRequest("POST", "/commands", strings.NewReader(`{"script":"yes","max_output":1024,"output_policy":"head_tail","kill_on_output_limit":true}`))
+	w := httptest.NewRecorder()
+
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusAccepted, w.Code)
+	mockService.AssertExpectations(t)
+}
+
+func TestCreateCommandOutputLimitAboveCeiling(t *testing.T) {
+	mockService := new(MockCommandService)
+	mockService.On("ProcessCommand", "yes", models.CommandOptions{MaxOutput: 1 << 40}).Return(nil, services.ErrOutputLimitTooLarge)
+
+	handler := handlers.NewCommandHandlers(mockService, nil)
+	router := gin.Default()
+	router.POST("/commands", handler.CreateCommand)
+
+	req, _ := http.NewRequest("POST", "/commands", strings.NewReader(`{"script":"yes","max_output":1099511627776}`))
+	w := httptest.NewRecorder()
+
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.JSONEq(t, `{"error":"max_output exceeds the configured output size limit"}`, w.Body.String())
+	mockService.AssertExpectations(t)
+}
+
+func TestCreateCommandInvalidOutputPolicy(t *testing.T) {
+	handler := handlers.NewCommandHandlers(new(MockCommandService), nil)
+	router := gin.Default()
+	router.POST("/commands", handler.CreateCommand)
+
+	req, _ := http.NewRequest("POST", "/commands", strings.NewReader(`{"script":"yes","output_policy":"middle"}`))
+	w := httptest.NewRecorder()
+
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.JSONEq(t, `{"error":"Output policy must be 'head', 'tail' or 'head_tail'"}`, w.Body.String())
+}
+
+func TestDeleteCommand(t *testing.T) {
+	mockService := new(MockCommandService)
+	mockService.On("DeleteCommand", 5).Return(nil)
+
+	handler := handlers.NewCommandHandlers(mockService, nil)
+	router := gin.Default()
+	router.DELETE("/commands/:id", handler.DeleteCommand)
+
+	req, _ := http.NewRequest("DELETE", "/commands/5", nil)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.JSONEq(t, `{"message":"Command deleted","id":5}`, w.Body.String())
+	mockService.AssertExpectations(t)
+}
+
+func TestDeleteRunningCommand(t *testing.T) {
+	mockService := new(MockCommandService)
+	mockService.On("DeleteCommand", 5).Return(services.ErrCommandRunning)
+	mockService.On("DeleteCommand", 6).Return(services.ErrNotFound)
+
+	handler := handlers.NewCommandHandlers(mockService, nil)
+	router := gin.Default()
+	router.DELETE("/commands/:id", handler.DeleteCommand)
+
+	req, _ := http.NewRequest("DELETE", "/commands/5", nil)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusConflict, w.Code)
+
+	req, _ = http.NewRequest("DELETE", "/commands/6", nil)
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusNotFound, w.Code)
+	mockService.AssertExpectations(t)
+}
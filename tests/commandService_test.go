@@ -0,0 +1,386 @@
+package tests_test
+
+import (
+	"context"
+	"github.com/17HIERARCH70/BashAPI/internal/config"
+	"github.com/17HIERARCH70/BashAPI/internal/domain/models"
+	"github.com/17HIERARCH70/BashAPI/internal/lib/archive"
+	"github.com/17HIERARCH70/BashAPI/internal/lib/webhook"
+	services "github.com/17HIERARCH70/BashAPI/internal/services/command"
+	"github.com/17HIERARCH70/BashAPI/internal/storage/memory"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+// newMemoryService runs a command service on the memory storage with the given capacity.
+// Commands still running at the end of the test are stopped.
+func newMemoryService(t *testing.T, capacity int) *services.CommandService {
+	cfg := &config.Config{}
+	cfg.Commands.MaxConcurrent = capacity
+	cfg.Commands.Timeout = 30
+	cfg.Commands.MaxWait = 30
+	service := services.NewCommandService(memory.NewStore(), slog.New(slog.NewTextHandler(io.Discard, nil)), cfg)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	service.StartDispatcher(ctx)
+	t.Cleanup(func() {
+		cancel()
+		_ = service.StopAllRunningCommands()
+	})
+	return service
+}
+
+func createCommand(t *testing.T, service *services.CommandService, script string, opts models.CommandOptions) int {
+	response, err := service.ProcessCommand(script, opts)
+	require.NoError(t, err)
+	return response["id"].(int)
+}
+
+func waitForCommand(t *testing.T, service *services.CommandService, id int) models.Command {
+	command, finished, err := service.WaitForCommand(context.Background(), id, 10*time.Second)
+	require.NoError(t, err)
+	require.True(t, finished, "command %d is still %s", id, command.Status)
+	return command
+}
+
+func queuedIDs(t *testing.T, service *services.CommandService) []int {
+	queue, err := service.FetchQueueList()
+	require.NoError(t, err)
+	ids := []int{}
+	for _, item := range queue {
+		ids = append(ids, item.CommandId)
+	}
+	return ids
+}
+
+func TestMemoryServiceExecutesCommand(t *testing.T) {
+	service := newMemoryService(t, 1)
+
+	response, err := service.ProcessCommand("echo hello; echo world >&2", models.CommandOptions{})
+	require.NoError(t, err)
+	assert.Equal(t, "Command is being executed", response["message"])
+	id := response["id"].(int)
+
+	command := waitForCommand(t, service, id)
+	assert.Equal(t, models.StatusCompleted, command.Status)
+	require.NotNil(t, command.ExitCode)
+	assert.Equal(t, 0, *command.ExitCode)
+	assert.Equal(t, "hello\nworld\n", command.Output)
+
+	events, err := service.FetchCommandEvents(id)
+	require.NoError(t, err)
+	var statuses []string
+	for _, event := range events {
+		statuses = append(statuses, event.To)
+	}
+	assert.Equal(t, []string{models.StatusWaiting, models.StatusRunning, models.StatusCompleted}, statuses)
+
+	replayed, err := service.ReplayEvents(models.EventFilter{Events: []string{"completed"}})
+	require.NoError(t, err)
+	require.Len(t, replayed, 1)
+	assert.Equal(t, id, replayed[0].CommandID)
+	assert.Equal(t, models.StatusRunning, *replayed[0].PreviousStatus)
+	require.NotNil(t, replayed[0].ExitCode)
+	assert.Equal(t, 0, *replayed[0].ExitCode)
+}
+
+func TestMemoryServiceReplaysEventsBeforeCursor(t *testing.T) {
+	service := newMemoryService(t, 1)
+	id := createCommand(t, service, "echo hello", models.CommandOptions{})
+	waitForCommand(t, service, id)
+
+	all, err := service.ReplayEvents(models.EventFilter{})
+	require.NoError(t, err)
+	require.Len(t, all, 3)
+
+	// The events shortly before the cursor may have committed after it, so they come again
+	replayed, err := service.ReplayEvents(models.EventFilter{After: all[2].EventID})
+	require.NoError(t, err)
+	assert.Equal(t, all, replayed)
+
+	replayed, err = service.ReplayEvents(models.EventFilter{After: all[2].EventID, Since: time.Now().Add(time.Hour)})
+	require.NoError(t, err)
+	assert.Empty(t, replayed)
+}
+
+func TestMemoryServiceTimesOutCommand(t *testing.T) {
+	service := newMemoryService(t, 1)
+	service.Config.Commands.Timeout = 1
+
+	id := createCommand(t, service, "echo started; sleep 30", models.CommandOptions{})
+	command := waitForCommand(t, service, id)
+	assert.Equal(t, models.StatusTimeout, command.Status)
+	assert.Nil(t, command.ExitCode)
+	assert.Equal(t, "started\nCommand execution timed out\n", command.Output)
+
+	// The slot is free again
+	next := createCommand(t, service, "echo next", models.CommandOptions{})
+	assert.Equal(t, models.StatusCompleted, waitForCommand(t, service, next).Status)
+}
+
+func TestMemoryServiceQueuesBeyondCapacity(t *testing.T) {
+	service := newMemoryService(t, 1)
+
+	first := createCommand(t, service, "sleep 0.3", models.CommandOptions{})
+	response, err := service.ProcessCommand("echo second", models.CommandOptions{})
+	require.NoError(t, err)
+	assert.Equal(t, "Command is being queued", response["message"])
+	assert.Equal(t, 1, response["position"])
+	second := response["id"].(int)
+
+	capacity, err := service.FetchCapacity()
+	require.NoError(t, err)
+	assert.Equal(t, models.Capacity{Total: 1, Used: 1, Available: 0}, capacity)
+
+	// The queued command starts once the first one frees its slot
+	queued := waitForCommand(t, service, second)
+	assert.Equal(t, models.StatusCompleted, queued.Status)
+	started := waitForCommand(t, service, first)
+	assert.Equal(t, models.StatusCompleted, started.Status)
+	assert.False(t, queued.StartedAt.Before(*started.FinishedAt))
+	assert.Empty(t, queuedIDs(t, service))
+}
+
+func TestMemoryServiceLocksHoldBackConflictingCommands(t *testing.T) {
+	service := newMemoryService(t, 3)
+	exclusive := models.CommandOptions{Locks: []models.CommandLock{{Key: "db"}}}
+	shared := models.CommandOptions{Locks: []models.CommandLock{{Key: "db", Mode: models.LockShared}}}
+
+	holder := createCommand(t, service, "sleep 0.3", exclusive)
+	blocked := createCommand(t, service, "echo blocked", shared)
+	free := createCommand(t, service, "echo free", models.CommandOptions{Locks: []models.CommandLock{{Key: "cache"}}})
+
+	queue, err := service.FetchQueueList()
+	require.NoError(t, err)
+	require.Len(t, queue, 1)
+	assert.Equal(t, blocked, queue[0].CommandId)
+	require.NotNil(t, queue[0].BlockedBy)
+	assert.Equal(t, holder, *queue[0].BlockedBy)
+
+	assert.Equal(t, models.StatusCompleted, waitForCommand(t, service, free).Status)
+	assert.Equal(t, models.StatusCompleted, waitForCommand(t, service, blocked).Status)
+	assert.Equal(t, models.StatusCompleted, waitForCommand(t, service, holder).Status)
+}
+
+func TestMemoryServicePausesQueue(t *testing.T) {
+	service := newMemoryService(t, 2)
+	require.NoError(t, service.PauseQueue("reports", "maintenance"))
+
+	response, err := service.ProcessCommand("echo report", models.CommandOptions{Queue: "reports"})
+	require.NoError(t, err)
+	assert.Equal(t, true, response["paused"])
+	assert.Equal(t, "maintenance", response["reason"])
+	id := response["id"].(int)
+
+	// Other queues keep running
+	other := createCommand(t, service, "echo other", models.CommandOptions{})
+	assert.Equal(t, models.StatusCompleted, waitForCommand(t, service, other).Status)
+
+	queue, err := service.FetchQueueList()
+	require.NoError(t, err)
+	require.Len(t, queue, 1)
+	assert.True(t, queue[0].Paused)
+
+	require.NoError(t, service.ResumeQueue("reports"))
+	assert.Equal(t, models.StatusCompleted, waitForCommand(t, service, id).Status)
+	assert.ErrorIs(t, service.ResumeQueue("reports"), services.ErrQueueNotPaused)
+}
+
+func TestMemoryServiceMovesCancelsAndStops(t *testing.T) {
+	service := newMemoryService(t, 1)
+
+	running := createCommand(t, service, "sleep 30", models.CommandOptions{})
+	a := createCommand(t, service, "echo a", models.CommandOptions{})
+	b := createCommand(t, service, "echo b", models.CommandOptions{})
+	c := createCommand(t, service, "echo c", models.CommandOptions{})
+
+	require.NoError(t, service.MoveQueuedCommand(c, 1))
+	assert.Equal(t, []int{c, a, b}, queuedIDs(t, service))
+	assert.ErrorIs(t, service.MoveQueuedCommand(running, 1), services.ErrNotQueued)
+
+	require.NoError(t, service.CancelCommand(a))
+	assert.ErrorIs(t, service.CancelCommand(a), services.ErrNotQueued)
+	cancelled, err := service.FetchCommandByID(a)
+	require.NoError(t, err)
+	assert.Equal(t, models.StatusCancelled, cancelled.Status)
+	assert.Equal(t, []int{c, b}, queuedIDs(t, service))
+
+	// The PID is saved once the process has started
+	require.Eventually(t, func() bool {
+		command, err := service.FetchCommandByID(running)
+		return err == nil && command.PID != nil
+	}, 5*time.Second, 10*time.Millisecond)
+	require.NoError(t, service.StopCommand(running))
+	stopped := waitForCommand(t, service, running)
+	assert.Equal(t, models.StatusStopped, stopped.Status)
+
+	assert.Equal(t, models.StatusCompleted, waitForCommand(t, service, c).Status)
+	assert.Equal(t, models.StatusCompleted, waitForCommand(t, service, b).Status)
+}
+
+func TestMemoryServicePagesCommands(t *testing.T) {
+	service := newMemoryService(t, 3)
+	var ids []int
+	for _, script := range []string{"echo 1", "echo 2", "echo 3"} {
+		ids = append(ids, createCommand(t, service, script, models.CommandOptions{Labels: map[string]string{"env": "test"}}))
+	}
+
+	page, err := service.FetchCommands(models.CommandQuery{Desc: true, Limit: 2})
+	require.NoError(t, err)
+	require.Len(t, page.Items, 2)
+	assert.Equal(t, []int{ids[2], ids[1]}, []int{page.Items[0].ID, page.Items[1].ID})
+	require.NotEmpty(t, page.NextCursor)
+
+	page, err = service.FetchCommands(models.CommandQuery{Desc: true, Limit: 2, Cursor: page.NextCursor})
+	require.NoError(t, err)
+	require.Len(t, page.Items, 1)
+	assert.Equal(t, ids[0], page.Items[0].ID)
+	assert.Empty(t, page.NextCursor)
+
+	_, err = service.FetchCommands(models.CommandQuery{Sort: models.SortByCreatedAt, Cursor: "bogus"})
+	assert.ErrorIs(t, err, services.ErrInvalidCursor)
+}
+
+func TestMemoryServiceDeletesCommands(t *testing.T) {
+	service := newMemoryService(t, 1)
+	id := createCommand(t, service, "echo done", models.CommandOptions{})
+	waitForCommand(t, service, id)
+
+	result, err := service.PurgeCommands(models.PurgeFilter{All: true, DryRun: true})
+	require.NoError(t, err)
+	assert.Equal(t, []int{id}, result.IDs)
+
+	require.NoError(t, service.DeleteCommand(id))
+	_, err = service.FetchCommandByID(id)
+	assert.ErrorIs(t, err, services.ErrNotFound)
+	_, err = service.FetchCommandEvents(id)
+	assert.ErrorIs(t, err, services.ErrNotFound)
+}
+
+func TestMemoryServiceIdempotencyKeys(t *testing.T) {
+	service := newMemoryService(t, 1)
+	service.Config.Commands.IdempotencyTTL = 60
+	opts := models.CommandOptions{IdempotencyKey: "key"}
+
+	first, err := service.ProcessCommand("echo once", opts)
+	require.NoError(t, err)
+	replayed, err := service.ProcessCommand("echo once", opts)
+	require.NoError(t, err)
+	assert.EqualValues(t, first["id"], replayed["id"])
+
+	_, err = service.ProcessCommand("echo twice", opts)
+	assert.ErrorIs(t, err, services.ErrIdempotencyConflict)
+	page, err := service.FetchCommands(models.CommandQuery{})
+	require.NoError(t, err)
+	assert.Len(t, page.Items, 1)
+}
+
+func TestMemoryServiceSearchesScriptsAndOutputs(t *testing.T) {
+	service := newMemoryService(t, 1)
+	echoID := createCommand(t, service, "echo needle", models.CommandOptions{})
+	printfID := createCommand(t, service, "printf 'hay\\n%sdle\\n' Nee", models.CommandOptions{})
+	otherID := createCommand(t, service, "echo hay", models.CommandOptions{})
+	for _, id := range []int{echoID, printfID, otherID} {
+		waitForCommand(t, service, id)
+	}
+
+	page, err := service.SearchCommands(models.SearchQuery{Text: "needle"})
+	require.NoError(t, err)
+	snippets := map[int]models.SearchResult{}
+	for _, result := range page.Items {
+		snippets[result.Command.ID] = result
+	}
+	require.Len(t, snippets, 2)
+	assert.Contains(t, snippets[echoID].ScriptSnippet, "<mark>needle</mark>")
+	assert.Contains(t, snippets[echoID].OutputSnippet, "<mark>needle</mark>")
+	assert.Empty(t, snippets[printfID].ScriptSnippet)
+	assert.Contains(t, snippets[printfID].OutputSnippet, "<mark>Needle</mark>")
+	assert.Empty(t, page.NextCursor)
+}
+
+func TestMemoryServiceManagesWebhooks(t *testing.T) {
+	service := newMemoryService(t, 1)
+
+	hook, err := service.CreateWebhook(models.Webhook{URL: "http://example.com/hook", Secret: "secret"})
+	require.NoError(t, err)
+	assert.NotZero(t, hook.ID)
+	assert.Empty(t, hook.Secret)
+
+	webhooks, err := service.FetchWebhooks()
+	require.NoError(t, err)
+	require.Len(t, webhooks, 1)
+	assert.Equal(t, "secret", webhooks[0].Secret)
+	assert.Equal(t, []string{}, webhooks[0].Events)
+
+	require.NoError(t, service.DeleteWebhook(hook.ID))
+	assert.ErrorIs(t, service.DeleteWebhook(hook.ID), services.ErrWebhookNotFound)
+	webhooks, err = service.FetchWebhooks()
+	require.NoError(t, err)
+	assert.Empty(t, webhooks)
+}
+
+func TestMemoryServiceDeliversWebhooks(t *testing.T) {
+	received := make(chan string, 16)
+	receiver := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		received <- r.Header.Get(webhook.EventHeader)
+	}))
+	defer receiver.Close()
+
+	service := newMemoryService(t, 1)
+	service.Config.Commands.Webhooks = config.WebhooksConfig{Enabled: true, Interval: 1, Timeout: 5, MaxAttempts: 3}
+	hook, err := service.CreateWebhook(models.Webhook{URL: receiver.URL, Events: []string{models.EventCompleted}})
+	require.NoError(t, err)
+	id := createCommand(t, service, "echo hooked", models.CommandOptions{})
+	waitForCommand(t, service, id)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	service.StartWebhooks(ctx)
+	select {
+	case event := <-received:
+		assert.Equal(t, models.EventCompleted, event)
+	case <-time.After(10 * time.Second):
+		t.Fatal("webhook was not delivered")
+	}
+
+	require.Eventually(t, func() bool {
+		deliveries, err := service.FetchDeliveries(models.DeliveryQuery{WebhookID: &hook.ID})
+		require.NoError(t, err)
+		return len(deliveries) == 1 && deliveries[0].Status == models.DeliveryDelivered
+	}, 5*time.Second, 50*time.Millisecond)
+	deliveries, err := service.FetchDeliveries(models.DeliveryQuery{CommandID: &id})
+	require.NoError(t, err)
+	require.Len(t, deliveries, 1)
+	assert.Equal(t, id, deliveries[0].CommandID)
+	require.Len(t, deliveries[0].AttemptLog, 1)
+	assert.Equal(t, http.StatusOK, *deliveries[0].AttemptLog[0].StatusCode)
+}
+
+func TestMemoryServiceArchivesOutputs(t *testing.T) {
+	service := newMemoryService(t, 1)
+	store, err := archive.NewFileStore(t.TempDir(), "gzip")
+	require.NoError(t, err)
+	service.Archive = store
+	service.Config.Commands.Archive = config.ArchiveConfig{Enabled: true, Interval: 1}
+	id := createCommand(t, service, "echo archived", models.CommandOptions{})
+	waitForCommand(t, service, id)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	service.StartArchiver(ctx)
+	require.Eventually(t, func() bool {
+		command, err := service.FetchCommandByID(id)
+		require.NoError(t, err)
+		return command.OutputArchivedAt != nil
+	}, 5*time.Second, 50*time.Millisecond)
+
+	output, err := service.FetchCommandOutput(id, models.OutputQuery{})
+	require.NoError(t, err)
+	assert.Equal(t, "archived\n", output.Output)
+}
@@ -0,0 +1,79 @@
+package tests_test
+
+import (
+	"fmt"
+	"github.com/17HIERARCH70/BashAPI/internal/domain/models"
+	"github.com/17HIERARCH70/BashAPI/internal/handlers"
+	services "github.com/17HIERARCH70/BashAPI/internal/services/command"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestGetCommandEvents(t *testing.T) {
+	mockService := new(MockCommandService)
+	waiting := models.StatusWaiting
+	at := time.Date(2026, 10, 1, 12, 0, 0, 0, time.UTC)
+	events := []models.CommandEvent{
+		{ID: 1, CommandID: 4, To: models.StatusWaiting, Actor: "api", Reason: "created", CreatedAt: at},
+		{ID: 2, CommandID: 4, From: &waiting, To: models.StatusRunning, Actor: "dispatcher", CreatedAt: at.Add(time.Second)},
+	}
+	mockService.On("FetchCommandEvents", 4).Return(events, nil)
+	mockService.On("FetchCommandEvents", 5).Return([]models.CommandEvent(nil), services.ErrNotFound)
+
+	handler := handlers.NewCommandHandlers(mockService, nil)
+	router := gin.Default()
+	router.GET("/commands/:id/events", handler.GetCommandEvents)
+
+	req, _ := http.NewRequest("GET", "/commands/4/events", nil)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.JSONEq(t, `[
+		{"id":1,"command_id":4,"from":null,"to":"waiting","actor":"api","reason":"created","created_at":"2026-10-01T12:00:00Z"},
+		{"id":2,"command_id":4,"from":"waiting","to":"running","actor":"dispatcher","reason":"","created_at":"2026-10-01T12:00:01Z"}
+	]`, w.Body.String())
+
+	req, _ = http.NewRequest("GET", "/commands/5/events", nil)
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusNotFound, w.Code)
+	mockService.AssertExpectations(t)
+}
+
+func TestStopFinishedCommandConflicts(t *testing.T) {
+	mockService := new(MockCommandService)
+	mockService.On("StopCommand", 3).Return(fmt.Errorf("%w: completed -> stopped", services.ErrInvalidTransition))
+
+	handler := handlers.NewCommandHandlers(mockService, nil)
+	router := gin.Default()
+	router.POST("/commands/:id/stop", handler.StopCommand)
+
+	req, _ := http.NewRequest("POST", "/commands/3/stop", nil)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusConflict, w.Code)
+	assert.JSONEq(t, `{"error":"invalid status transition: completed -> stopped"}`, w.Body.String())
+	mockService.AssertExpectations(t)
+}
+
+func TestForceStartCompletedCommandConflicts(t *testing.T) {
+	mockService := new(MockCommandService)
+	mockService.On("ForceStartCommand", 3).Return(gin.H{}, fmt.Errorf("%w: completed -> running", services.ErrInvalidTransition))
+
+	handler := handlers.NewCommandHandlers(mockService, nil)
+	router := gin.Default()
+	router.POST("/commands/:id/fstart", handler.ForceStartCommand)
+
+	req, _ := http.NewRequest("POST", "/commands/3/fstart", nil)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusConflict, w.Code)
+	mockService.AssertExpectations(t)
+}
@@ -0,0 +1,91 @@
+package tests_test
+
+import (
+	"encoding/json"
+	"github.com/17HIERARCH70/BashAPI/internal/domain/models"
+	"github.com/17HIERARCH70/BashAPI/internal/handlers"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func serveReadyz(t *testing.T, handler *handlers.CommandHandlers) (int, models.Readiness) {
+	router := gin.New()
+	router.GET("/readyz", handler.Readyz)
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/readyz", nil)
+	router.ServeHTTP(w, req)
+
+	var readiness models.Readiness
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &readiness))
+	return w.Code, readiness
+}
+
+var passingChecks = []models.HealthCheck{
+	{Name: "database", Status: models.HealthOK},
+	{Name: "migrations", Status: models.HealthOK, Detail: "version 18, expected 18"},
+	{Name: "dispatcher", Status: models.HealthOK},
+}
+
+func TestHealthz(t *testing.T) {
+	handler := handlers.NewCommandHandlers(new(MockCommandService), nil)
+	router := gin.New()
+	router.GET("/healthz", handler.Healthz)
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/healthz", nil)
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.JSONEq(t, `{"status":"ok"}`, w.Body.String())
+}
+
+func TestReadyz(t *testing.T) {
+	mockService := new(MockCommandService)
+	mockService.On("CheckReadiness", mock.Anything).Return(passingChecks)
+	handler := handlers.NewCommandHandlers(mockService, nil)
+
+	code, readiness := serveReadyz(t, handler)
+
+	assert.Equal(t, http.StatusOK, code)
+	assert.Equal(t, models.HealthOK, readiness.Status)
+	require.Len(t, readiness.Checks, 4)
+	assert.Equal(t, "shutdown", readiness.Checks[3].Name)
+	assert.Equal(t, models.HealthOK, readiness.Checks[3].Status)
+	mockService.AssertExpectations(t)
+}
+
+func TestReadyzFailingCheck(t *testing.T) {
+	mockService := new(MockCommandService)
+	mockService.On("CheckReadiness", mock.Anything).Return([]models.HealthCheck{
+		{Name: "database", Status: models.HealthOK},
+		{Name: "migrations", Status: models.HealthFailing, Error: "schema is behind, apply the pending migrations"},
+		{Name: "dispatcher", Status: models.HealthOK},
+	})
+	handler := handlers.NewCommandHandlers(mockService, nil)
+
+	code, readiness := serveReadyz(t, handler)
+
+	assert.Equal(t, http.StatusServiceUnavailable, code)
+	assert.Equal(t, models.HealthFailing, readiness.Status)
+	assert.Equal(t, "schema is behind, apply the pending migrations", readiness.Checks[1].Error)
+}
+
+func TestReadyzDuringShutdown(t *testing.T) {
+	mockService := new(MockCommandService)
+	mockService.On("CheckReadiness", mock.Anything).Return(passingChecks)
+	handler := handlers.NewCommandHandlers(mockService, nil)
+
+	code, _ := serveReadyz(t, handler)
+	require.Equal(t, http.StatusOK, code)
+
+	handler.BeginShutdown()
+	code, readiness := serveReadyz(t, handler)
+
+	assert.Equal(t, http.StatusServiceUnavailable, code)
+	assert.Equal(t, models.HealthFailing, readiness.Status)
+	assert.Equal(t, models.HealthCheck{Name: "shutdown", Status: models.HealthFailing, Error: "server is shutting down"}, readiness.Checks[3])
+}
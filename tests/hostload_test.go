@@ -0,0 +1,58 @@
+package tests_test
+
+import (
+	"github.com/17HIERARCH70/BashAPI/internal/lib/hostload"
+	"github.com/stretchr/testify/assert"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestParseLoadAvg(t *testing.T) {
+	load1, load5, load15, err := hostload.ParseLoadAvg("0.36 0.40 0.28 2/72 25190\n")
+
+	assert.NoError(t, err)
+	assert.Equal(t, 0.36, load1)
+	assert.Equal(t, 0.40, load5)
+	assert.Equal(t, 0.28, load15)
+}
+
+func TestParseLoadAvgInvalid(t *testing.T) {
+	_, _, _, err := hostload.ParseLoadAvg("garbage")
+	assert.Error(t, err)
+}
+
+func TestParseMemInfo(t *testing.T) {
+	data := "MemTotal:        6158152 kB\nMemFree:         4473552 kB\nMemAvailable:    5651592 kB\n"
+	total, available, err := hostload.ParseMemInfo(data)
+
+	assert.NoError(t, err)
+	assert.Equal(t, uint64(6158152*1024), total)
+	assert.Equal(t, uint64(5651592*1024), available)
+}
+
+func TestParseMemInfoMissingAvailable(t *testing.T) {
+	_, _, err := hostload.ParseMemInfo("MemTotal:        6158152 kB\n")
+	assert.Error(t, err)
+}
+
+func TestParsePressure(t *testing.T) {
+	data := "some avg10=3.31 avg60=3.30 avg300=4.19 total=49542276\nfull avg10=0.00 avg60=0.00 avg300=0.00 total=0\n"
+	pressure, err := hostload.ParsePressure(data)
+
+	assert.NoError(t, err)
+	assert.Equal(t, 3.31, pressure)
+}
+
+func TestReadWithoutPressure(t *testing.T) {
+	dir := t.TempDir()
+	assert.NoError(t, os.WriteFile(filepath.Join(dir, "loadavg"), []byte("1.50 1.00 0.50 1/10 42\n"), 0o644))
+	assert.NoError(t, os.WriteFile(filepath.Join(dir, "meminfo"), []byte("MemTotal: 2048 kB\nMemAvailable: 1024 kB\n"), 0o644))
+
+	m, err := hostload.Read(dir)
+
+	assert.NoError(t, err)
+	assert.Equal(t, 1.5, m.Load1)
+	assert.Equal(t, uint64(1024*1024), m.MemAvailable)
+	assert.Nil(t, m.CPUPressure)
+}
@@ -0,0 +1,139 @@
+package tests_test
+
+import (
+	"encoding/json"
+	"github.com/17HIERARCH70/BashAPI/internal/domain/models"
+	"github.com/17HIERARCH70/BashAPI/internal/handlers"
+	"github.com/17HIERARCH70/BashAPI/internal/lib/labels"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestParseSelector(t *testing.T) {
+	selector, err := labels.Parse("env=prod, team!=infra,canary,!legacy,tier==web")
+	assert.NoError(t, err)
+	assert.Equal(t, labels.Selector{
+		{Key: "env", Operator: labels.Equals, Value: "prod"},
+		{Key: "team", Operator: labels.NotEquals, Value: "infra"},
+		{Key: "canary", Operator: labels.Exists},
+		{Key: "legacy", Operator: labels.NotExists},
+		{Key: "tier", Operator: labels.Equals, Value: "web"},
+	}, selector)
+	assert.Equal(t, "env=prod,team!=infra,canary,!legacy,tier=web", selector.String())
+
+	for _, invalid := range []string{"=prod", "env=a b", "env=prod=x", "!"} {
+		_, err := labels.Parse(invalid)
+		assert.ErrorIs(t, err, labels.ErrInvalidSelector, invalid)
+	}
+}
+
+func TestSelectorMatches(t *testing.T) {
+	selector, _ := labels.Parse("env=prod,team!=infra,!legacy")
+
+	assert.True(t, selector.Matches(map[string]string{"env": "prod", "team": "web"}))
+	assert.True(t, selector.Matches(map[string]string{"env": "prod"}))
+	assert.False(t, selector.Matches(map[string]string{"env": "prod", "team": "infra"}))
+	assert.False(t, selector.Matches(map[string]string{"env": "prod", "legacy": ""}))
+	assert.False(t, selector.Matches(nil))
+	assert.True(t, labels.Selector(nil).Matches(nil))
+}
+
+func TestSelectorJSON(t *testing.T) {
+	var filter models.SelectorFilter
+	assert.NoError(t, json.Unmarshal([]byte(`{"selector":"deploy=1234"}`), &filter))
+	assert.Equal(t, labels.Selector{{Key: "deploy", Operator: labels.Equals, Value: "1234"}}, filter.Selector)
+
+	err := json.Unmarshal([]byte(`{"selector":"deploy=12 34"}`), &filter)
+	assert.ErrorIs(t, err, labels.ErrInvalidSelector)
+}
+
+func TestStopCommandsBySelector(t *testing.T) {
+	mockService := new(MockCommandService)
+	selector, _ := labels.Parse("deploy=1234")
+	mockService.On("StopCommands", models.SelectorFilter{Selector: selector}).Return([]int{4, 5}, nil)
+
+	handler := handlers.NewCommandHandlers(mockService, nil)
+	router := gin.Default()
+	router.POST("/commands/stop", handler.StopCommands)
+
+	req, _ := http.NewRequest("POST", "/commands/stop", strings.NewReader(`{"selector":"deploy=1234"}`))
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.JSONEq(t, `{"message":"Commands stopped","stopped":[4,5]}`, w.Body.String())
+	mockService.AssertExpectations(t)
+}
+
+func TestStopCommandsInvalidSelector(t *testing.T) {
+	handler := handlers.NewCommandHandlers(new(MockCommandService), nil)
+	router := gin.Default()
+	router.POST("/commands/stop", handler.StopCommands)
+
+	req, _ := http.NewRequest("POST", "/commands/stop", strings.NewReader(`{"selector":""}`))
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.JSONEq(t, `{"error":"Selector is required"}`, w.Body.String())
+
+	req, _ = http.NewRequest("POST", "/commands/stop", strings.NewReader(`{"selector":"env=a b"}`))
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.Contains(t, w.Body.String(), "invalid label selector")
+}
+
+func TestUpdateCommandLabels(t *testing.T) {
+	mockService := new(MockCommandService)
+	prod := "prod"
+	update := models.LabelUpdate{Labels: map[string]*string{"env": &prod, "old": nil}}
+	result := models.CommandLabels{Labels: map[string]string{"env": "prod"}, Annotations: map[string]string{}}
+	mockService.On("UpdateLabels", 3, update).Return(result, nil)
+
+	handler := handlers.NewCommandHandlers(mockService, nil)
+	router := gin.Default()
+	router.PATCH("/commands/:id/labels", handler.UpdateCommandLabels)
+
+	req, _ := http.NewRequest("PATCH", "/commands/3/labels", strings.NewReader(`{"labels":{"env":"prod","old":null}}`))
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.JSONEq(t, `{"labels":{"env":"prod"},"annotations":{}}`, w.Body.String())
+	mockService.AssertExpectations(t)
+}
+
+func TestCreateCommandWithInvalidLabels(t *testing.T) {
+	handler := handlers.NewCommandHandlers(new(MockCommandService), nil)
+	router := gin.Default()
+	router.POST("/commands", handler.CreateCommand)
+
+	req, _ := http.NewRequest("POST", "/commands", strings.NewReader(`{"script":"echo hi","labels":{"env":"prod east"}}`))
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.Contains(t, w.Body.String(), "invalid label")
+}
+
+func TestGetCommandsListBySelector(t *testing.T) {
+	mockService := new(MockCommandService)
+	selector, _ := labels.Parse("env=prod,team!=infra")
+	mockService.On("FetchCommands", models.CommandQuery{Labels: selector, Sort: models.SortByID}).
+		Return(models.CommandPage{Items: []models.Command{}}, nil)
+
+	handler := handlers.NewCommandHandlers(mockService, nil)
+	router := gin.Default()
+	router.GET("/commands", handler.GetCommandsList)
+
+	req, _ := http.NewRequest("GET", "/commands?selector=env%3Dprod%2Cteam%21%3Dinfra", nil)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	mockService.AssertExpectations(t)
+}
@@ -0,0 +1,60 @@
+package tests_test
+
+import (
+	"github.com/17HIERARCH70/BashAPI/internal/lib/lines"
+	"github.com/stretchr/testify/assert"
+	"regexp"
+	"testing"
+)
+
+func toStrings(selected [][]byte) []string {
+	result := make([]string, 0, len(selected))
+	for _, line := range selected {
+		result = append(result, string(line))
+	}
+	return result
+}
+
+func TestHeadAcrossPieces(t *testing.T) {
+	head := &lines.Head{N: 2}
+	head.Write([]byte("fir"))
+	head.Write([]byte("st\nsec"))
+	assert.False(t, head.Done())
+	head.Write([]byte("ond\nthird\n"))
+
+	assert.True(t, head.Done())
+	assert.Equal(t, []string{"first", "second"}, toStrings(head.Lines()))
+}
+
+func TestHeadKeepsUnterminatedLastLine(t *testing.T) {
+	head := &lines.Head{Match: regexp.MustCompile("ERROR")}
+	head.Write([]byte("ok\nERROR one\nok\nERROR two"))
+
+	assert.Equal(t, []string{"ERROR one", "ERROR two"}, toStrings(head.Lines()))
+}
+
+func TestTailFedBackwards(t *testing.T) {
+	tail := &lines.Tail{N: 2}
+	tail.Prepend([]byte("ree\nfour\n"))
+	tail.Prepend([]byte("one\ntwo\nth"))
+
+	assert.True(t, tail.Done())
+	assert.Equal(t, []string{"three", "four"}, toStrings(tail.Lines()))
+}
+
+func TestTailWithMatchReachesStart(t *testing.T) {
+	tail := &lines.Tail{N: 5, Match: regexp.MustCompile("^E")}
+	tail.Prepend([]byte("\nok\nEnd"))
+	tail.Prepend([]byte("E1"))
+
+	assert.False(t, tail.Done())
+	assert.Equal(t, []string{"E1", "End"}, toStrings(tail.Lines()))
+}
+
+func TestTailKeepsEmptyLines(t *testing.T) {
+	tail := &lines.Tail{}
+	tail.Prepend([]byte("\na\n\n"))
+
+	assert.Equal(t, []string{"", "a", ""}, toStrings(tail.Lines()))
+	assert.Equal(t, "x\ny\n", string(lines.Join([][]byte{[]byte("x"), []byte("y")})))
+}
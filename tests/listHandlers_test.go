@@ -0,0 +1,209 @@
+package tests_test
+
+import (
+	"encoding/json"
+	"github.com/17HIERARCH70/BashAPI/internal/domain/models"
+	"github.com/17HIERARCH70/BashAPI/internal/handlers"
+	services "github.com/17HIERARCH70/BashAPI/internal/services/command"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+// listedJSON is the JSON of commands in the command list, which leaves out the output.
+func listedJSON(t *testing.T, commands []models.Command) []byte {
+	data, err := json.Marshal(commands)
+	require.NoError(t, err)
+	var items []map[string]interface{}
+	require.NoError(t, json.Unmarshal(data, &items))
+	for _, item := range items {
+		delete(item, "Output")
+	}
+	data, err = json.Marshal(items)
+	require.NoError(t, err)
+	return data
+}
+
+func TestGetCommandsListFilters(t *testing.T) {
+	mockService := new(MockCommandService)
+	createdAfter := time.Date(2024, 5, 1, 0, 0, 0, 0, time.UTC)
+	exitCode := 1
+	expectedQuery := models.CommandQuery{
+		Statuses:       []string{"error", "timeout"},
+		CreatedAfter:   &createdAfter,
+		Submitter:      "ci",
+		Tags:           []string{"env:prod", "nightly"},
+		ScriptContains: "backup",
+		ExitCode:       &exitCode,
+		Sort:           models.SortByCreatedAt,
+		Desc:           true,
+		Cursor:         "abc",
+		Limit:          10,
+	}
+	page := models.CommandPage{Items: []models.Command{{ID: 7, Status: "error"}}, NextCursor: "next"}
+	mockService.On("FetchCommands", expectedQuery).Return(page, nil)
+
+	handler := handlers.NewCommandHandlers(mockService, nil)
+	router := gin.Default()
+	router.GET("/commands", handler.GetCommandsList)
+
+	req, _ := http.NewRequest("GET", "/commands?status=error,timeout&created_after=2024-05-01T00:00:00Z"+
+		"&submitter=ci&tag=env:prod,nightly&script=backup&exit_code=1&sort=created_at&order=desc&cursor=abc&limit=10", nil)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "next", w.Header().Get("X-Next-Cursor"))
+	mockService.AssertExpectations(t)
+}
+
+func TestGetCommandsListLeavesOutOutput(t *testing.T) {
+	mockService := new(MockCommandService)
+	page := models.CommandPage{Items: []models.Command{{ID: 7, Status: "completed"}}}
+	mockService.On("FetchCommands", models.CommandQuery{Sort: models.SortByID}).Return(page, nil)
+
+	handler := handlers.NewCommandHandlers(mockService, nil)
+	router := gin.Default()
+	router.GET("/commands", handler.GetCommandsList)
+
+	req, _ := http.NewRequest("GET", "/commands", nil)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	var items []map[string]interface{}
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &items))
+	assert.Len(t, items, 1)
+	assert.NotContains(t, items[0], "Output")
+	assert.Equal(t, "completed", items[0]["Status"])
+	mockService.AssertExpectations(t)
+}
+
+func TestGetCommandsListFields(t *testing.T) {
+	mockService := new(MockCommandService)
+	page := models.CommandPage{Items: []models.Command{{ID: 7, Status: "completed", Script: "echo hi"}}}
+	mockService.On("FetchCommands", models.CommandQuery{Sort: models.SortByID, WithOutput: true}).Return(page, nil)
+
+	handler := handlers.NewCommandHandlers(mockService, nil)
+	router := gin.Default()
+	router.GET("/commands", handler.GetCommandsList)
+
+	req, _ := http.NewRequest("GET", "/commands?fields=id,status,output", nil)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.JSONEq(t, `[{"ID":7,"Status":"completed","Output":""}]`, w.Body.String())
+	mockService.AssertExpectations(t)
+}
+
+func TestGetCommandsListInvalidQuery(t *testing.T) {
+	handler := handlers.NewCommandHandlers(new(MockCommandService), nil)
+	router := gin.Default()
+	router.GET("/commands", handler.GetCommandsList)
+
+	for query, message := range map[string]string{
+		"created_before=yesterday": "created_before must be an RFC 3339 time",
+		"exit_code=x":              "exit_code must be a number",
+		"sort=script":              "sort must be 'id', 'created_at' or 'updated_at'",
+		"order=up":                 "order must be 'asc' or 'desc'",
+		"limit=5000":               "limit must be between 1 and 1000",
+		"fields=id,secret":         "unknown field 'secret'",
+	} {
+		req, _ := http.NewRequest("GET", "/commands?"+query, nil)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusBadRequest, w.Code, query)
+		assert.Contains(t, w.Body.String(), message, query)
+	}
+}
+
+func TestGetCommandsListInvalidCursor(t *testing.T) {
+	mockService := new(MockCommandService)
+	mockService.On("FetchCommands", models.CommandQuery{Sort: models.SortByID, Cursor: "bad"}).
+		Return(models.CommandPage{}, services.ErrInvalidCursor)
+
+	handler := handlers.NewCommandHandlers(mockService, nil)
+	router := gin.Default()
+	router.GET("/commands", handler.GetCommandsList)
+
+	req, _ := http.NewRequest("GET", "/commands?cursor=bad", nil)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.JSONEq(t, `{"error":"Invalid cursor"}`, w.Body.String())
+	mockService.AssertExpectations(t)
+}
+
+func TestCreateCommandWithTags(t *testing.T) {
+	mockService := new(MockCommandService)
+	opts := models.CommandOptions{Tags: []string{"nightly"}, Submitter: "ci"}
+	mockService.On("ProcessCommand", "echo hi", opts).Return(gin.H{"message": "Command is being executed"}, nil)
+
+	handler := handlers.NewCommandHandlers(mockService, nil)
+	router := gin.Default()
+	router.POST("/commands", handler.CreateCommand)
+
+	req, _ := http.NewRequest("POST", "/commands", strings.NewReader(`{"script":"echo hi","tags":["nightly"]}`))
+	req.Header.Set("X-Submitter", "ci")
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusAccepted, w.Code)
+
+	req, _ = http.NewRequest("POST", "/commands", strings.NewReader(`{"script":"echo hi","tags":["has space"]}`))
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	mockService.AssertExpectations(t)
+}
+
+func TestSearchCommands(t *testing.T) {
+	mockService := new(MockCommandService)
+	expectedQuery := models.SearchQuery{
+		Text:         "connection refused",
+		CommandQuery: models.CommandQuery{Statuses: []string{"error"}, Sort: models.SortByID, Desc: true},
+	}
+	page := models.SearchPage{
+		Items: []models.SearchResult{{
+			Command:       models.Command{ID: 3, Status: "error"},
+			OutputSnippet: "curl: (7) <mark>connection</mark> <mark>refused</mark>",
+		}},
+		NextCursor: "next",
+	}
+	mockService.On("SearchCommands", expectedQuery).Return(page, nil)
+
+	handler := handlers.NewCommandHandlers(mockService, nil)
+	router := gin.Default()
+	router.GET("/commands/search", handler.SearchCommands)
+
+	req, _ := http.NewRequest("GET", "/commands/search?q=connection+refused&status=error", nil)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "next", w.Header().Get("X-Next-Cursor"))
+	var results []models.SearchResult
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &results))
+	assert.Equal(t, page.Items, results)
+	mockService.AssertExpectations(t)
+}
+
+func TestSearchCommandsRequiresText(t *testing.T) {
+	handler := handlers.NewCommandHandlers(new(MockCommandService), nil)
+	router := gin.Default()
+	router.GET("/commands/search", handler.SearchCommands)
+
+	req, _ := http.NewRequest("GET", "/commands/search?q=+", nil)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.JSONEq(t, `{"error":"q is required"}`, w.Body.String())
+}
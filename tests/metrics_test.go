@@ -0,0 +1,70 @@
+package tests_test
+
+import (
+	"errors"
+	"github.com/17HIERARCH70/BashAPI/internal/lib/metrics"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"strings"
+	"testing"
+)
+
+func TestCommandCollector(t *testing.T) {
+	collector := metrics.NewCommandCollector(func() (metrics.CommandStats, error) {
+		return metrics.CommandStats{Running: 2, Queued: 5, Paused: 3, UsedSlots: 4, Slots: 8}, nil
+	})
+
+	expected := `
+# HELP bashapi_command_stats_up Whether the command gauges could be read from the database.
+# TYPE bashapi_command_stats_up gauge
+bashapi_command_stats_up 1
+# HELP bashapi_commands_paused Queued commands held back by a paused queue.
+# TYPE bashapi_commands_paused gauge
+bashapi_commands_paused 3
+# HELP bashapi_commands_queued Commands waiting in the queue.
+# TYPE bashapi_commands_queued gauge
+bashapi_commands_queued 5
+# HELP bashapi_commands_running Commands currently running.
+# TYPE bashapi_commands_running gauge
+bashapi_commands_running 2
+# HELP bashapi_concurrency_slots Concurrency slots shared by running commands.
+# TYPE bashapi_concurrency_slots gauge
+bashapi_concurrency_slots 8
+# HELP bashapi_concurrency_slots_used Concurrency slots taken by running commands.
+# TYPE bashapi_concurrency_slots_used gauge
+bashapi_concurrency_slots_used 4
+`
+	assert.NoError(t, testutil.CollectAndCompare(collector, strings.NewReader(expected)))
+}
+
+func TestCommandCollectorFailure(t *testing.T) {
+	collector := metrics.NewCommandCollector(func() (metrics.CommandStats, error) {
+		return metrics.CommandStats{}, errors.New("database is down")
+	})
+
+	expected := `
+# HELP bashapi_command_stats_up Whether the command gauges could be read from the database.
+# TYPE bashapi_command_stats_up gauge
+bashapi_command_stats_up 0
+`
+	assert.NoError(t, testutil.CollectAndCompare(collector, strings.NewReader(expected)))
+}
+
+func TestMetricsRegistry(t *testing.T) {
+	registry, err := metrics.NewRegistry()
+	require.NoError(t, err)
+
+	metrics.CommandsFinished.WithLabelValues("default", "completed").Inc()
+	metrics.QueueWait.WithLabelValues("default").Observe(1.5)
+	families, err := registry.Gather()
+	require.NoError(t, err)
+
+	names := map[string]bool{}
+	for _, family := range families {
+		names[family.GetName()] = true
+	}
+	assert.True(t, names["bashapi_commands_finished_total"])
+	assert.True(t, names["bashapi_command_queue_wait_seconds"])
+	assert.True(t, names["go_goroutines"])
+}
@@ -0,0 +1,125 @@
+package tests_test
+
+import (
+	"context"
+	"github.com/17HIERARCH70/BashAPI/internal/storage/postgresql"
+	"github.com/17HIERARCH70/BashAPI/migrations"
+	"github.com/jackc/pgx/v4/pgxpool"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"os"
+	"testing"
+	"testing/fstest"
+)
+
+func TestEmbeddedMigrations(t *testing.T) {
+	list, err := postgresql.LoadMigrations(migrations.FS)
+	require.NoError(t, err)
+	require.NotEmpty(t, list)
+
+	for i, migration := range list {
+		assert.Equal(t, int64(i+1), migration.Version, "migrations are numbered without gaps")
+	}
+	assert.Equal(t, list[len(list)-1].Version, postgresql.SchemaVersion)
+	assert.Equal(t, "create_commands_table", list[0].Name)
+	assert.Contains(t, list[0].Up, "commands.commands")
+}
+
+func TestLoadMigrations(t *testing.T) {
+	fsys := fstest.MapFS{
+		"000002_add_column.up.sql":     {Data: []byte("ALTER TABLE t ADD COLUMN c INT;")},
+		"000002_add_column.down.sql":   {Data: []byte("ALTER TABLE t DROP COLUMN c;")},
+		"000001_create_table.up.sql":   {Data: []byte("CREATE TABLE t (id INT);")},
+		"000001_create_table.down.sql": {Data: []byte("DROP TABLE t;")},
+	}
+
+	list, err := postgresql.LoadMigrations(fsys)
+
+	require.NoError(t, err)
+	assert.Equal(t, []postgresql.Migration{
+		{Version: 1, Name: "create_table", Up: "CREATE TABLE t (id INT);", Down: "DROP TABLE t;"},
+		{Version: 2, Name: "add_column", Up: "ALTER TABLE t ADD COLUMN c INT;", Down: "ALTER TABLE t DROP COLUMN c;"},
+	}, list)
+}
+
+func TestLoadMigrationsInvalid(t *testing.T) {
+	tests := map[string]fstest.MapFS{
+		"missing down script": {
+			"000001_create_table.up.sql": {Data: []byte("CREATE TABLE t (id INT);")},
+		},
+		"no version": {
+			"create_table.up.sql":   {Data: []byte("CREATE TABLE t (id INT);")},
+			"create_table.down.sql": {Data: []byte("DROP TABLE t;")},
+		},
+		"no direction": {
+			"000001_create_table.sql": {Data: []byte("CREATE TABLE t (id INT);")},
+		},
+		"names differ": {
+			"000001_create_table.up.sql": {Data: []byte("CREATE TABLE t (id INT);")},
+			"000001_drop_table.down.sql": {Data: []byte("DROP TABLE t;")},
+		},
+	}
+	for name, fsys := range tests {
+		t.Run(name, func(t *testing.T) {
+			_, err := postgresql.LoadMigrations(fsys)
+			assert.Error(t, err)
+		})
+	}
+}
+
+func TestCheckSchemaVersion(t *testing.T) {
+	assert.NoError(t, postgresql.CheckSchemaVersion(postgresql.SchemaVersion, false))
+	// A newer schema left by the next release during a rolling upgrade is accepted
+	assert.NoError(t, postgresql.CheckSchemaVersion(postgresql.SchemaVersion+1, false))
+	assert.ErrorIs(t, postgresql.CheckSchemaVersion(postgresql.SchemaVersion-1, false), postgresql.ErrSchemaBehind)
+	assert.ErrorIs(t, postgresql.CheckSchemaVersion(postgresql.NilVersion, false), postgresql.ErrSchemaBehind)
+	assert.ErrorIs(t, postgresql.CheckSchemaVersion(postgresql.SchemaVersion, true), postgresql.ErrSchemaDirty)
+}
+
+// TestOutputChunksRoundTrip rolls the output chunks back into commands.output and applies
+// them again. It needs an empty database, BASHAPI_TEST_DATABASE_URL is its connection URL.
+func TestOutputChunksRoundTrip(t *testing.T) {
+	url := os.Getenv("BASHAPI_TEST_DATABASE_URL")
+	if url == "" {
+		t.Skip("BASHAPI_TEST_DATABASE_URL is not set")
+	}
+	ctx := context.Background()
+	db, err := pgxpool.Connect(ctx, url)
+	require.NoError(t, err)
+	defer db.Close()
+	_, err = postgresql.MigrateUp(ctx, db)
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		_, err := postgresql.MigrateDown(ctx, db, int(postgresql.SchemaVersion))
+		assert.NoError(t, err)
+	})
+
+	// The second output is binary, it is no valid UTF-8 and holds a NUL byte
+	outputs := map[string][]byte{"echo": []byte("héllo\n"), "binary": {0xff, 0x00, 'a'}}
+	ids := make(map[string]int)
+	for script, output := range outputs {
+		var id int
+		require.NoError(t, db.QueryRow(ctx, "INSERT INTO commands.commands (script) VALUES ($1) RETURNING id", script).Scan(&id))
+		_, err = db.Exec(ctx, `INSERT INTO commands.command_output_chunks (command_id, seq, byte_offset, data)
+			VALUES ($1, 0, 0, $2)`, id, output)
+		require.NoError(t, err)
+		ids[script] = id
+	}
+
+	_, err = postgresql.MigrateDown(ctx, db, int(postgresql.SchemaVersion-8))
+	require.NoError(t, err)
+	folded := make(map[string]string)
+	for script, id := range ids {
+		var output string
+		require.NoError(t, db.QueryRow(ctx, "SELECT output FROM commands.commands WHERE id = $1", id).Scan(&output))
+		folded[script] = output
+	}
+	assert.Equal(t, "héllo\n", folded["echo"])
+	assert.Equal(t, `\377\000a`, folded["binary"])
+
+	_, err = postgresql.MigrateUp(ctx, db)
+	require.NoError(t, err)
+	var size int64
+	require.NoError(t, db.QueryRow(ctx, "SELECT output_size FROM commands.commands WHERE id = $1", ids["echo"]).Scan(&size))
+	assert.Equal(t, int64(len(outputs["echo"])), size)
+}
@@ -0,0 +1,92 @@
+package tests_test
+
+import (
+	"github.com/17HIERARCH70/BashAPI/internal/domain/models"
+	"github.com/17HIERARCH70/BashAPI/internal/handlers"
+	services "github.com/17HIERARCH70/BashAPI/internal/services/command"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func setupOutputRouter(mockService *MockCommandService) *gin.Engine {
+	handler := handlers.NewCommandHandlers(mockService, nil)
+	router := gin.Default()
+	router.GET("/commands/:id/output", handler.GetCommandOutput)
+	return router
+}
+
+func TestGetCommandOutputRange(t *testing.T) {
+	mockService := new(MockCommandService)
+	next := int64(15)
+	mockService.On("FetchCommandOutput", 1, models.OutputQuery{Offset: 10, Limit: 5}).
+		Return(models.CommandOutput{CommandID: 1, Size: 40, Offset: 10, NextOffset: &next, Output: "hello"}, nil)
+
+	req, _ := http.NewRequest("GET", "/commands/1/output?offset=10&limit=5", nil)
+	w := httptest.NewRecorder()
+	setupOutputRouter(mockService).ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.JSONEq(t, `{"command_id":1,"size":40,"truncated":false,"offset":10,"next_offset":15,"output":"hello"}`, w.Body.String())
+	mockService.AssertExpectations(t)
+}
+
+func TestGetCommandOutputTailWithGrep(t *testing.T) {
+	mockService := new(MockCommandService)
+	lines := 1
+	mockService.On("FetchCommandOutput", 1, mock.MatchedBy(func(query models.OutputQuery) bool {
+		return query.Tail == 200 && query.Grep != nil && query.Grep.String() == "ERROR"
+	})).Return(models.CommandOutput{CommandID: 1, Size: 40, Lines: &lines, Output: "ERROR boom\n"}, nil)
+
+	req, _ := http.NewRequest("GET", "/commands/1/output?tail=200&grep=ERROR", nil)
+	w := httptest.NewRecorder()
+	setupOutputRouter(mockService).ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.JSONEq(t, `{"command_id":1,"size":40,"truncated":false,"offset":0,"lines":1,"output":"ERROR boom\n"}`, w.Body.String())
+	mockService.AssertExpectations(t)
+}
+
+func TestGetCommandOutputRaw(t *testing.T) {
+	mockService := new(MockCommandService)
+	mockService.On("FetchCommandOutput", 7, models.OutputQuery{Head: 2}).
+		Return(models.CommandOutput{CommandID: 7, Size: 100, Output: "a\nb\n"}, nil)
+
+	req, _ := http.NewRequest("GET", "/commands/7/output?head=2&raw=true", nil)
+	w := httptest.NewRecorder()
+	setupOutputRouter(mockService).ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "a\nb\n", w.Body.String())
+	assert.Equal(t, "text/plain; charset=utf-8", w.Header().Get("Content-Type"))
+	assert.Equal(t, `attachment; filename="command-7-output.txt"`, w.Header().Get("Content-Disposition"))
+	mockService.AssertExpectations(t)
+}
+
+func TestGetCommandOutputInvalidQuery(t *testing.T) {
+	for _, query := range []string{"offset=-1", "limit=abc", "head=1&tail=1", "grep=(", "raw=maybe"} {
+		mockService := new(MockCommandService)
+
+		req, _ := http.NewRequest("GET", "/commands/1/output?"+query, nil)
+		w := httptest.NewRecorder()
+		setupOutputRouter(mockService).ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusBadRequest, w.Code, query)
+		mockService.AssertNotCalled(t, "FetchCommandOutput", mock.Anything, mock.Anything)
+	}
+}
+
+func TestGetCommandOutputNotFound(t *testing.T) {
+	mockService := new(MockCommandService)
+	mockService.On("FetchCommandOutput", 9, models.OutputQuery{}).Return(models.CommandOutput{}, services.ErrNotFound)
+
+	req, _ := http.NewRequest("GET", "/commands/9/output", nil)
+	w := httptest.NewRecorder()
+	setupOutputRouter(mockService).ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusNotFound, w.Code)
+	assert.JSONEq(t, `{"error":"Command not found"}`, w.Body.String())
+}
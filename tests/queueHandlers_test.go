@@ -0,0 +1,134 @@
+package tests_test
+
+import (
+	"github.com/17HIERARCH70/BashAPI/internal/domain/models"
+	"github.com/17HIERARCH70/BashAPI/internal/handlers"
+	services "github.com/17HIERARCH70/BashAPI/internal/services/command"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestCancelCommand(t *testing.T) {
+	mockService := new(MockCommandService)
+	mockService.On("CancelCommand", 3).Return(nil)
+
+	handler := handlers.NewCommandHandlers(mockService, nil)
+	router := gin.Default()
+	router.POST("/commands/:id/cancel", handler.CancelCommand)
+
+	req, _ := http.NewRequest("POST", "/commands/3/cancel", nil)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.JSONEq(t, `{"message":"Command cancelled","id":3}`, w.Body.String())
+	mockService.AssertExpectations(t)
+}
+
+func TestCancelCommandNotQueued(t *testing.T) {
+	mockService := new(MockCommandService)
+	mockService.On("CancelCommand", 3).Return(services.ErrNotQueued)
+
+	handler := handlers.NewCommandHandlers(mockService, nil)
+	router := gin.Default()
+	router.POST("/commands/:id/cancel", handler.CancelCommand)
+
+	req, _ := http.NewRequest("POST", "/commands/3/cancel", nil)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusConflict, w.Code)
+	assert.JSONEq(t, `{"error":"Command is not waiting in the queue"}`, w.Body.String())
+	mockService.AssertExpectations(t)
+}
+
+func TestMoveQueuedCommand(t *testing.T) {
+	cases := map[string]int{
+		`{"position":"front"}`: 1,
+		`{"position":"back"}`:  math.MaxInt32,
+		`{"position":4}`:       4,
+	}
+
+	for body, position := range cases {
+		mockService := new(MockCommandService)
+		mockService.On("MoveQueuedCommand", 7, position).Return(nil)
+
+		handler := handlers.NewCommandHandlers(mockService, nil)
+		router := gin.Default()
+		router.POST("/commands/:id/move", handler.MoveQueuedCommand)
+
+		req, _ := http.NewRequest("POST", "/commands/7/move", strings.NewReader(body))
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.JSONEq(t, `{"message":"Command moved","id":7}`, w.Body.String())
+		mockService.AssertExpectations(t)
+	}
+}
+
+func TestMoveQueuedCommandInvalidPosition(t *testing.T) {
+	handler := handlers.NewCommandHandlers(new(MockCommandService), nil)
+	router := gin.Default()
+	router.POST("/commands/:id/move", handler.MoveQueuedCommand)
+
+	for _, body := range []string{`{"position":"middle"}`, `{"position":0}`, `{}`} {
+		req, _ := http.NewRequest("POST", "/commands/7/move", strings.NewReader(body))
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+		assert.JSONEq(t, `{"error":"Position must be 'front', 'back' or a positive number"}`, w.Body.String())
+	}
+}
+
+func TestMoveQueuedCommandNotFound(t *testing.T) {
+	mockService := new(MockCommandService)
+	mockService.On("MoveQueuedCommand", 99, 1).Return(services.ErrNotFound)
+
+	handler := handlers.NewCommandHandlers(mockService, nil)
+	router := gin.Default()
+	router.POST("/commands/:id/move", handler.MoveQueuedCommand)
+
+	req, _ := http.NewRequest("POST", "/commands/99/move", strings.NewReader(`{"position":"front"}`))
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusNotFound, w.Code)
+	mockService.AssertExpectations(t)
+}
+
+func TestCancelQueuedCommands(t *testing.T) {
+	mockService := new(MockCommandService)
+	mockService.On("CancelQueuedCommands", models.QueueFilter{Queue: "deploy", ScriptContains: "rsync"}).Return([]int{4, 5}, nil)
+
+	handler := handlers.NewCommandHandlers(mockService, nil)
+	router := gin.Default()
+	router.POST("/commands/queue/cancel", handler.CancelQueuedCommands)
+
+	req, _ := http.NewRequest("POST", "/commands/queue/cancel", strings.NewReader(`{"queue":"deploy","script_contains":"rsync"}`))
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.JSONEq(t, `{"message":"Commands cancelled","cancelled":[4,5]}`, w.Body.String())
+	mockService.AssertExpectations(t)
+}
+
+func TestCancelQueuedCommandsRequiresFilter(t *testing.T) {
+	handler := handlers.NewCommandHandlers(new(MockCommandService), nil)
+	router := gin.Default()
+	router.POST("/commands/queue/cancel", handler.CancelQueuedCommands)
+
+	req, _ := http.NewRequest("POST", "/commands/queue/cancel", strings.NewReader(`{}`))
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.JSONEq(t, `{"error":"At least one filter or \"all\": true is required"}`, w.Body.String())
+}
@@ -0,0 +1,62 @@
+package tests_test
+
+import (
+	"github.com/17HIERARCH70/BashAPI/internal/lib/schedule"
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"time"
+)
+
+func TestEstimateStartsWithFreeCapacity(t *testing.T) {
+	now := time.Now()
+	starts := schedule.EstimateStarts(now, 2, nil, []schedule.Pending{
+		{Weight: 1, Duration: time.Minute},
+		{Weight: 1, Duration: time.Minute},
+	})
+
+	assert.Equal(t, now, *starts[0])
+	assert.Equal(t, now, *starts[1])
+}
+
+func TestEstimateStartsWaitsForRunningCommands(t *testing.T) {
+	now := time.Now()
+	running := []schedule.Running{
+		{Weight: 1, FinishesAt: now.Add(30 * time.Second)},
+		{Weight: 1, FinishesAt: now.Add(10 * time.Second)},
+	}
+	starts := schedule.EstimateStarts(now, 2, running, []schedule.Pending{
+		{Weight: 1, Duration: time.Minute},
+		{Weight: 1, Duration: time.Minute},
+		{Weight: 1, Duration: time.Minute},
+	})
+
+	assert.Equal(t, now.Add(10*time.Second), *starts[0])
+	assert.Equal(t, now.Add(30*time.Second), *starts[1])
+	assert.Equal(t, now.Add(70*time.Second), *starts[2])
+}
+
+func TestEstimateStartsHeavyCommandWaitsForSlots(t *testing.T) {
+	now := time.Now()
+	running := []schedule.Running{{Weight: 1, FinishesAt: now.Add(20 * time.Second)}}
+	starts := schedule.EstimateStarts(now, 3, running, []schedule.Pending{
+		{Weight: 3, Duration: time.Minute},
+		{Weight: 1, Duration: 5 * time.Second},
+	})
+
+	// The light command is packed in right away, the heavy one needs every slot free
+	assert.Equal(t, now.Add(20*time.Second), *starts[0])
+	assert.Equal(t, now, *starts[1])
+}
+
+func TestEstimateStartsSkipsHeldCommands(t *testing.T) {
+	now := time.Now()
+	starts := schedule.EstimateStarts(now, 1, nil, []schedule.Pending{
+		{Weight: 1, Duration: time.Minute, Held: true},
+		{Weight: 1, Duration: time.Minute},
+		{Weight: 2, Duration: time.Minute},
+	})
+
+	assert.Nil(t, starts[0])
+	assert.Equal(t, now, *starts[1])
+	assert.Nil(t, starts[2])
+}
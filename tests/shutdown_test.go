@@ -0,0 +1,47 @@
+package tests_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"github.com/17HIERARCH70/BashAPI/internal/domain/models"
+	"github.com/17HIERARCH70/BashAPI/internal/handlers"
+	services "github.com/17HIERARCH70/BashAPI/internal/services/command"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestCreateCommandDuringShutdown(t *testing.T) {
+	mockService := new(MockCommandService)
+	mockService.On("ProcessCommand", "deploy.sh", models.CommandOptions{}).Return(nil, services.ErrShuttingDown)
+	handler := handlers.NewCommandHandlers(mockService, nil)
+	router := gin.New()
+	router.POST("/commands", handler.CreateCommand)
+
+	body, _ := json.Marshal(gin.H{"script": "deploy.sh"})
+	req, _ := http.NewRequest("POST", "/commands", bytes.NewBuffer(body))
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusServiceUnavailable, w.Code)
+	assert.JSONEq(t, `{"error":"server is shutting down"}`, w.Body.String())
+	mockService.AssertExpectations(t)
+}
+
+func TestForceStartCommandDuringShutdown(t *testing.T) {
+	mockService := new(MockCommandService)
+	mockService.On("ForceStartCommand", 7).Return(gin.H{}, services.ErrShuttingDown)
+	handler := handlers.NewCommandHandlers(mockService, nil)
+	router := gin.New()
+	router.POST("/commands/:id/fstart", handler.ForceStartCommand)
+
+	req, _ := http.NewRequest("POST", "/commands/7/fstart", nil)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusServiceUnavailable, w.Code)
+	assert.JSONEq(t, `{"error":"server is shutting down"}`, w.Body.String())
+	mockService.AssertExpectations(t)
+}
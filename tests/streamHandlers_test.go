@@ -0,0 +1,117 @@
+package tests_test
+
+import (
+	"github.com/17HIERARCH70/BashAPI/internal/domain/models"
+	"github.com/17HIERARCH70/BashAPI/internal/handlers"
+	"github.com/17HIERARCH70/BashAPI/internal/lib/labels"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/net/websocket"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func lifecycleEvent(id int64, commandID int, status string) models.LifecycleEvent {
+	return models.LifecycleEvent{Event: models.StatusEvents[status], EventID: id, CommandID: commandID, Status: status,
+		Queue: "deploy", Labels: map[string]string{"env": "prod"}, OccurredAt: time.Date(2026, 10, 1, 12, 0, 0, 0, time.UTC)}
+}
+
+func TestStreamEventsSSE(t *testing.T) {
+	mockService := new(MockCommandService)
+	selector, _ := labels.Parse("env=prod")
+	filter := models.EventFilter{Namespace: "deploy", Selector: selector, Events: []string{"started", "completed"}, After: 5}
+	live := make(chan models.LifecycleEvent, 2)
+	live <- lifecycleEvent(6, 3, models.StatusRunning)
+	live <- lifecycleEvent(7, 3, models.StatusCompleted)
+	close(live)
+	mockService.On("SubscribeEvents", filter).Return(live)
+	mockService.On("ReplayEvents", filter).Return([]models.LifecycleEvent{lifecycleEvent(6, 3, models.StatusRunning)}, nil)
+
+	handler := handlers.NewCommandHandlers(mockService, nil)
+	router := gin.Default()
+	router.GET("/events", handler.StreamEvents)
+
+	req, _ := http.NewRequest("GET", "/events?namespace=deploy&selector=env%3Dprod&type=started,completed", nil)
+	req.Header.Set("Last-Event-ID", "5")
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "text/event-stream", w.Header().Get("Content-Type"))
+	body := w.Body.String()
+	// The replayed event is not sent again when it also arrives live
+	assert.Equal(t, 1, strings.Count(body, "id: 6\n"))
+	assert.Contains(t, body, "id: 6\nevent: started\ndata: {")
+	assert.Contains(t, body, "id: 7\nevent: completed\ndata: {")
+	assert.Less(t, strings.Index(body, "id: 6\n"), strings.Index(body, "id: 7\n"))
+	mockService.AssertExpectations(t)
+}
+
+func TestStreamEventsForwardsLateLiveEvent(t *testing.T) {
+	mockService := new(MockCommandService)
+	filter := models.EventFilter{After: 5}
+	// Event 7 committed after event 8, which was already replayed
+	live := make(chan models.LifecycleEvent, 2)
+	live <- lifecycleEvent(8, 4, models.StatusRunning)
+	live <- lifecycleEvent(7, 3, models.StatusCompleted)
+	close(live)
+	mockService.On("SubscribeEvents", filter).Return(live)
+	mockService.On("ReplayEvents", filter).Return([]models.LifecycleEvent{lifecycleEvent(8, 4, models.StatusRunning)}, nil)
+
+	handler := handlers.NewCommandHandlers(mockService, nil)
+	router := gin.Default()
+	router.GET("/events", handler.StreamEvents)
+
+	req, _ := http.NewRequest("GET", "/events?after=5", nil)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	body := w.Body.String()
+	assert.Equal(t, 1, strings.Count(body, "id: 8\n"))
+	assert.Contains(t, body, "id: 7\nevent: completed\ndata: {")
+	mockService.AssertExpectations(t)
+}
+
+func TestStreamEventsInvalidFilter(t *testing.T) {
+	mockService := new(MockCommandService)
+	handler := handlers.NewCommandHandlers(mockService, nil)
+	router := gin.Default()
+	router.GET("/events", handler.StreamEvents)
+
+	for _, query := range []string{"type=finished", "selector=%3Dprod", "namespace=bad%20name", "after=-1"} {
+		req, _ := http.NewRequest("GET", "/events?"+query, nil)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		assert.Equal(t, http.StatusBadRequest, w.Code, query)
+	}
+	mockService.AssertNotCalled(t, "SubscribeEvents")
+}
+
+func TestStreamEventsWebSocket(t *testing.T) {
+	mockService := new(MockCommandService)
+	live := make(chan models.LifecycleEvent, 1)
+	live <- lifecycleEvent(9, 4, models.StatusError)
+	mockService.On("SubscribeEvents", models.EventFilter{}).Return(live)
+
+	handler := handlers.NewCommandHandlers(mockService, nil)
+	router := gin.Default()
+	router.GET("/events", handler.StreamEvents)
+	server := httptest.NewServer(router)
+	defer server.Close()
+
+	ws, err := websocket.Dial("ws"+strings.TrimPrefix(server.URL, "http")+"/events", "", server.URL)
+	require.NoError(t, err)
+	defer ws.Close()
+
+	var event models.LifecycleEvent
+	require.NoError(t, websocket.JSON.Receive(ws, &event))
+	assert.Equal(t, int64(9), event.EventID)
+	assert.Equal(t, models.EventFailed, event.Event)
+	assert.Equal(t, "deploy", event.Queue)
+	close(live)
+}
@@ -0,0 +1,101 @@
+package tests_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"github.com/17HIERARCH70/BashAPI/internal/domain/models"
+	"github.com/17HIERARCH70/BashAPI/internal/handlers"
+	"github.com/17HIERARCH70/BashAPI/internal/lib/tracing"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	collectortrace "go.opentelemetry.io/proto/otlp/collector/trace/v1"
+	"google.golang.org/protobuf/proto"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+)
+
+const testTraceParent = "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01"
+
+func TestTraceParentRoundTrip(t *testing.T) {
+	ctx := tracing.ContextWithTraceParent(context.Background(), testTraceParent)
+	assert.Equal(t, testTraceParent, tracing.TraceParent(ctx))
+	assert.Equal(t, []string{"TRACEPARENT=" + testTraceParent}, tracing.Environment(ctx))
+
+	assert.Equal(t, "", tracing.TraceParent(context.Background()))
+	assert.Nil(t, tracing.Environment(context.Background()))
+	assert.Equal(t, "", tracing.TraceParent(tracing.ContextWithTraceParent(context.Background(), "garbage")))
+}
+
+func TestTracingExportsToCollector(t *testing.T) {
+	var mu sync.Mutex
+	var spans []string
+	var traceIDs [][]byte
+	collector := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/v1/traces", r.URL.Path)
+		body, _ := io.ReadAll(r.Body)
+		var request collectortrace.ExportTraceServiceRequest
+		if assert.NoError(t, proto.Unmarshal(body, &request)) {
+			mu.Lock()
+			for _, resource := range request.ResourceSpans {
+				for _, scope := range resource.ScopeSpans {
+					for _, span := range scope.Spans {
+						spans = append(spans, span.Name)
+						traceIDs = append(traceIDs, span.TraceId)
+					}
+				}
+			}
+			mu.Unlock()
+		}
+		w.Header().Set("Content-Type", "application/x-protobuf")
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer collector.Close()
+
+	shutdown, err := tracing.Setup(context.Background(), tracing.Options{
+		Endpoint: collector.URL, ServiceName: "bashapi-test", SampleRatio: 1,
+	})
+	require.NoError(t, err)
+
+	// A span started from a stored trace context joins the trace of the request
+	ctx, request := tracing.Tracer().Start(context.Background(), "POST /api/commands/")
+	stored := tracing.TraceParent(ctx)
+	require.NotEmpty(t, stored)
+	request.End()
+	_, execute := tracing.Tracer().Start(tracing.ContextWithTraceParent(context.Background(), stored), "executeCommand")
+	execute.End()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	require.NoError(t, shutdown(ctx))
+
+	mu.Lock()
+	defer mu.Unlock()
+	assert.ElementsMatch(t, []string{"POST /api/commands/", "executeCommand"}, spans)
+	require.Len(t, traceIDs, 2)
+	assert.Equal(t, traceIDs[0], traceIDs[1])
+}
+
+func TestCreateCommandPassesTraceContext(t *testing.T) {
+	mockService := new(MockCommandService)
+	mockService.On("ProcessCommand", "echo hi", models.CommandOptions{TraceParent: testTraceParent}).
+		Return(gin.H{"message": "Command is being executed"}, nil)
+
+	handler := handlers.NewCommandHandlers(mockService, nil)
+	router := gin.Default()
+	router.POST("/commands", handler.CreateCommand)
+
+	body, _ := json.Marshal(gin.H{"script": "echo hi"})
+	req, _ := http.NewRequestWithContext(tracing.ContextWithTraceParent(context.Background(), testTraceParent),
+		"POST", "/commands", bytes.NewBuffer(body))
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusAccepted, w.Code)
+	mockService.AssertExpectations(t)
+}
@@ -0,0 +1,101 @@
+package tests_test
+
+import (
+	"github.com/17HIERARCH70/BashAPI/internal/domain/models"
+	"github.com/17HIERARCH70/BashAPI/internal/handlers"
+	services "github.com/17HIERARCH70/BashAPI/internal/services/command"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func setupWaitRouter(mockService *MockCommandService) *gin.Engine {
+	handler := handlers.NewCommandHandlers(mockService, nil)
+	router := gin.Default()
+	router.GET("/commands/:id/wait", handler.WaitForCommand)
+	router.POST("/commands", handler.CreateCommand)
+	return router
+}
+
+func TestWaitForFinishedCommand(t *testing.T) {
+	mockService := new(MockCommandService)
+	exitCode := 0
+	command := models.Command{ID: 4, Status: models.StatusCompleted, Output: "done\n", ExitCode: &exitCode}
+	mockService.On("WaitForCommand", mock.Anything, 4, 60*time.Second).Return(command, true, nil)
+
+	req, _ := http.NewRequest("GET", "/commands/4/wait?timeout=60s", nil)
+	w := httptest.NewRecorder()
+	setupWaitRouter(mockService).ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Body.String(), `"Status":"completed"`)
+	assert.Contains(t, w.Body.String(), `"ExitCode":0`)
+	mockService.AssertExpectations(t)
+}
+
+func TestWaitForCommandTimesOut(t *testing.T) {
+	mockService := new(MockCommandService)
+	command := models.Command{ID: 4, Status: models.StatusRunning}
+	mockService.On("WaitForCommand", mock.Anything, 4, 5*time.Second).Return(command, false, nil)
+	mockService.On("WaitForCommand", mock.Anything, 5, 30*time.Second).Return(models.Command{}, false, services.ErrNotFound)
+	router := setupWaitRouter(mockService)
+
+	req, _ := http.NewRequest("GET", "/commands/4/wait?timeout=5", nil)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusAccepted, w.Code)
+	assert.Contains(t, w.Body.String(), `"Status":"running"`)
+
+	req, _ = http.NewRequest("GET", "/commands/5/wait", nil)
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusNotFound, w.Code)
+	mockService.AssertExpectations(t)
+}
+
+func TestWaitInvalidTimeout(t *testing.T) {
+	router := setupWaitRouter(new(MockCommandService))
+
+	for _, timeout := range []string{"soon", "-5s", "0"} {
+		req, _ := http.NewRequest("GET", "/commands/4/wait?timeout="+timeout, nil)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		assert.Equal(t, http.StatusBadRequest, w.Code, timeout)
+	}
+}
+
+func TestCreateCommandAndWait(t *testing.T) {
+	mockService := new(MockCommandService)
+	exitCode := 2
+	mockService.On("ProcessCommand", "exit 2", models.CommandOptions{}).Return(gin.H{"message": "Command is being executed", "id": 9}, nil)
+	mockService.On("WaitForCommand", mock.Anything, 9, 10*time.Second).
+		Return(models.Command{ID: 9, Status: models.StatusError, ExitCode: &exitCode}, true, nil)
+
+	req, _ := http.NewRequest("POST", "/commands?wait=true&timeout=10s", strings.NewReader(`{"script":"exit 2"}`))
+	w := httptest.NewRecorder()
+	setupWaitRouter(mockService).ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Body.String(), `"ExitCode":2`)
+	mockService.AssertExpectations(t)
+}
+
+func TestCreateCommandAndWaitTimesOut(t *testing.T) {
+	mockService := new(MockCommandService)
+	mockService.On("ProcessCommand", "sleep 60", models.CommandOptions{}).Return(gin.H{"message": "Command is being executed", "id": 9}, nil)
+	mockService.On("WaitForCommand", mock.Anything, 9, 30*time.Second).
+		Return(models.Command{ID: 9, Status: models.StatusRunning}, false, nil)
+
+	req, _ := http.NewRequest("POST", "/commands?wait=true", strings.NewReader(`{"script":"sleep 60"}`))
+	w := httptest.NewRecorder()
+	setupWaitRouter(mockService).ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusAccepted, w.Code)
+	assert.JSONEq(t, `{"message":"Command is being executed","id":9,"status":"running"}`, w.Body.String())
+	mockService.AssertExpectations(t)
+}
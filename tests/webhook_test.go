@@ -0,0 +1,159 @@
+package tests_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"github.com/17HIERARCH70/BashAPI/internal/domain/models"
+	"github.com/17HIERARCH70/BashAPI/internal/handlers"
+	"github.com/17HIERARCH70/BashAPI/internal/lib/webhook"
+	services "github.com/17HIERARCH70/BashAPI/internal/services/command"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestWebhookSignature(t *testing.T) {
+	secret := []byte("s3cret")
+	body := []byte(`{"event":"completed","command_id":7}`)
+	signature := webhook.Sign(secret, 1760000000, body)
+
+	assert.Equal(t, "sha256=", signature[:7])
+	assert.True(t, webhook.Verify(secret, 1760000000, body, signature))
+	assert.False(t, webhook.Verify(secret, 1760000001, body, signature))
+	assert.False(t, webhook.Verify([]byte("other"), 1760000000, body, signature))
+	assert.False(t, webhook.Verify(secret, 1760000000, []byte(`{}`), signature))
+	assert.False(t, webhook.Verify(secret, 1760000000, body, signature[7:]))
+}
+
+func TestWebhookBackoff(t *testing.T) {
+	base, max := 10*time.Second, time.Minute
+	assert.Equal(t, 10*time.Second, webhook.Backoff(1, base, max))
+	assert.Equal(t, 20*time.Second, webhook.Backoff(2, base, max))
+	assert.Equal(t, 40*time.Second, webhook.Backoff(3, base, max))
+	assert.Equal(t, time.Minute, webhook.Backoff(4, base, max))
+	assert.Equal(t, time.Minute, webhook.Backoff(100, base, max))
+}
+
+func TestCreateWebhook(t *testing.T) {
+	mockService := new(MockCommandService)
+	created := models.Webhook{ID: 3, URL: "https://ci.example.com/hook", Events: []string{"completed", "failed"},
+		CreatedAt: time.Date(2026, 10, 1, 12, 0, 0, 0, time.UTC)}
+	mockService.On("CreateWebhook", models.Webhook{URL: "https://ci.example.com/hook", Secret: "abc", Events: []string{"completed", "failed"}}).
+		Return(created, nil)
+
+	handler := handlers.NewCommandHandlers(mockService, nil)
+	router := gin.Default()
+	router.POST("/admin/webhooks", handler.CreateWebhook)
+
+	body, _ := json.Marshal(gin.H{"url": "https://ci.example.com/hook", "secret": "abc", "events": []string{"completed", "failed"}})
+	req, _ := http.NewRequest("POST", "/admin/webhooks", bytes.NewBuffer(body))
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusCreated, w.Code)
+	assert.JSONEq(t, `{"id":3,"url":"https://ci.example.com/hook","events":["completed","failed"],"created_at":"2026-10-01T12:00:00Z"}`, w.Body.String())
+	mockService.AssertExpectations(t)
+}
+
+func TestCreateWebhookValidation(t *testing.T) {
+	mockService := new(MockCommandService)
+	handler := handlers.NewCommandHandlers(mockService, nil)
+	router := gin.Default()
+	router.POST("/admin/webhooks", handler.CreateWebhook)
+
+	for _, body := range []gin.H{
+		{},
+		{"url": "ftp://ci.example.com/hook"},
+		{"url": "/hook"},
+		{"url": "https://ci.example.com/hook", "events": []string{"finished"}},
+	} {
+		data, _ := json.Marshal(body)
+		req, _ := http.NewRequest("POST", "/admin/webhooks", bytes.NewBuffer(data))
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		assert.Equal(t, http.StatusBadRequest, w.Code, body)
+	}
+	mockService.AssertNotCalled(t, "CreateWebhook")
+}
+
+func TestGetWebhooksHidesSecrets(t *testing.T) {
+	mockService := new(MockCommandService)
+	mockService.On("FetchWebhooks").Return([]models.Webhook{{ID: 1, URL: "https://ci.example.com/hook", Secret: "abc", Events: []string{}}}, nil)
+
+	handler := handlers.NewCommandHandlers(mockService, nil)
+	router := gin.Default()
+	router.GET("/admin/webhooks", handler.GetWebhooks)
+
+	req, _ := http.NewRequest("GET", "/admin/webhooks", nil)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.NotContains(t, w.Body.String(), "abc")
+}
+
+func TestDeleteWebhook(t *testing.T) {
+	mockService := new(MockCommandService)
+	mockService.On("DeleteWebhook", 1).Return(nil)
+	mockService.On("DeleteWebhook", 2).Return(services.ErrWebhookNotFound)
+
+	handler := handlers.NewCommandHandlers(mockService, nil)
+	router := gin.Default()
+	router.DELETE("/admin/webhooks/:id", handler.DeleteWebhook)
+
+	for id, code := range map[string]int{"1": http.StatusOK, "2": http.StatusNotFound, "x": http.StatusBadRequest} {
+		req, _ := http.NewRequest("DELETE", "/admin/webhooks/"+id, nil)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		assert.Equal(t, code, w.Code, id)
+	}
+	mockService.AssertExpectations(t)
+}
+
+func TestGetCommandDeliveries(t *testing.T) {
+	mockService := new(MockCommandService)
+	commandID := 7
+	statusCode := 502
+	deliveries := []models.WebhookDelivery{{ID: 11, CommandID: 7, Event: "completed", URL: "https://ci.example.com/hook",
+		Status: models.DeliveryPending, Attempts: 1, LastStatusCode: &statusCode,
+		AttemptLog: []models.WebhookAttempt{{Attempt: 1, StatusCode: &statusCode, Error: "unexpected status 502: "}}}}
+	mockService.On("FetchDeliveries", models.DeliveryQuery{CommandID: &commandID, Limit: 5}).Return(deliveries, nil)
+
+	handler := handlers.NewCommandHandlers(mockService, nil)
+	router := gin.Default()
+	router.GET("/commands/:id/deliveries", handler.GetCommandDeliveries)
+
+	req, _ := http.NewRequest("GET", "/commands/7/deliveries?limit=5", nil)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	var response []models.WebhookDelivery
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+	assert.Equal(t, 1, len(response))
+	assert.Equal(t, 502, *response[0].AttemptLog[0].StatusCode)
+
+	req, _ = http.NewRequest("GET", "/commands/7/deliveries?limit=-1", nil)
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	mockService.AssertExpectations(t)
+}
+
+func TestCreateCommandRejectsInvalidCallbackURL(t *testing.T) {
+	mockService := new(MockCommandService)
+	handler := handlers.NewCommandHandlers(mockService, nil)
+	router := gin.Default()
+	router.POST("/commands", handler.CreateCommand)
+
+	body, _ := json.Marshal(gin.H{"script": "echo hi", "callback_url": "not a url"})
+	req, _ := http.NewRequest("POST", "/commands", bytes.NewBuffer(body))
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	mockService.AssertNotCalled(t, "ProcessCommand")
+}